@@ -0,0 +1,223 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/token"
+)
+
+// Actions recorded in the audit log
+const (
+	auditCategoryCreate          = "category.create"
+	auditCategoryUpdate          = "category.update"
+	auditCategoryDelete          = "category.delete"
+	auditShopCreate              = "shop.create"
+	auditShopUpdate              = "shop.update"
+	auditShopDelete              = "shop.delete"
+	auditProductCreate           = "product.create"
+	auditProductUpdate           = "product.update"
+	auditProductDelete           = "product.delete"
+	auditOrderCreate             = "order.create"
+	auditOrderStatusUpdate       = "order.status_update"
+	auditOrderCancel             = "order.cancel"
+	auditOrderPay                = "order.pay"
+	auditShopOrderStatusUpdate   = "shop_order.status_update"
+	auditReturnCreate            = "return.create"
+	auditReturnStatusUpdate      = "return.status_update"
+	auditReturnRefundRetry       = "return.refund_retry"
+	auditUserRoleUpdate          = "user.role_update"
+	auditUserDelete              = "user.delete"
+	auditUserSuspend             = "user.suspend"
+	auditUserUnsuspend           = "user.unsuspend"
+	auditUserUnlock              = "user.unlock"
+	auditSellerApplicationReview = "seller_application.review"
+)
+
+// Types of the resources that audit events point to
+const (
+	auditTargetCategory          = "category"
+	auditTargetShop              = "shop"
+	auditTargetProduct           = "product"
+	auditTargetOrder             = "order"
+	auditTargetShopOrder         = "shop_order"
+	auditTargetPayment           = "payment"
+	auditTargetReturnRequest     = "return_request"
+	auditTargetUser              = "user"
+	auditTargetSellerApplication = "seller_application"
+)
+
+// systemActor is the actor recorded for actions that no user is authenticated for
+const systemActor = "system"
+
+// audit records that the authenticated user performed an action on a resource, or the system on
+// routes without one. before and after are the resource's state around the change, and only the
+// fields that differ are kept. Either one is nil when the resource was created or deleted. The change
+// has already been made by then, so a failure to record it is only logged.
+func (server *Server) audit(ctx *gin.Context, action string, targetType string, targetID uuid.UUID, before interface{}, after interface{}) {
+	beforeDiff, afterDiff, err := auditDiff(before, after)
+	if err != nil {
+		log.Printf("Warning: failed to record audit event %s on %s %s: %v", action, targetType, targetID, err)
+		return
+	}
+
+	actorID, actorUsername := uuid.Nil, systemActor
+	if authPayload, ok := ctx.Get(authorizationPayloadKey); ok {
+		actorID = authPayload.(*token.Payload).UserID
+		actorUsername = authPayload.(*token.Payload).Username
+	}
+
+	arg := db.CreateAuditEventParams{
+		ActorID:       actorID,
+		ActorUsername: actorUsername,
+		Action:        action,
+		TargetType:    targetType,
+		TargetID:      targetID,
+		Before:        beforeDiff,
+		After:         afterDiff,
+		ClientIp:      ctx.ClientIP(),
+		RequestID:     ctx.GetString(requestIDKey),
+	}
+
+	err = server.store.CreateAuditEvent(ctx, arg)
+	if err != nil {
+		log.Printf("Warning: failed to record audit event %s on %s %s: %v", action, targetType, targetID, err)
+	}
+}
+
+// auditDiff turns two states of a resource into JSON objects holding only the fields that changed
+func auditDiff(before interface{}, after interface{}) (json.RawMessage, json.RawMessage, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for field, value := range beforeFields {
+		if afterValue, ok := afterFields[field]; ok && bytes.Equal(value, afterValue) {
+			delete(beforeFields, field)
+			delete(afterFields, field)
+		}
+	}
+
+	beforeDiff, err := json.Marshal(beforeFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterDiff, err := json.Marshal(afterFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return beforeDiff, afterDiff, nil
+}
+
+// auditFields splits the JSON form of a resource into its fields
+func auditFields(state interface{}) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if state == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+type listAuditEventsRequest struct {
+	PageID     int32     `form:"page_id" binding:"required,min=1"`
+	PageSize   int32     `form:"page_size" binding:"required,min=5,max=100"`
+	ActorID    string    `form:"actor_id" binding:"omitempty,uuid"`
+	Action     string    `form:"action" binding:"max=100"`
+	TargetType string    `form:"target_type" binding:"max=50"`
+	TargetID   string    `form:"target_id" binding:"omitempty,uuid"`
+	RequestID  string    `form:"request_id" binding:"max=64"`
+	From       time.Time `form:"from" time_format:"2006-01-02" time_utc:"1"`
+	To         time.Time `form:"to" time_format:"2006-01-02" time_utc:"1"`
+}
+
+type auditEventResponse struct {
+	ID            uuid.UUID       `json:"id"`
+	ActorID       uuid.UUID       `json:"actor_id"`
+	ActorUsername string          `json:"actor_username"`
+	Action        string          `json:"action"`
+	TargetType    string          `json:"target_type"`
+	TargetID      uuid.UUID       `json:"target_id"`
+	Before        json.RawMessage `json:"before"`
+	After         json.RawMessage `json:"after"`
+	ClientIP      string          `json:"client_ip"`
+	RequestID     string          `json:"request_id"`
+	CreatedAt     string          `json:"created_at"`
+}
+
+func newAuditEventResponse(event db.AuditEvent) auditEventResponse {
+	return auditEventResponse{
+		ID:            event.ID,
+		ActorID:       event.ActorID,
+		ActorUsername: event.ActorUsername,
+		Action:        event.Action,
+		TargetType:    event.TargetType,
+		TargetID:      event.TargetID,
+		Before:        event.Before,
+		After:         event.After,
+		ClientIP:      event.ClientIp,
+		RequestID:     event.RequestID,
+		CreatedAt:     event.CreatedAt.String(),
+	}
+}
+
+// listAuditEvents lists audit events, newest first, optionally filtered by actor, action, target,
+// request and a range of days. Both days of the range are included.
+func (server *Server) listAuditEvents(ctx *gin.Context) {
+	var req listAuditEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListAuditEventsParams{
+		ActorID: parseNullUUID(req.ActorID),
+		Action: sql.NullString{
+			String: req.Action,
+			Valid:  req.Action != "",
+		},
+		TargetType: sql.NullString{
+			String: req.TargetType,
+			Valid:  req.TargetType != "",
+		},
+		TargetID: parseNullUUID(req.TargetID),
+		RequestID: sql.NullString{
+			String: req.RequestID,
+			Valid:  req.RequestID != "",
+		},
+		CreatedFrom: sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		// The end date is inclusive
+		CreatedTo: sql.NullTime{Time: req.To.AddDate(0, 0, 1), Valid: !req.To.IsZero()},
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	}
+
+	events, err := server.store.ListAuditEvents(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]auditEventResponse, len(events))
+	for i, event := range events {
+		response[i] = newAuditEventResponse(event)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
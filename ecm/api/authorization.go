@@ -0,0 +1,241 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/token"
+)
+
+// Permissions granted to roles in the role_permissions table. A permission with an :own and an :any
+// variant is checked with authorize, which picks the variant from the resource's owner.
+const (
+	permCategoryManage  = "category:manage"
+	permShopCreate      = "shop:create"
+	permShopRead        = "shop:read"
+	permShopWrite       = "shop:write"
+	permProductWrite    = "product:write"
+	permOrderCreate     = "order:create"
+	permOrderRead       = "order:read"
+	permOrderReadAny    = "order:read:any"
+	permOrderStatusAny  = "order:status:any"
+	permOrderCancel     = "order:cancel"
+	permOrderPay        = "order:pay"
+	permShopOrderStatus = "shop_order:status"
+	permReturnCreate    = "return:create"
+	permReturnReview    = "return:review"
+	permUserManage      = "user:manage"
+	permStatsRead       = "stats:read"
+	permAuditRead       = "audit:read"
+
+	permSellerApplicationCreate = "seller_application:create"
+	permSellerApplicationReview = "seller_application:review"
+)
+
+// rolePermissionsCacheTTL bounds how long it takes for a change to role_permissions to take effect
+const rolePermissionsCacheTTL = time.Minute
+
+var errPermissionDenied = errors.New("you don't have permission to perform this action")
+
+type cachedRolePermissions struct {
+	permissions map[string]bool
+	expiresAt   time.Time
+}
+
+// authorizer answers permission checks from the role_permissions table, cached per role
+type authorizer struct {
+	store db.Store
+
+	mu    sync.Mutex
+	roles map[string]cachedRolePermissions
+}
+
+func newAuthorizer(store db.Store) *authorizer {
+	return &authorizer{
+		store: store,
+		roles: make(map[string]cachedRolePermissions),
+	}
+}
+
+// HasPermission reports whether the role has been granted the permission
+func (a *authorizer) HasPermission(ctx context.Context, role string, permission string) (bool, error) {
+	now := time.Now()
+
+	a.mu.Lock()
+	cached, ok := a.roles[role]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.permissions[permission], nil
+	}
+
+	permissions, err := a.store.ListRolePermissions(ctx, db.UserRole(role))
+	if err != nil {
+		return false, err
+	}
+
+	cached = cachedRolePermissions{
+		permissions: make(map[string]bool, len(permissions)),
+		expiresAt:   now.Add(rolePermissionsCacheTTL),
+	}
+	for _, p := range permissions {
+		cached.permissions[p] = true
+	}
+
+	a.mu.Lock()
+	a.roles[role] = cached
+	a.mu.Unlock()
+	return cached.permissions[permission], nil
+}
+
+// can reports whether the authenticated user has the permission
+func (server *Server) can(ctx *gin.Context, permission string) (bool, error) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	return server.authorizer.HasPermission(ctx, authPayload.Role, permission)
+}
+
+// canAccess reports whether the authenticated user holds permission:any, or permission:own for a
+// resource owned by them
+func (server *Server) canAccess(ctx *gin.Context, permission string, ownerID uuid.UUID) (bool, error) {
+	ok, err := server.can(ctx, permission+":any")
+	if err != nil || ok {
+		return ok, err
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if ownerID != authPayload.UserID {
+		return false, nil
+	}
+	return server.can(ctx, permission+":own")
+}
+
+// authorize checks canAccess for handlers that find the owner themselves. It returns false if a
+// response has already been written.
+func (server *Server) authorize(ctx *gin.Context, permission string, ownerID uuid.UUID) bool {
+	ok, err := server.canAccess(ctx, permission, ownerID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if !ok {
+		ctx.JSON(http.StatusForbidden, errorResponse(errPermissionDenied))
+		return false
+	}
+	return true
+}
+
+// requirePermission creates a middleware that checks a permission that does not depend on a resource
+func (server *Server) requirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ok, err := server.can(ctx, permission)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(errPermissionDenied))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// ownerResolver finds the user who owns the resource with the given ID
+type ownerResolver struct {
+	resource string
+	// param is the path parameter that holds the ID, :id unless set
+	param string
+	owner func(ctx context.Context, store db.Store, id uuid.UUID) (uuid.UUID, error)
+}
+
+var (
+	shopOwner = ownerResolver{
+		resource: "shop",
+		owner: func(ctx context.Context, store db.Store, id uuid.UUID) (uuid.UUID, error) {
+			shop, err := store.GetShop(ctx, id)
+			return shop.OwnerID, err
+		},
+	}
+
+	productOwner = ownerResolver{
+		resource: "product",
+		owner: func(ctx context.Context, store db.Store, id uuid.UUID) (uuid.UUID, error) {
+			product, err := store.GetProduct(ctx, id)
+			if err != nil {
+				return uuid.Nil, err
+			}
+			shop, err := store.GetShop(ctx, product.ShopID)
+			return shop.OwnerID, err
+		},
+	}
+
+	orderOwner = ownerResolver{
+		resource: "order",
+		owner: func(ctx context.Context, store db.Store, id uuid.UUID) (uuid.UUID, error) {
+			order, err := store.GetOrder(ctx, id)
+			return order.UserID, err
+		},
+	}
+
+	// returnOwner resolves to the seller of the shop that a return request is made to
+	returnOwner = ownerResolver{
+		resource: "return request",
+		param:    "returnId",
+		owner: func(ctx context.Context, store db.Store, id uuid.UUID) (uuid.UUID, error) {
+			returnRequest, err := store.GetReturnRequest(ctx, id)
+			if err != nil {
+				return uuid.Nil, err
+			}
+			shop, err := store.GetShop(ctx, returnRequest.ShopID)
+			return shop.OwnerID, err
+		},
+	}
+)
+
+// requireOwnedPermission creates a middleware that checks permission:any, or permission:own for the
+// resource identified by the resolver's path parameter
+func (server *Server) requireOwnedPermission(permission string, resolver ownerResolver) gin.HandlerFunc {
+	param := resolver.param
+	if param == "" {
+		param = "id"
+	}
+
+	return func(ctx *gin.Context) {
+		id, err := uuid.Parse(ctx.Param(param))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		ownerID, err := resolver.owner(ctx, server.store, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				err := fmt.Errorf("%s not found", resolver.resource)
+				ctx.AbortWithStatusJSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ok, err := server.canAccess(ctx, permission, ownerID)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(errPermissionDenied))
+			return
+		}
+
+		ctx.Next()
+	}
+}
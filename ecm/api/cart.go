@@ -4,11 +4,11 @@ import (
 	"database/sql"
 	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/money"
 	"github.com/qhh/ecm/token"
 )
 
@@ -18,18 +18,18 @@ type addToCartRequest struct {
 }
 
 type cartItemResponse struct {
-	ID          uuid.UUID `json:"id"`
-	ProductID   uuid.UUID `json:"product_id"`
-	ProductName string    `json:"product_name"`
-	Quantity    int32     `json:"quantity"`
-	Price       float64   `json:"price"`
-	ImageURL    string    `json:"image_url"`
-	CreatedAt   string    `json:"created_at"`
-	UpdatedAt   string    `json:"updated_at"`
+	ID          uuid.UUID    `json:"id"`
+	ProductID   uuid.UUID    `json:"product_id"`
+	ProductName string       `json:"product_name"`
+	Quantity    int32        `json:"quantity"`
+	UnitPrice   money.Amount `json:"unit_price"`
+	Price       money.Amount `json:"price"`
+	ImageURL    string       `json:"image_url"`
+	CreatedAt   string       `json:"created_at"`
+	UpdatedAt   string       `json:"updated_at"`
 }
 
 func newCartItemResponse(cartItem db.GetCartItemsRow) cartItemResponse {
-	price, _ := strconv.ParseFloat(cartItem.Price, 64)
 	imageURL := ""
 	if cartItem.ImageUrl.Valid {
 		imageURL = cartItem.ImageUrl.String
@@ -40,7 +40,8 @@ func newCartItemResponse(cartItem db.GetCartItemsRow) cartItemResponse {
 		ProductID:   cartItem.ProductID,
 		ProductName: cartItem.ProductName,
 		Quantity:    cartItem.Quantity,
-		Price:       price,
+		UnitPrice:   cartItem.UnitPrice,
+		Price:       cartItem.Price,
 		ImageURL:    imageURL,
 		CreatedAt:   cartItem.CreatedAt.String(),
 		UpdatedAt:   cartItem.UpdatedAt.String(),
@@ -79,10 +80,12 @@ func (server *Server) addToCart(ctx *gin.Context) {
 		return
 	}
 
+	// Remember the price the buyer sees now, so checkout can tell them if it changes
 	arg := db.AddToCartParams{
 		UserID:    authPayload.UserID,
 		ProductID: productID,
 		Quantity:  req.Quantity,
+		UnitPrice: product.Price,
 	}
 
 	cartItem, err := server.store.AddToCart(ctx, arg)
@@ -45,12 +45,6 @@ func (server *Server) createCategory(ctx *gin.Context) {
 		return
 	}
 
-	// Only admin can create categories
-	if !server.isAdmin(ctx) {
-		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("admin role required")))
-		return
-	}
-
 	arg := db.CreateCategoryParams{
 		Name: req.Name,
 		Description: sql.NullString{
@@ -65,7 +59,10 @@ func (server *Server) createCategory(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, newCategoryResponse(category))
+	rsp := newCategoryResponse(category)
+	server.audit(ctx, auditCategoryCreate, auditTargetCategory, category.ID, nil, rsp)
+
+	ctx.JSON(http.StatusCreated, rsp)
 }
 
 func (server *Server) getCategory(ctx *gin.Context) {
@@ -108,12 +105,6 @@ type updateCategoryRequest struct {
 }
 
 func (server *Server) updateCategory(ctx *gin.Context) {
-	// Only admin can update categories
-	if !server.isAdmin(ctx) {
-		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("admin role required")))
-		return
-	}
-
 	id, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
@@ -126,6 +117,16 @@ func (server *Server) updateCategory(ctx *gin.Context) {
 		return
 	}
 
+	before, err := server.store.GetCategory(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("category not found")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	arg := db.UpdateCategoryParams{
 		ID:   id,
 		Name: req.Name,
@@ -145,22 +146,20 @@ func (server *Server) updateCategory(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, newCategoryResponse(category))
+	rsp := newCategoryResponse(category)
+	server.audit(ctx, auditCategoryUpdate, auditTargetCategory, category.ID, newCategoryResponse(before), rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
 }
-func (server *Server) deleteCategory(ctx *gin.Context) {
-	// Only admin can delete categories
-	if !server.isAdmin(ctx) {
-		ctx.JSON(http.StatusForbidden, errorResponse(errors.New("admin role required")))
-		return
-	}
 
+func (server *Server) deleteCategory(ctx *gin.Context) {
 	id, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	err = server.store.DeleteCategory(ctx, id)
+	category, err := server.store.GetCategory(ctx, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("category not found")))
@@ -170,5 +169,13 @@ func (server *Server) deleteCategory(ctx *gin.Context) {
 		return
 	}
 
+	err = server.store.DeleteCategory(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.audit(ctx, auditCategoryDelete, auditTargetCategory, category.ID, newCategoryResponse(category), nil)
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "category deleted successfully"})
 }
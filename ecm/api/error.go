@@ -1,10 +1,25 @@
 package api
 
 import (
+	"errors"
+
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 )
 
+// uniqueViolation is the PostgreSQL error code for a unique constraint violation
+const uniqueViolation = "23505"
+
 // errorResponse is a generic error response structure
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
+
+// isUniqueViolation reports whether err was caused by a unique constraint, optionally a specific one
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != uniqueViolation {
+		return false
+	}
+	return constraint == "" || pqErr.Constraint == constraint
+}
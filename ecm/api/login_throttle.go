@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	db "github.com/qhh/ecm/db/sqlc"
+)
+
+const (
+	// loginFailureWindow is how long failed attempts are remembered after the last one
+	loginFailureWindow = time.Hour
+
+	// loginBackoffBase is the delay after the first attempt past the free attempts; it doubles with every further attempt
+	loginBackoffBase = time.Second
+
+	// loginMaxLockout caps the backoff, which makes it a temporary lockout once it is reached
+	loginMaxLockout = 15 * time.Minute
+
+	// loginThrottlePruneInterval controls how often stale rows are deleted
+	loginThrottlePruneInterval = 10 * time.Minute
+)
+
+// loginThrottlePolicy is the number of failures allowed before the backoff starts
+type loginThrottlePolicy struct {
+	freeAttempts int32
+}
+
+var (
+	// usernameThrottlePolicy protects a single account from password guessing
+	usernameThrottlePolicy = loginThrottlePolicy{freeAttempts: 5}
+
+	// ipThrottlePolicy slows down a single client trying many accounts
+	ipThrottlePolicy = loginThrottlePolicy{freeAttempts: 20}
+)
+
+// loginThrottle tracks failed logins per username and per client IP in the database, so that the
+// limits hold across server instances
+type loginThrottle struct {
+	store db.Store
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func newLoginThrottle(store db.Store) *loginThrottle {
+	return &loginThrottle{
+		store:     store,
+		lastPrune: time.Now(),
+	}
+}
+
+// usernameThrottleKey is case-insensitive so that changing the case does not reset the counter
+func usernameThrottleKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Attempt counts an attempt for the key before the password is checked, and returns how long the
+// caller has to wait if the key is locked out, or zero. Counting comes first, in one statement, so
+// that a burst of parallel guesses cannot all get in before the lockout is recorded: the attempt that
+// uses up the free attempts locks the key, and every later one is refused until the backoff is over.
+// Refused attempts are not counted.
+func (lt *loginThrottle) Attempt(ctx context.Context, key string, policy loginThrottlePolicy) (time.Duration, error) {
+	now := time.Now().UTC()
+	lt.prune(now)
+
+	_, err := lt.store.RecordLoginAttempt(ctx, db.RecordLoginAttemptParams{
+		ThrottleKey:        key,
+		AttemptedAt:        now,
+		ResetBefore:        now.Add(-loginFailureWindow),
+		FreeAttempts:       policy.freeAttempts,
+		BackoffBaseSeconds: loginBackoffBase.Seconds(),
+		MaxLockoutSeconds:  loginMaxLockout.Seconds(),
+	})
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	throttle, err := lt.store.GetLoginThrottle(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	wait := throttle.LockedUntil.Time.Sub(now)
+	if !throttle.LockedUntil.Valid || wait <= 0 {
+		// The lockout ended in the meantime
+		return lt.Attempt(ctx, key, policy)
+	}
+	return wait, nil
+}
+
+// Forgive takes back an attempt that turned out to be right, lifting the lockout if it no longer
+// goes past the free attempts
+func (lt *loginThrottle) Forgive(ctx context.Context, key string, policy loginThrottlePolicy) error {
+	return lt.store.ForgiveLoginAttempt(ctx, db.ForgiveLoginAttemptParams{
+		FreeAttempts: policy.freeAttempts,
+		ThrottleKey:  key,
+	})
+}
+
+// Reset forgets the attempts for the key
+func (lt *loginThrottle) Reset(ctx context.Context, key string) error {
+	return lt.store.ClearLoginThrottle(ctx, key)
+}
+
+// prune deletes rows whose failures and lockout are both over, in the background
+func (lt *loginThrottle) prune(now time.Time) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	if now.Sub(lt.lastPrune) < loginThrottlePruneInterval {
+		return
+	}
+	lt.lastPrune = now
+
+	go func() {
+		if err := lt.store.DeleteStaleLoginThrottles(context.Background(), now.Add(-loginFailureWindow)); err != nil {
+			log.Println("Warning: failed to delete stale login throttles:", err)
+		}
+	}()
+}
@@ -0,0 +1,480 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/token"
+	"github.com/qhh/ecm/util"
+)
+
+const (
+	// mfaTokenDuration is how long a user has to enter their second factor after the password
+	mfaTokenDuration = 5 * time.Minute
+
+	// recoveryCodeCount is the number of recovery codes handed out when 2FA is enabled
+	recoveryCodeCount = 10
+)
+
+var errInvalidMFACode = errors.New("invalid two-factor authentication code")
+
+type mfaChallengeResponse struct {
+	MFARequired           bool      `json:"mfa_required"`
+	MFAEnrollmentRequired bool      `json:"mfa_enrollment_required"`
+	MFAToken              string    `json:"mfa_token"`
+	MFATokenExpiresAt     time.Time `json:"mfa_token_expires_at"`
+}
+
+type totpEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// mfaRequiredForRole reports whether accounts with the role may not sign in without 2FA
+func (server *Server) mfaRequiredForRole(role db.UserRole) bool {
+	return server.config.MFARequired && (role == db.UserRoleAdmin || role == db.UserRoleSeller)
+}
+
+// mfaChallenge returns a challenge the user has to answer with a second factor before a session is
+// created, or false if the password alone is enough for this user
+func (server *Server) mfaChallenge(ctx *gin.Context, user db.User) (mfaChallengeResponse, bool, error) {
+	credential, err := server.store.GetTOTPCredential(ctx, user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return mfaChallengeResponse{}, false, err
+	}
+
+	enabled := err == nil && credential.ConfirmedAt.Valid
+	if !enabled && !server.mfaRequiredForRole(user.Role) {
+		return mfaChallengeResponse{}, false, nil
+	}
+
+	mfaToken, payload, err := server.tokenMaker.CreateToken(
+		token.TokenTypeMFA,
+		uuid.Nil,
+		user.ID,
+		user.Username,
+		string(user.Role),
+		mfaTokenDuration,
+	)
+	if err != nil {
+		return mfaChallengeResponse{}, false, err
+	}
+
+	return mfaChallengeResponse{
+		MFARequired:           true,
+		MFAEnrollmentRequired: !enabled,
+		MFAToken:              mfaToken,
+		MFATokenExpiresAt:     payload.ExpiredAt,
+	}, true, nil
+}
+
+// verifyMFAToken checks an MFA challenge token. It returns false if a response has already been written.
+func (server *Server) verifyMFAToken(ctx *gin.Context, mfaToken string) (*token.Payload, bool) {
+	payload, err := server.tokenMaker.VerifyToken(mfaToken, token.TokenTypeMFA)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return nil, false
+	}
+
+	revoked, err := server.revocations.IsRevoked(ctx, payload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+	if revoked {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("token has been revoked")))
+		return nil, false
+	}
+
+	return payload, true
+}
+
+// checkTOTPCode validates a code from the user's authenticator app. A code is accepted only once.
+func (server *Server) checkTOTPCode(ctx *gin.Context, credential db.TotpCredential, code string) (bool, error) {
+	step, ok := util.ValidateTOTP(credential.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	rows, err := server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+		UserID:       credential.UserID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code
+func (server *Server) checkSecondFactor(ctx *gin.Context, credential db.TotpCredential, code string) (bool, error) {
+	if len(code) == 6 {
+		return server.checkTOTPCode(ctx, credential, code)
+	}
+
+	rows, err := server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		UserID:   credential.UserID,
+		CodeHash: util.HashToken(util.NormalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// startTOTPEnrollment stores a new pending secret for the user
+func (server *Server) startTOTPEnrollment(ctx *gin.Context, user db.User) (totpEnrollmentResponse, error) {
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return totpEnrollmentResponse{}, err
+	}
+
+	credential, err := server.store.UpsertPendingTOTPCredential(ctx, db.UpsertPendingTOTPCredentialParams{
+		UserID: user.ID,
+		Secret: secret,
+	})
+	if err != nil {
+		return totpEnrollmentResponse{}, err
+	}
+
+	return totpEnrollmentResponse{
+		Secret:     credential.Secret,
+		OtpauthURI: util.TOTPURI(server.config.MFAIssuer, user.Username, credential.Secret),
+	}, nil
+}
+
+// enableTOTP confirms the user's pending secret and replaces their recovery codes
+func (server *Server) enableTOTP(ctx *gin.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := util.RandomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = server.store.ConfirmTOTPCredentialWithTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = server.store.DeleteRecoveryCodesWithTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, code := range codes {
+		err = server.store.CreateRecoveryCodeWithTx(ctx, tx, db.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: util.HashToken(code),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp, err := server.startTOTPEnrollment(ctx, user)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(errors.New("two-factor authentication is already enabled")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	credential, err := server.store.GetTOTPCredential(ctx, authPayload.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("two-factor enrollment has not been started")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if credential.ConfirmedAt.Valid {
+		ctx.JSON(http.StatusConflict, errorResponse(errors.New("two-factor authentication is already enabled")))
+		return
+	}
+
+	ok, err := server.checkTOTPCode(ctx, credential, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidMFACode))
+		return
+	}
+
+	codes, err := server.enableTOTP(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+type disableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+func (server *Server) disableTOTP(ctx *gin.Context) {
+	var req disableTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if server.mfaRequiredForRole(user.Role) {
+		err := errors.New("two-factor authentication is mandatory for your role")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	// Turning off the second factor must not allow guessing the password any faster than the login does
+	usernameKey := usernameThrottleKey(user.Username)
+	ipKey := ipThrottleKey(ctx.ClientIP())
+
+	retryAfter, err := server.beginLoginAttempt(ctx, usernameKey, ipKey)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if retryAfter > 0 {
+		tooManyLoginAttempts(ctx, retryAfter)
+		return
+	}
+
+	err = util.CheckPassword(req.Password, user.PasswordHash)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+
+	err = server.loginSucceeded(ctx, usernameKey, ipKey)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	credential, err := server.store.GetTOTPCredential(ctx, user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == sql.ErrNoRows || !credential.ConfirmedAt.Valid {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("two-factor authentication is not enabled")))
+		return
+	}
+
+	ok, err := server.checkSecondFactor(ctx, credential, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		server.recordLoginFailure(ctx, usernameKey, ipKey)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFACode))
+		return
+	}
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	err = server.store.DeleteTOTPCredentialWithTx(ctx, tx, user.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.DeleteRecoveryCodesWithTx(ctx, tx, user.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// loginMFA completes a login with a TOTP or recovery code. The challenge token can only be used
+// once, so a wrong code means starting over with the password.
+func (server *Server) loginMFA(ctx *gin.Context) {
+	var req loginMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, ok := server.verifyMFAToken(ctx, req.MFAToken)
+	if !ok {
+		return
+	}
+
+	credential, err := server.store.GetTOTPCredential(ctx, payload.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := errors.New("two-factor enrollment has not been started")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.revocations.Revoke(ctx, payload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// A pending enrollment can only be completed with a code from the new secret
+	if credential.ConfirmedAt.Valid {
+		ok, err = server.checkSecondFactor(ctx, credential, req.Code)
+	} else {
+		ok, err = server.checkTOTPCode(ctx, credential, req.Code)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		server.recordLoginFailure(ctx, usernameThrottleKey(payload.Username), ipThrottleKey(ctx.ClientIP()))
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFACode))
+		return
+	}
+
+	var recoveryCodes []string
+	if !credential.ConfirmedAt.Valid {
+		recoveryCodes, err = server.enableTOTP(ctx, credential.UserID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	user, err := server.store.GetUser(ctx, payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp, err := server.createSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	rsp.RecoveryCodes = recoveryCodes
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type loginMFAEnrollRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// loginMFAEnroll lets a user whose role requires 2FA enroll before their first login with it
+func (server *Server) loginMFAEnroll(ctx *gin.Context) {
+	var req loginMFAEnrollRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, ok := server.verifyMFAToken(ctx, req.MFAToken)
+	if !ok {
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp, err := server.startTOTPEnrollment(ctx, user)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(errors.New("two-factor authentication is already enabled")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
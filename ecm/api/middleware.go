@@ -3,9 +3,11 @@ package api
 import (
 	"errors"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/qhh/ecm/token"
 )
 
@@ -13,10 +15,30 @@ const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
 	authorizationPayloadKey = "authorization_payload"
+	requestIDHeaderKey      = "X-Request-ID"
+	requestIDKey            = "request_id"
 )
 
+// validRequestID matches the request IDs accepted from clients and proxies
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestIDMiddleware gives every request an ID, which is sent back in the X-Request-ID header and
+// recorded with audit events. An ID set by a proxy in front of the server is kept if it looks valid.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeaderKey)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx.Set(requestIDKey, requestID)
+		ctx.Header(requestIDHeaderKey, requestID)
+		ctx.Next()
+	}
+}
+
 // authMiddleware creates a gin middleware for authorization
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+func authMiddleware(tokenMaker token.Maker, revocations *revocationStore) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
@@ -40,28 +62,24 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		}
 
 		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		payload, err := tokenMaker.VerifyToken(accessToken, token.TokenTypeAccess)
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
-		ctx.Set(authorizationPayloadKey, payload)
-		ctx.Next()
-	}
-}
-
-// requireRole creates a middleware that checks if the user has the required role
-func requireRole(requiredRole string) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-
-		if authPayload.Role != requiredRole && authPayload.Role != "admin" {
-			err := errors.New("unauthorized: insufficient permissions")
-			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+		revoked, err := revocations.IsRevoked(ctx, payload)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if revoked {
+			err := errors.New("token has been revoked")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
+		ctx.Set(authorizationPayloadKey, payload)
 		ctx.Next()
 	}
 }
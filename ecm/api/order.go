@@ -3,54 +3,58 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
-	"strconv"
+	"sort"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/money"
 	"github.com/qhh/ecm/token"
 )
 
 type createOrderRequest struct {
-	ShippingAddress string `json:"shipping_address" binding:"required"`
-	PaymentMethod   string `json:"payment_method" binding:"required"`
+	ShippingAddress string        `json:"shipping_address" binding:"required"`
+	PaymentMethod   string        `json:"payment_method" binding:"required"`
+	ConfirmedTotal  *money.Amount `json:"confirmed_total" binding:"omitempty,min=0"`
+	paymentDetails
 }
 
 type orderItemResponse struct {
-	ID          uuid.UUID `json:"id"`
-	ProductID   uuid.UUID `json:"product_id"`
-	ProductName string    `json:"product_name"`
-	Quantity    int32     `json:"quantity"`
-	Price       float64   `json:"price"`
-	ImageURL    string    `json:"image_url"`
-	CreatedAt   string    `json:"created_at"`
+	ID          uuid.UUID    `json:"id"`
+	ShopOrderID uuid.UUID    `json:"shop_order_id"`
+	ProductID   uuid.UUID    `json:"product_id"`
+	ProductName string       `json:"product_name"`
+	Quantity    int32        `json:"quantity"`
+	Price       money.Amount `json:"price"`
+	ImageURL    string       `json:"image_url"`
+	CreatedAt   string       `json:"created_at"`
 }
 
 type orderResponse struct {
-	ID              uuid.UUID           `json:"id"`
-	UserID          uuid.UUID           `json:"user_id"`
-	Status          string              `json:"status"`
-	TotalAmount     float64             `json:"total_amount"`
-	ShippingAddress string              `json:"shipping_address"`
-	PaymentMethod   string              `json:"payment_method"`
-	CreatedAt       string              `json:"created_at"`
-	UpdatedAt       string              `json:"updated_at"`
-	Items           []orderItemResponse `json:"items,omitempty"`
+	ID              uuid.UUID                   `json:"id"`
+	UserID          uuid.UUID                   `json:"user_id"`
+	Status          string                      `json:"status"`
+	TotalAmount     money.Amount                `json:"total_amount"`
+	ShippingAddress string                      `json:"shipping_address"`
+	PaymentMethod   string                      `json:"payment_method"`
+	CreatedAt       string                      `json:"created_at"`
+	UpdatedAt       string                      `json:"updated_at"`
+	Items           []orderItemResponse         `json:"items,omitempty"`
+	ShopOrders      []shopOrderResponse         `json:"shop_orders,omitempty"`
+	StatusHistory   []orderStatusChangeResponse `json:"status_history,omitempty"`
+	Payment         *paymentResponse            `json:"payment,omitempty"`
 }
 
 func newOrderResponse(order db.Order) orderResponse {
-	// Handle total_amount conversion properly based on its actual type in the DB
-	var totalAmount float64
-
-	// Since TotalAmount is a string, convert it directly
-	totalAmount, _ = strconv.ParseFloat(order.TotalAmount, 64)
-
 	return orderResponse{
 		ID:              order.ID,
 		UserID:          order.UserID,
 		Status:          string(order.Status),
-		TotalAmount:     totalAmount,
+		TotalAmount:     order.TotalAmount,
 		ShippingAddress: order.ShippingAddress,
 		PaymentMethod:   order.PaymentMethod,
 		CreatedAt:       order.CreatedAt.String(),
@@ -59,12 +63,6 @@ func newOrderResponse(order db.Order) orderResponse {
 }
 
 func newOrderItemResponse(item db.GetOrderItemsRow) orderItemResponse {
-	// Convert price based on its actual type
-	var price float64
-
-	// Handle price conversion - assuming item.Price is a string in the DB
-	price, _ = strconv.ParseFloat(item.Price, 64)
-
 	// Set quantity directly as it's already int32
 	quantity := item.Quantity
 
@@ -76,10 +74,11 @@ func newOrderItemResponse(item db.GetOrderItemsRow) orderItemResponse {
 
 	return orderItemResponse{
 		ID:          item.ID,
+		ShopOrderID: item.ShopOrderID,
 		ProductID:   item.ProductID,
 		ProductName: item.ProductName,
 		Quantity:    quantity,
-		Price:       price,
+		Price:       item.Price,
 		ImageURL:    imageURL,
 		CreatedAt:   item.CreatedAt.String(),
 	}
@@ -92,8 +91,17 @@ func (server *Server) createOrder(ctx *gin.Context) {
 		return
 	}
 
+	if err := req.check(req.PaymentMethod); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
+	if !server.requireVerifiedEmail(ctx, authPayload.UserID) {
+		return
+	}
+
 	// Get cart items
 	cartItems, err := server.store.GetCartItems(ctx, authPayload.UserID)
 	if err != nil {
@@ -106,21 +114,6 @@ func (server *Server) createOrder(ctx *gin.Context) {
 		return
 	}
 
-	// Calculate total amount
-	var totalAmount float64
-	for _, item := range cartItems {
-		// Convert price based on its actual type
-		var itemPrice float64
-		// Assuming item.Price is already a string based on the error message
-		itemPrice, _ = strconv.ParseFloat(item.Price, 64)
-
-		// Get quantity as an int32
-		var quantity int32
-		quantity = item.Quantity // Assuming item.Quantity is already an int32
-
-		totalAmount += itemPrice * float64(quantity)
-	}
-
 	// Create transaction
 	tx, err := server.store.BeginTx(ctx)
 	if err != nil {
@@ -129,10 +122,55 @@ func (server *Server) createOrder(ctx *gin.Context) {
 	}
 	defer tx.Rollback()
 
+	products, shortages, err := server.takeStock(ctx, tx, cartItems)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if len(shortages) > 0 {
+		rsp := errorResponse(errInsufficientStock)
+		rsp["items"] = shortages
+		ctx.JSON(http.StatusConflict, rsp)
+		return
+	}
+
+	// Calculate total amount from the locked products, so a seller changing a price now cannot change
+	// what the buyer pays
+	var totalAmount money.Amount
+	var priceChanges []priceChange
+	var shopIDs []uuid.UUID
+	subtotals := make(map[uuid.UUID]money.Amount)
+	for _, item := range cartItems {
+		product := products[item.ProductID]
+		totalAmount += product.Price.Mul(item.Quantity)
+
+		if _, ok := subtotals[product.ShopID]; !ok {
+			shopIDs = append(shopIDs, product.ShopID)
+		}
+		subtotals[product.ShopID] += product.Price.Mul(item.Quantity)
+
+		if product.Price != item.UnitPrice {
+			priceChanges = append(priceChanges, priceChange{
+				ProductID:   item.ProductID,
+				ProductName: item.ProductName,
+				OldPrice:    item.UnitPrice,
+				NewPrice:    product.Price,
+			})
+		}
+	}
+
+	if len(priceChanges) > 0 && !confirmsTotal(req.ConfirmedTotal, totalAmount) {
+		rsp := errorResponse(errPricesChanged)
+		rsp["items"] = priceChanges
+		rsp["total"] = totalAmount
+		ctx.JSON(http.StatusConflict, rsp)
+		return
+	}
+
 	// Create order
 	orderArg := db.CreateOrderParams{
 		UserID:          authPayload.UserID,
-		TotalAmount:     strconv.FormatFloat(totalAmount, 'f', 2, 64),
+		TotalAmount:     totalAmount,
 		ShippingAddress: req.ShippingAddress,
 		PaymentMethod:   req.PaymentMethod,
 	}
@@ -143,29 +181,55 @@ func (server *Server) createOrder(ctx *gin.Context) {
 		return
 	}
 
-	// Create order items and update product stock
-	for _, item := range cartItems {
-		// Create order item
-		itemArg := db.CreateOrderItemParams{
-			OrderID:   order.ID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     item.Price,
+	historyArg := db.CreateOrderStatusChangeParams{
+		OrderID:   order.ID,
+		ToStatus:  order.Status,
+		ChangedBy: uuid.NullUUID{UUID: authPayload.UserID, Valid: true},
+	}
+
+	err = server.store.CreateOrderStatusChangeWithTx(ctx, tx, historyArg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.createPayment(ctx, tx, order)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Split the order into one shop order per shop, for the sellers to fulfill
+	shopOrderIDs := make(map[uuid.UUID]uuid.UUID, len(shopIDs))
+	for _, shopID := range shopIDs {
+		shopOrderArg := db.CreateShopOrderParams{
+			OrderID:  order.ID,
+			ShopID:   shopID,
+			Subtotal: subtotals[shopID],
 		}
 
-		_, err = server.store.CreateOrderItemWithTx(ctx, tx, itemArg)
+		shopOrder, err := server.store.CreateShopOrderWithTx(ctx, tx, shopOrderArg)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 			return
 		}
+		shopOrderIDs[shopID] = shopOrder.ID
+	}
 
-		// Update product stock
-		stockArg := db.UpdateProductStockParams{
-			ID:            item.ProductID,
-			StockQuantity: -item.Quantity, // Decrease stock
+	// Create order items
+	for _, item := range cartItems {
+		product := products[item.ProductID]
+
+		// Create order item
+		itemArg := db.CreateOrderItemParams{
+			OrderID:     order.ID,
+			ShopOrderID: shopOrderIDs[product.ShopID],
+			ProductID:   item.ProductID,
+			Quantity:    item.Quantity,
+			Price:       product.Price,
 		}
 
-		_, err = server.store.UpdateProductStockWithTx(ctx, tx, stockArg)
+		_, err = server.store.CreateOrderItemWithTx(ctx, tx, itemArg)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 			return
@@ -186,6 +250,21 @@ func (server *Server) createOrder(ctx *gin.Context) {
 		return
 	}
 
+	server.audit(ctx, auditOrderCreate, auditTargetOrder, order.ID, nil, newOrderResponse(order))
+
+	// The order is placed either way. Until its payment is captured it stays pending, and the buyer can
+	// pay for it again.
+	_, err = server.attemptPayment(ctx, order, req.paymentDetails)
+	if err != nil {
+		log.Printf("Warning: failed to take payment for order %s: %v", order.ID, err)
+	}
+
+	paymentRsp, err := server.getPaymentResponse(ctx, order.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	// Get order items for response
 	orderItems, err := server.store.GetOrderItems(ctx, order.ID)
 	if err != nil {
@@ -200,10 +279,82 @@ func (server *Server) createOrder(ctx *gin.Context) {
 		itemsResponse[i] = newOrderItemResponse(item)
 	}
 	response.Items = itemsResponse
+	response.Payment = &paymentRsp
 
 	ctx.JSON(http.StatusCreated, response)
 }
 
+var errInsufficientStock = errors.New("there is not enough stock left for some items in your cart")
+
+// stockShortage is a cart item that there is not enough stock left for
+type stockShortage struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	Requested   int32     `json:"requested"`
+	Available   int32     `json:"available"`
+}
+
+// takeStock takes the stock for every cart item. A product's stock is only decreased if enough is
+// left, and its row stays locked until the transaction ends, so concurrent orders cannot oversell it.
+// Products are taken in a fixed order so that two orders for the same products cannot deadlock. It
+// returns the locked products by ID. If some items are short of stock they are returned instead, and
+// the transaction must be rolled back.
+func (server *Server) takeStock(ctx *gin.Context, tx *sql.Tx, cartItems []db.GetCartItemsRow) (map[uuid.UUID]db.Product, []stockShortage, error) {
+	items := make([]db.GetCartItemsRow, len(cartItems))
+	copy(items, cartItems)
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ProductID.String() < items[j].ProductID.String()
+	})
+
+	products := make(map[uuid.UUID]db.Product, len(items))
+	var shortages []stockShortage
+	for _, item := range items {
+		arg := db.DecreaseProductStockParams{
+			ID:       item.ProductID,
+			Quantity: item.Quantity,
+		}
+
+		product, err := server.store.DecreaseProductStockWithTx(ctx, tx, arg)
+		if err == nil {
+			products[item.ProductID] = product
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return nil, nil, err
+		}
+
+		product, err = server.store.GetProduct(ctx, item.ProductID)
+		if err != nil {
+			return nil, nil, err
+		}
+		shortages = append(shortages, stockShortage{
+			ProductID:   item.ProductID,
+			ProductName: item.ProductName,
+			Requested:   item.Quantity,
+			Available:   product.StockQuantity,
+		})
+	}
+	if len(shortages) > 0 {
+		return nil, shortages, nil
+	}
+	return products, nil, nil
+}
+
+var errPricesChanged = errors.New("the prices of some items in your cart have changed since they were added")
+
+// priceChange is a cart item whose price has changed since it was added to the cart
+type priceChange struct {
+	ProductID   uuid.UUID    `json:"product_id"`
+	ProductName string       `json:"product_name"`
+	OldPrice    money.Amount `json:"old_price"`
+	NewPrice    money.Amount `json:"new_price"`
+}
+
+// confirmsTotal reports whether the buyer confirmed paying the given total
+func confirmsTotal(confirmed *money.Amount, total money.Amount) bool {
+	return confirmed != nil && *confirmed == total
+}
+
 func (server *Server) getOrder(ctx *gin.Context) {
 	id, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
@@ -221,14 +372,6 @@ func (server *Server) getOrder(ctx *gin.Context) {
 		return
 	}
 
-	// Check authorization
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if order.UserID != authPayload.UserID && authPayload.Role != "admin" {
-		err := errors.New("you don't have permission to view this order")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
-		return
-	}
-
 	// Get order items
 	orderItems, err := server.store.GetOrderItems(ctx, id)
 	if err != nil {
@@ -244,6 +387,45 @@ func (server *Server) getOrder(ctx *gin.Context) {
 	}
 	response.Items = itemsResponse
 
+	shopOrders, err := server.store.ListShopOrdersByOrder(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response.ShopOrders = make([]shopOrderResponse, len(shopOrders))
+	for i, row := range shopOrders {
+		shopOrder := db.ShopOrder{
+			ID:        row.ID,
+			OrderID:   row.OrderID,
+			ShopID:    row.ShopID,
+			Status:    row.Status,
+			Subtotal:  row.Subtotal,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+		}
+		response.ShopOrders[i] = newShopOrderResponse(shopOrder)
+		response.ShopOrders[i].ShopName = row.ShopName
+	}
+
+	history, err := server.store.ListOrderStatusHistory(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response.StatusHistory = make([]orderStatusChangeResponse, len(history))
+	for i, change := range history {
+		response.StatusHistory[i] = newOrderStatusChangeResponse(change)
+	}
+
+	paymentRsp, err := server.getPaymentResponse(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response.Payment = &paymentRsp
+
 	ctx.JSON(http.StatusOK, response)
 }
 
@@ -264,37 +446,228 @@ func (server *Server) getUserOrders(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response)
 }
 
+type listAllOrdersRequest struct {
+	PageID   int32     `form:"page_id" binding:"required,min=1"`
+	PageSize int32     `form:"page_size" binding:"required,min=5,max=100"`
+	Status   string    `form:"status" binding:"omitempty,oneof=pending processing shipped delivered cancelled"`
+	UserID   string    `form:"user_id" binding:"omitempty,uuid"`
+	ShopID   string    `form:"shop_id" binding:"omitempty,uuid"`
+	From     time.Time `form:"from" time_format:"2006-01-02" time_utc:"1"`
+	To       time.Time `form:"to" time_format:"2006-01-02" time_utc:"1"`
+	MinTotal string    `form:"min_total" binding:"max=20"`
+	MaxTotal string    `form:"max_total" binding:"max=20"`
+	Sort     string    `form:"sort" binding:"omitempty,oneof=created_at total_amount"`
+	Order    string    `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+type adminOrderResponse struct {
+	orderResponse
+	Username  string `json:"username"`
+	ItemCount int32  `json:"item_count"`
+}
+
+// listAllOrders lists the orders of every user, newest first unless asked otherwise
+func (server *Server) listAllOrders(ctx *gin.Context) {
+	var req listAllOrdersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	minTotal, err := parseNullAmount(req.MinTotal)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	maxTotal, err := parseNullAmount(req.MaxTotal)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	sort := req.Sort
+	if sort == "" {
+		sort = "created_at"
+	}
+	direction := req.Order
+	if direction == "" {
+		direction = "desc"
+	}
+
+	arg := db.ListAllOrdersParams{
+		Status: db.NullOrderStatus{
+			OrderStatus: db.OrderStatus(req.Status),
+			Valid:       req.Status != "",
+		},
+		UserID:      parseNullUUID(req.UserID),
+		ShopID:      parseNullUUID(req.ShopID),
+		CreatedFrom: sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		// The end date is inclusive
+		CreatedTo: sql.NullTime{Time: req.To.AddDate(0, 0, 1), Valid: !req.To.IsZero()},
+		MinTotal:  minTotal,
+		MaxTotal:  maxTotal,
+		SortBy:    sort + "_" + direction,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	}
+
+	orders, err := server.store.ListAllOrders(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]adminOrderResponse, len(orders))
+	for i, row := range orders {
+		order := db.Order{
+			ID:              row.ID,
+			UserID:          row.UserID,
+			Status:          row.Status,
+			TotalAmount:     row.TotalAmount,
+			ShippingAddress: row.ShippingAddress,
+			PaymentMethod:   row.PaymentMethod,
+			CreatedAt:       row.CreatedAt,
+			UpdatedAt:       row.UpdatedAt,
+		}
+		response[i] = adminOrderResponse{
+			orderResponse: newOrderResponse(order),
+			Username:      row.Username,
+			ItemCount:     row.ItemCount,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// parseNullUUID parses an ID that binding has already validated, treating an empty one as NULL
+func parseNullUUID(id string) uuid.NullUUID {
+	if id == "" {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: uuid.MustParse(id), Valid: true}
+}
+
+// parseNullAmount parses an optional amount that cannot be negative, treating an empty one as NULL
+func parseNullAmount(text string) (money.NullAmount, error) {
+	if text == "" {
+		return money.NullAmount{}, nil
+	}
+
+	amount, err := money.Parse(text)
+	if err != nil {
+		return money.NullAmount{}, err
+	}
+	if amount < 0 {
+		return money.NullAmount{}, fmt.Errorf("%w: %q cannot be negative", money.ErrInvalidAmount, text)
+	}
+	return money.NullAmount{Amount: amount, Valid: true}, nil
+}
+
 type updateOrderStatusRequest struct {
 	Status string `json:"status" binding:"required,oneof=pending processing shipped delivered cancelled"`
+	Note   string `json:"note" binding:"max=500"`
+	shipmentDetails
 }
 
+// updateOrderStatus moves an order to the next status. Changes that orderStatusTransitions does not
+// allow are rejected with 409 Conflict, and so are orders that have not been paid yet, unless they
+// are cancelled. Shipping an order can come with the carrier and tracking
+// number, otherwise the parcels are booked with the server's carrier.
 func (server *Server) updateOrderStatus(ctx *gin.Context) {
-	// Only admin can update order status
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateOrderStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !req.shipmentDetails.isEmpty() && req.Status != string(db.OrderStatusShipped) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errShipmentDetailsNotShipped))
+		return
+	}
+
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if authPayload.Role != "admin" {
-		err := errors.New("only admin can update order status")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := server.store.GetOrderForUpdateWithTx(ctx, tx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("order not found")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	order, err := server.changeOrderStatus(ctx, tx, before, db.OrderStatus(req.Status), authPayload.UserID, req.Note, req.shipmentDetails)
+	if err != nil {
+		var transitionErr *orderStatusTransitionError
+		if errors.As(err, &transitionErr) || errors.Is(err, errOrderNotPaid) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	switch order.Status {
+	case db.OrderStatusShipped:
+		server.dispatchShipments(ctx, order.ID)
+	case db.OrderStatusCancelled:
+		server.settlePayment(ctx, order.ID)
+	}
+
+	rsp := newOrderResponse(order)
+	server.audit(ctx, auditOrderStatusUpdate, auditTargetOrder, order.ID, newOrderResponse(before), rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type cancelOrderRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// cancelOrder lets buyers cancel their own order until it ships. The items go back in stock and the
+// reason is kept in the order's status history.
+func (server *Server) cancelOrder(ctx *gin.Context) {
 	id, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	var req updateOrderStatusRequest
+	var req cancelOrderRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	arg := db.UpdateOrderStatusParams{
-		ID:     id,
-		Status: db.OrderStatus(req.Status),
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
+	defer tx.Rollback()
 
-	order, err := server.store.UpdateOrderStatus(ctx, arg)
+	before, err := server.store.GetOrderForUpdateWithTx(ctx, tx, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("order not found")))
@@ -304,5 +677,31 @@ func (server *Server) updateOrderStatus(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, newOrderResponse(order))
+	order, err := server.changeOrderStatus(ctx, tx, before, db.OrderStatusCancelled, authPayload.UserID, req.Reason, shipmentDetails{})
+	if err != nil {
+		var transitionErr *orderStatusTransitionError
+		if errors.As(err, &transitionErr) {
+			if transitionErr.shopOrderID != uuid.Nil {
+				ctx.JSON(http.StatusConflict, errorResponse(errors.New("part of this order has already shipped, so it can no longer be cancelled")))
+				return
+			}
+			ctx.JSON(http.StatusConflict, errorResponse(errors.New("only pending and processing orders can be cancelled")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.settlePayment(ctx, order.ID)
+
+	rsp := newOrderResponse(order)
+	server.audit(ctx, auditOrderCancel, auditTargetOrder, order.ID, newOrderResponse(before), rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
 }
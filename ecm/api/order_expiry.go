@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+)
+
+const (
+	// unpaidOrderSweepInterval is how often orders are checked for an expired payment
+	unpaidOrderSweepInterval = 5 * time.Minute
+	// unpaidOrderSweepLimit is how many expired orders are cancelled in one sweep
+	unpaidOrderSweepLimit = 100
+)
+
+// expireUnpaidOrders cancels, every unpaidOrderSweepInterval, the pending orders that were placed more
+// than UNPAID_ORDER_EXPIRY ago and are still not paid, so that the stock they hold goes back on sale.
+// It runs for as long as the server does.
+func (server *Server) expireUnpaidOrders(expiry time.Duration) {
+	ticker := time.NewTicker(unpaidOrderSweepInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		server.sweepUnpaidOrders(context.Background(), now.Add(-expiry))
+	}
+}
+
+// sweepUnpaidOrders cancels the unpaid pending orders placed before placedBefore, each in its own
+// transaction
+func (server *Server) sweepUnpaidOrders(ctx context.Context, placedBefore time.Time) {
+	arg := db.ListExpiredUnpaidOrderIDsParams{
+		CreatedAt: placedBefore,
+		Limit:     unpaidOrderSweepLimit,
+	}
+
+	orderIDs, err := server.store.ListExpiredUnpaidOrderIDs(ctx, arg)
+	if err != nil {
+		log.Println("Warning: failed to list expired unpaid orders:", err)
+		return
+	}
+
+	for _, orderID := range orderIDs {
+		cancelled, err := server.cancelUnpaidOrder(ctx, orderID)
+		if err != nil {
+			log.Printf("Warning: failed to cancel unpaid order %s: %v", orderID, err)
+			continue
+		}
+		if cancelled {
+			server.settlePayment(ctx, orderID)
+		}
+	}
+}
+
+// cancelUnpaidOrder cancels an order whose payment expired, which puts its items back in stock. It
+// reports false if the order was paid or changed since it was listed.
+func (server *Server) cancelUnpaidOrder(ctx context.Context, orderID uuid.UUID) (bool, error) {
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	order, err := server.store.GetOrderForUpdateWithTx(ctx, tx, orderID)
+	if err != nil {
+		return false, err
+	}
+	if order.Status != db.OrderStatusPending {
+		return false, nil
+	}
+
+	// Every other change to the order's shop orders waits for the order's lock, so the payment can be
+	// locked ahead of them here
+	current, err := server.store.GetPaymentByOrderForUpdateWithTx(ctx, tx, orderID)
+	if err != nil {
+		return false, err
+	}
+	if !payableStatuses[current.Status] {
+		return false, nil
+	}
+
+	note := "The order was not paid in time"
+	_, err = server.changeOrderStatus(ctx, tx, order, db.OrderStatusCancelled, uuid.Nil, note, shipmentDetails{})
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+)
+
+// orderStatusTransitions lists the statuses an order can move to from each status. Orders can only be
+// cancelled before they are shipped, and delivered and cancelled orders are final.
+var orderStatusTransitions = map[db.OrderStatus][]db.OrderStatus{
+	db.OrderStatusPending:    {db.OrderStatusProcessing, db.OrderStatusCancelled},
+	db.OrderStatusProcessing: {db.OrderStatusShipped, db.OrderStatusCancelled},
+	db.OrderStatusShipped:    {db.OrderStatusDelivered},
+	db.OrderStatusDelivered:  {},
+	db.OrderStatusCancelled:  {},
+}
+
+// orderStatusTransitionError rejects a status change that orderStatusTransitions does not allow. It
+// names the shop order when the change was refused for one of the order's parts.
+type orderStatusTransitionError struct {
+	shopOrderID uuid.UUID
+	from        db.OrderStatus
+	to          db.OrderStatus
+}
+
+func (e *orderStatusTransitionError) Error() string {
+	subject := "order"
+	if e.shopOrderID != uuid.Nil {
+		subject = "shop order " + e.shopOrderID.String()
+	}
+
+	allowed := orderStatusTransitions[e.from]
+	if len(allowed) == 0 {
+		return fmt.Sprintf("%s is %s and its status can no longer change", subject, e.from)
+	}
+
+	names := make([]string, len(allowed))
+	for i, status := range allowed {
+		names[i] = string(status)
+	}
+	return fmt.Sprintf("%s cannot go from %s to %s, only to %s", subject, e.from, e.to, strings.Join(names, " or "))
+}
+
+// canChangeOrderStatus reports whether an order can move from one status to another
+func canChangeOrderStatus(from db.OrderStatus, to db.OrderStatus) bool {
+	for _, status := range orderStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// orderStatusProgress ranks the statuses of orders that are not cancelled by how far along they are
+var orderStatusProgress = map[db.OrderStatus]int{
+	db.OrderStatusPending:    0,
+	db.OrderStatusProcessing: 1,
+	db.OrderStatusShipped:    2,
+	db.OrderStatusDelivered:  3,
+}
+
+// derivedOrderStatus works out an order's status from its shop orders. The order is only as far along
+// as the least advanced shop order that is not cancelled, except that it is processing rather than
+// pending once any of them has moved on. It is cancelled when all of them are.
+func derivedOrderStatus(shopOrders []db.ShopOrder) db.OrderStatus {
+	status := db.OrderStatusCancelled
+	started := false
+	for _, shopOrder := range shopOrders {
+		if shopOrder.Status == db.OrderStatusCancelled {
+			continue
+		}
+		if shopOrder.Status != db.OrderStatusPending {
+			started = true
+		}
+		if status == db.OrderStatusCancelled || orderStatusProgress[shopOrder.Status] < orderStatusProgress[status] {
+			status = shopOrder.Status
+		}
+	}
+
+	if status == db.OrderStatusPending && started {
+		return db.OrderStatusProcessing
+	}
+	return status
+}
+
+// changeOrderStatus moves an order and its shop orders to a new status and adds the changes to the
+// order's history, along with an optional note. Shop orders that already have the status or were
+// cancelled are left alone, and the change fails if any other one cannot make it. The order must have
+// been locked with GetOrderForUpdateWithTx in the same transaction, so that two changes cannot both
+// start from the same status. It returns an *orderStatusTransitionError if the change is not allowed.
+// Shop orders that are shipped get a shipment described by shipment.
+func (server *Server) changeOrderStatus(ctx context.Context, tx *sql.Tx, order db.Order, to db.OrderStatus, actorID uuid.UUID, note string, shipment shipmentDetails) (db.Order, error) {
+	if !canChangeOrderStatus(order.Status, to) {
+		return db.Order{}, &orderStatusTransitionError{from: order.Status, to: to}
+	}
+
+	shopOrders, err := server.store.ListShopOrdersByOrderForUpdateWithTx(ctx, tx, order.ID)
+	if err != nil {
+		return db.Order{}, err
+	}
+
+	for _, shopOrder := range shopOrders {
+		if shopOrder.Status == to || shopOrder.Status == db.OrderStatusCancelled {
+			continue
+		}
+
+		_, err = server.changeShopOrderStatus(ctx, tx, order, shopOrder, to, actorID, note, shipment)
+		if err != nil {
+			return db.Order{}, err
+		}
+	}
+
+	return server.recordOrderStatus(ctx, tx, order, to, actorID, note)
+}
+
+// changeShopOrderStatus moves one shop order to a new status and adds the change to its order's
+// history. It cannot leave pending until the order is paid, except to be cancelled. Shipping a shop
+// order creates its shipment, and cancelling it puts its items back in stock and takes its subtotal
+// off the payment. The shop order's order must have been locked in the same transaction and is passed
+// in as order, and its status should be updated with syncOrderStatus afterwards. Changes made by the
+// carrier rather than a user have no actorID.
+func (server *Server) changeShopOrderStatus(ctx context.Context, tx *sql.Tx, order db.Order, shopOrder db.ShopOrder, to db.OrderStatus, actorID uuid.UUID, note string, shipment shipmentDetails) (db.ShopOrder, error) {
+	if !canChangeOrderStatus(shopOrder.Status, to) {
+		return db.ShopOrder{}, &orderStatusTransitionError{shopOrderID: shopOrder.ID, from: shopOrder.Status, to: to}
+	}
+
+	if shopOrder.Status == db.OrderStatusPending && to != db.OrderStatusCancelled {
+		err := server.checkOrderPaid(ctx, tx, shopOrder.OrderID)
+		if err != nil {
+			return db.ShopOrder{}, err
+		}
+	}
+
+	arg := db.UpdateShopOrderStatusParams{
+		ID:     shopOrder.ID,
+		Status: to,
+	}
+
+	updated, err := server.store.UpdateShopOrderStatusWithTx(ctx, tx, arg)
+	if err != nil {
+		return db.ShopOrder{}, err
+	}
+
+	historyArg := db.CreateOrderStatusChangeParams{
+		OrderID:     shopOrder.OrderID,
+		ShopOrderID: uuid.NullUUID{UUID: shopOrder.ID, Valid: true},
+		FromStatus:  db.NullOrderStatus{OrderStatus: shopOrder.Status, Valid: true},
+		ToStatus:    to,
+		ChangedBy:   uuid.NullUUID{UUID: actorID, Valid: actorID != uuid.Nil},
+		Note: sql.NullString{
+			String: note,
+			Valid:  note != "",
+		},
+	}
+
+	err = server.store.CreateOrderStatusChangeWithTx(ctx, tx, historyArg)
+	if err != nil {
+		return db.ShopOrder{}, err
+	}
+
+	switch to {
+	case db.OrderStatusShipped:
+		err = server.shipShopOrder(ctx, tx, order, shopOrder, shipment)
+		if err != nil {
+			return db.ShopOrder{}, err
+		}
+	case db.OrderStatusCancelled:
+		err = server.restockShopOrderItems(ctx, tx, shopOrder.ID)
+		if err != nil {
+			return db.ShopOrder{}, err
+		}
+
+		err = server.settleShopOrderCancellation(ctx, tx, updated)
+		if err != nil {
+			return db.ShopOrder{}, err
+		}
+	}
+
+	return updated, nil
+}
+
+// syncOrderStatus gives an order the status that its shop orders add up to, if it does not have it yet
+func (server *Server) syncOrderStatus(ctx context.Context, tx *sql.Tx, order db.Order, actorID uuid.UUID, note string) (db.Order, error) {
+	shopOrders, err := server.store.ListShopOrdersByOrderForUpdateWithTx(ctx, tx, order.ID)
+	if err != nil {
+		return db.Order{}, err
+	}
+
+	status := derivedOrderStatus(shopOrders)
+	if status == order.Status {
+		return order, nil
+	}
+	return server.recordOrderStatus(ctx, tx, order, status, actorID, note)
+}
+
+// recordOrderStatus sets an order's own status and adds the change to its history
+func (server *Server) recordOrderStatus(ctx context.Context, tx *sql.Tx, order db.Order, to db.OrderStatus, actorID uuid.UUID, note string) (db.Order, error) {
+	arg := db.UpdateOrderStatusParams{
+		ID:     order.ID,
+		Status: to,
+	}
+
+	updated, err := server.store.UpdateOrderStatusWithTx(ctx, tx, arg)
+	if err != nil {
+		return db.Order{}, err
+	}
+
+	historyArg := db.CreateOrderStatusChangeParams{
+		OrderID:    order.ID,
+		FromStatus: db.NullOrderStatus{OrderStatus: order.Status, Valid: true},
+		ToStatus:   to,
+		ChangedBy:  uuid.NullUUID{UUID: actorID, Valid: actorID != uuid.Nil},
+		Note: sql.NullString{
+			String: note,
+			Valid:  note != "",
+		},
+	}
+
+	err = server.store.CreateOrderStatusChangeWithTx(ctx, tx, historyArg)
+	if err != nil {
+		return db.Order{}, err
+	}
+	return updated, nil
+}
+
+// restockShopOrderItems gives back the stock that createOrder took for a shop order's items
+func (server *Server) restockShopOrderItems(ctx context.Context, tx *sql.Tx, shopOrderID uuid.UUID) error {
+	items, err := server.store.GetShopOrderItemsWithTx(ctx, tx, shopOrderID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		arg := db.UpdateProductStockParams{
+			ID:            item.ProductID,
+			StockQuantity: item.Quantity,
+		}
+
+		_, err = server.store.UpdateProductStockWithTx(ctx, tx, arg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type orderStatusChangeResponse struct {
+	ShopOrderID       string `json:"shop_order_id,omitempty"`
+	FromStatus        string `json:"from_status,omitempty"`
+	ToStatus          string `json:"to_status"`
+	ChangedBy         string `json:"changed_by,omitempty"`
+	ChangedByUsername string `json:"changed_by_username,omitempty"`
+	Note              string `json:"note,omitempty"`
+	ChangedAt         string `json:"changed_at"`
+}
+
+func newOrderStatusChangeResponse(change db.ListOrderStatusHistoryRow) orderStatusChangeResponse {
+	rsp := orderStatusChangeResponse{
+		ToStatus:          string(change.ToStatus),
+		ChangedByUsername: change.ChangedByUsername.String,
+		Note:              change.Note.String,
+		ChangedAt:         change.CreatedAt.String(),
+	}
+	// The order was placed with the first change
+	if change.FromStatus.Valid {
+		rsp.FromStatus = string(change.FromStatus.OrderStatus)
+	}
+	if change.ChangedBy.Valid {
+		rsp.ChangedBy = change.ChangedBy.UUID.String()
+	}
+	if change.ShopOrderID.Valid {
+		rsp.ShopOrderID = change.ShopOrderID.UUID.String()
+	}
+	return rsp
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+)
+
+func TestCanChangeOrderStatus(t *testing.T) {
+	const (
+		pending    = db.OrderStatusPending
+		processing = db.OrderStatusProcessing
+		shipped    = db.OrderStatusShipped
+		delivered  = db.OrderStatusDelivered
+		cancelled  = db.OrderStatusCancelled
+	)
+
+	// Every pair of statuses, so that a transition added to orderStatusTransitions has to be added here
+	testCases := []struct {
+		from    db.OrderStatus
+		to      db.OrderStatus
+		allowed bool
+	}{
+		{from: pending, to: pending},
+		{from: pending, to: processing, allowed: true},
+		{from: pending, to: shipped},
+		{from: pending, to: delivered},
+		{from: pending, to: cancelled, allowed: true},
+
+		{from: processing, to: pending},
+		{from: processing, to: processing},
+		{from: processing, to: shipped, allowed: true},
+		{from: processing, to: delivered},
+		{from: processing, to: cancelled, allowed: true},
+
+		// Shipped goods have left the shop, so they come back through a return instead
+		{from: shipped, to: pending},
+		{from: shipped, to: processing},
+		{from: shipped, to: shipped},
+		{from: shipped, to: delivered, allowed: true},
+		{from: shipped, to: cancelled},
+
+		{from: delivered, to: pending},
+		{from: delivered, to: processing},
+		{from: delivered, to: shipped},
+		{from: delivered, to: delivered},
+		{from: delivered, to: cancelled},
+
+		// Cancelling puts the stock back and voids or refunds the payment, which cannot be undone
+		{from: cancelled, to: pending},
+		{from: cancelled, to: processing},
+		{from: cancelled, to: shipped},
+		{from: cancelled, to: delivered},
+		{from: cancelled, to: cancelled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.from)+"To"+string(tc.to), func(t *testing.T) {
+			if got := canChangeOrderStatus(tc.from, tc.to); got != tc.allowed {
+				t.Errorf("canChangeOrderStatus(%s, %s) = %v; want %v", tc.from, tc.to, got, tc.allowed)
+			}
+		})
+	}
+}
+
+func TestDerivedOrderStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		statuses []db.OrderStatus
+		want     db.OrderStatus
+	}{
+		{name: "OnePending", statuses: []db.OrderStatus{db.OrderStatusPending}, want: db.OrderStatusPending},
+		{name: "OneShipped", statuses: []db.OrderStatus{db.OrderStatusShipped}, want: db.OrderStatusShipped},
+		{name: "AllPending", statuses: []db.OrderStatus{db.OrderStatusPending, db.OrderStatusPending}, want: db.OrderStatusPending},
+		{name: "AllDelivered", statuses: []db.OrderStatus{db.OrderStatusDelivered, db.OrderStatusDelivered}, want: db.OrderStatusDelivered},
+		// The order is pending until a shop starts on it, and then processing until every shop gets further
+		{name: "PendingAndProcessing", statuses: []db.OrderStatus{db.OrderStatusPending, db.OrderStatusProcessing}, want: db.OrderStatusProcessing},
+		{name: "PendingAndShipped", statuses: []db.OrderStatus{db.OrderStatusShipped, db.OrderStatusPending}, want: db.OrderStatusProcessing},
+		{name: "PendingAndDelivered", statuses: []db.OrderStatus{db.OrderStatusPending, db.OrderStatusDelivered}, want: db.OrderStatusProcessing},
+		{name: "ProcessingAndShipped", statuses: []db.OrderStatus{db.OrderStatusShipped, db.OrderStatusProcessing}, want: db.OrderStatusProcessing},
+		{name: "ShippedAndDelivered", statuses: []db.OrderStatus{db.OrderStatusDelivered, db.OrderStatusShipped}, want: db.OrderStatusShipped},
+		{name: "EveryStatus", statuses: []db.OrderStatus{db.OrderStatusDelivered, db.OrderStatusShipped, db.OrderStatusCancelled, db.OrderStatusProcessing, db.OrderStatusPending}, want: db.OrderStatusProcessing},
+		// Cancelled shop orders do not hold the order back
+		{name: "PendingAndCancelled", statuses: []db.OrderStatus{db.OrderStatusCancelled, db.OrderStatusPending}, want: db.OrderStatusPending},
+		{name: "ShippedAndCancelled", statuses: []db.OrderStatus{db.OrderStatusShipped, db.OrderStatusCancelled}, want: db.OrderStatusShipped},
+		{name: "DeliveredAndCancelled", statuses: []db.OrderStatus{db.OrderStatusCancelled, db.OrderStatusDelivered, db.OrderStatusCancelled}, want: db.OrderStatusDelivered},
+		{name: "AllCancelled", statuses: []db.OrderStatus{db.OrderStatusCancelled, db.OrderStatusCancelled}, want: db.OrderStatusCancelled},
+		{name: "NoShopOrders", statuses: nil, want: db.OrderStatusCancelled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shopOrders := make([]db.ShopOrder, len(tc.statuses))
+			for i, status := range tc.statuses {
+				shopOrders[i] = db.ShopOrder{ID: uuid.New(), Status: status}
+			}
+
+			if got := derivedOrderStatus(shopOrders); got != tc.want {
+				t.Errorf("derivedOrderStatus(%v) = %s; want %s", tc.statuses, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOrderStatusTransitionError(t *testing.T) {
+	shopOrderID := uuid.New()
+
+	testCases := []struct {
+		name string
+		err  *orderStatusTransitionError
+		want string
+	}{
+		{
+			name: "Order",
+			err:  &orderStatusTransitionError{from: db.OrderStatusShipped, to: db.OrderStatusCancelled},
+			want: "order cannot go from shipped to cancelled, only to delivered",
+		},
+		{
+			name: "ShopOrder",
+			err:  &orderStatusTransitionError{shopOrderID: shopOrderID, from: db.OrderStatusPending, to: db.OrderStatusShipped},
+			want: "shop order " + shopOrderID.String() + " cannot go from pending to shipped, only to processing or cancelled",
+		},
+		{
+			name: "Final",
+			err:  &orderStatusTransitionError{from: db.OrderStatusCancelled, to: db.OrderStatusShipped},
+			want: "order is cancelled and its status can no longer change",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.err.Error(); got != tc.want {
+				t.Errorf("Error() = %q; want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/money"
+	"github.com/qhh/ecm/util"
+)
+
+// newTestStore connects to the migrated database at DB_SOURCE, and skips the test when there is none
+func newTestStore(t *testing.T) db.Store {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping database test in short mode")
+	}
+
+	config, err := util.LoadConfig()
+	if err != nil {
+		t.Fatalf("cannot load config: %v", err)
+	}
+
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	if err != nil {
+		t.Fatalf("cannot open database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := conn.Ping(); err != nil {
+		t.Skipf("database is not available: %v", err)
+	}
+	return db.NewStore(conn)
+}
+
+// createTestProduct creates a product with the given stock in a new shop, and deletes them after the test
+func createTestProduct(t *testing.T, store db.Store, stock int32) db.Product {
+	t.Helper()
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+
+	seller, err := store.CreateUser(ctx, db.CreateUserParams{
+		Username:     "seller_" + suffix,
+		Email:        "seller_" + suffix + "@example.com",
+		PasswordHash: "not a hash",
+		Role:         db.UserRoleSeller,
+	})
+	if err != nil {
+		t.Fatalf("cannot create seller: %v", err)
+	}
+	t.Cleanup(func() { store.DeleteUser(ctx, seller.ID) })
+
+	shop, err := store.CreateShop(ctx, db.CreateShopParams{Name: "Shop " + suffix, OwnerID: seller.ID})
+	if err != nil {
+		t.Fatalf("cannot create shop: %v", err)
+	}
+	t.Cleanup(func() { store.DeleteShop(ctx, shop.ID) })
+
+	category, err := store.CreateCategory(ctx, db.CreateCategoryParams{Name: "Category " + suffix})
+	if err != nil {
+		t.Fatalf("cannot create category: %v", err)
+	}
+	t.Cleanup(func() { store.DeleteCategory(ctx, category.ID) })
+
+	product, err := store.CreateProduct(ctx, db.CreateProductParams{
+		Name:          "Product " + suffix,
+		Price:         money.MustParse("9.99"),
+		StockQuantity: stock,
+		ShopID:        shop.ID,
+		CategoryID:    category.ID,
+	})
+	if err != nil {
+		t.Fatalf("cannot create product: %v", err)
+	}
+	t.Cleanup(func() { store.DeleteProduct(ctx, product.ID) })
+
+	return product
+}
+
+// TestTakeStockConcurrently places more orders for a product at once than it has stock for, each in
+// its own transaction like createOrder, and checks that exactly the stock is sold
+func TestTakeStockConcurrently(t *testing.T) {
+	const stock = 3
+	const orders = 10
+
+	store := newTestStore(t)
+	server := &Server{store: store}
+	product := createTestProduct(t, store, stock)
+
+	cartItems := []db.GetCartItemsRow{{
+		ProductID:   product.ID,
+		ProductName: product.Name,
+		Quantity:    1,
+		UnitPrice:   product.Price,
+		Price:       product.Price,
+	}}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, shortOfStock := 0, 0
+	errs := make(chan error, orders)
+
+	start := make(chan struct{})
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("POST", "/orders", nil)
+			<-start
+
+			tx, err := store.BeginTx(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer tx.Rollback()
+
+			products, shortages, err := server.takeStock(ctx, tx, cartItems)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			if len(shortages) > 0 {
+				if len(products) > 0 || shortages[0].ProductID != product.ID || shortages[0].Requested != 1 {
+					t.Errorf("unexpected shortage result: %+v, %+v", products, shortages)
+				}
+				mu.Lock()
+				shortOfStock++
+				mu.Unlock()
+				return
+			}
+
+			if err := tx.Commit(); err != nil {
+				errs <- err
+				return
+			}
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("order failed: %v", err)
+	}
+	if succeeded != stock {
+		t.Errorf("%d orders succeeded; want %d", succeeded, stock)
+	}
+	if shortOfStock != orders-stock {
+		t.Errorf("%d orders were short of stock; want %d", shortOfStock, orders-stock)
+	}
+
+	updated, err := store.GetProduct(context.Background(), product.ID)
+	if err != nil {
+		t.Fatalf("cannot get product: %v", err)
+	}
+	if updated.StockQuantity != 0 {
+		t.Errorf("stock is %d; want 0", updated.StockQuantity)
+	}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/mail"
+	"github.com/qhh/ecm/util"
+)
+
+// resetTokenSize is the number of random bytes in a password reset token
+const resetTokenSize = 32
+
+var (
+	// resetEmailThrottlePolicy limits the reset emails that can be asked for one address
+	resetEmailThrottlePolicy = loginThrottlePolicy{freeAttempts: 3}
+
+	// resetIPThrottlePolicy slows down a single client asking for resets of many addresses
+	resetIPThrottlePolicy = loginThrottlePolicy{freeAttempts: 20}
+)
+
+func resetEmailThrottleKey(email string) string {
+	return "reset:" + strings.ToLower(email)
+}
+
+func resetIPThrottleKey(ip string) string {
+	return "reset-ip:" + ip
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword emails a password reset link. It answers the same way, straight away, whether or
+// not the email belongs to an account, so that neither the answer nor its timing tells which emails
+// are registered. The link is created and sent in the background. Requests are throttled per email
+// and per client IP, with the same backoff as logins.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	retryAfter, err := server.loginThrottle.Attempt(ctx, resetIPThrottleKey(ctx.ClientIP()), resetIPThrottlePolicy)
+	if err == nil && retryAfter == 0 {
+		retryAfter, err = server.loginThrottle.Attempt(ctx, resetEmailThrottleKey(req.Email), resetEmailThrottlePolicy)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if retryAfter > 0 {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		ctx.Header("Retry-After", strconv.Itoa(seconds))
+		err := fmt.Errorf("too many password reset requests, try again in %d seconds", seconds)
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+		return
+	}
+
+	go func() {
+		if err := server.sendPasswordReset(context.Background(), req.Email); err != nil {
+			log.Println("Warning: failed to send password reset:", err)
+		}
+	}()
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "if the email belongs to an account, a password reset link has been sent"})
+}
+
+// sendPasswordReset emails a new reset link to the account with the given email, if there is one
+func (server *Server) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := server.store.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	resetToken, err := util.RandomToken(resetTokenSize)
+	if err != nil {
+		return err
+	}
+
+	arg := db.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: util.HashToken(resetToken),
+		ExpiresAt: time.Now().Add(server.config.PasswordResetTokenDuration).UTC(),
+	}
+
+	_, err = server.store.CreatePasswordResetToken(ctx, arg)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", server.config.AppBaseURL, url.QueryEscape(resetToken))
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can only be used once.\n\n%s\n\nIf you did not ask to reset your password, you can ignore this email.\n",
+			user.Username, server.config.PasswordResetTokenDuration, link,
+		),
+	}
+
+	return server.mailer.Send(ctx, msg)
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// resetPassword sets a new password using a reset token, then signs the user out everywhere
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	resetToken, err := server.store.ConsumePasswordResetTokenWithTx(ctx, tx, util.HashToken(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("reset token is invalid or has expired")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.UpdateUserPasswordParams{
+		ID:           resetToken.UserID,
+		PasswordHash: hashedPassword,
+	}
+
+	_, err = server.store.UpdateUserPasswordWithTx(ctx, tx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Any other link that was sent out is no longer needed
+	err = server.store.InvalidatePasswordResetTokensWithTx(ctx, tx, resetToken.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.revokeAllUserSessions(ctx, resetToken.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
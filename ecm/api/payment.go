@@ -0,0 +1,654 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/money"
+	"github.com/qhh/ecm/payment"
+)
+
+var (
+	errCardRequired         = errors.New("card is required for credit_card payments")
+	errOrderNotPaid         = errors.New("the order has not been paid yet")
+	errPaymentNotPayable    = errors.New("this order's payment can no longer be made")
+	errCaptureFailed        = errors.New("cannot capture payment")
+	errRefundExceedsPayment = errors.New("refund is larger than what is left of the payment")
+	errRefundPaidBack       = errors.New("refund has already been paid back")
+	errRefundByHand         = errors.New("refunds of this payment are paid back by hand")
+)
+
+// paymentCard is a card that the buyer pays with. It is passed on to the payment provider and only
+// its last four digits are kept.
+type paymentCard struct {
+	Number   string `json:"number" binding:"required,numeric,min=12,max=19"`
+	ExpMonth int    `json:"exp_month" binding:"required,min=1,max=12"`
+	ExpYear  int    `json:"exp_year" binding:"required,min=2000,max=2100"`
+	CVC      string `json:"cvc" binding:"required,numeric,min=3,max=4"`
+}
+
+// paymentDetails is what the buyer gives to pay for an order
+type paymentDetails struct {
+	Card *paymentCard `json:"card"`
+	// ThreeDSecure is the outcome of 3-D Secure, for payments that came back requires_action
+	ThreeDSecure string `json:"three_d_secure" binding:"max=100"`
+}
+
+// check makes sure that the details are enough to pay with method
+func (details paymentDetails) check(method string) error {
+	if method == "credit_card" && details.Card == nil {
+		return errCardRequired
+	}
+	return nil
+}
+
+// payableStatuses are the statuses of payments that can still be made
+var payableStatuses = map[db.PaymentStatus]bool{
+	db.PaymentStatusPending:        true,
+	db.PaymentStatusRequiresAction: true,
+	db.PaymentStatusDeclined:       true,
+	db.PaymentStatusAuthorized:     true,
+}
+
+// isPaid reports whether a payment has been captured. Refunded payments were captured first.
+func isPaid(status db.PaymentStatus) bool {
+	return status == db.PaymentStatusCaptured || status == db.PaymentStatusRefunded
+}
+
+// createPayment starts the payment of an order that was just placed
+func (server *Server) createPayment(ctx context.Context, tx *sql.Tx, order db.Order) (db.Payment, error) {
+	arg := db.CreatePaymentParams{
+		OrderID:  order.ID,
+		Provider: server.payments.Name(),
+		Amount:   order.TotalAmount,
+	}
+
+	created, err := server.store.CreatePaymentWithTx(ctx, tx, arg)
+	if err != nil {
+		return db.Payment{}, err
+	}
+
+	historyArg := db.CreatePaymentStatusChangeParams{
+		PaymentID: created.ID,
+		ToStatus:  created.Status,
+	}
+
+	err = server.store.CreatePaymentStatusChangeWithTx(ctx, tx, historyArg)
+	return created, err
+}
+
+// attemptPayment charges an order's payment with the server's payment provider. Payments are captured
+// as soon as they are authorized. Declined payments and payments that need 3-D Secure can be tried
+// again with new details. When the capture fails, the authorization is kept so that trying again only
+// captures it.
+func (server *Server) attemptPayment(ctx context.Context, order db.Order, details paymentDetails) (db.Payment, error) {
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		return db.Payment{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := server.store.GetPaymentByOrderForUpdateWithTx(ctx, tx, order.ID)
+	if err != nil {
+		return db.Payment{}, err
+	}
+	if !payableStatuses[current.Status] {
+		return db.Payment{}, fmt.Errorf("%w, it is %s", errPaymentNotPayable, current.Status)
+	}
+	if current.Amount <= 0 {
+		return db.Payment{}, fmt.Errorf("%w, nothing is left to pay", errPaymentNotPayable)
+	}
+	if current.Provider != server.payments.Name() {
+		return db.Payment{}, fmt.Errorf("payment was started with %s, which is not the configured provider", current.Provider)
+	}
+
+	if current.Status != db.PaymentStatusAuthorized {
+		req := payment.AuthorizeRequest{
+			Reference:    current.ID.String(),
+			Amount:       current.Amount,
+			Method:       order.PaymentMethod,
+			ThreeDSecure: details.ThreeDSecure,
+		}
+		cardLast4 := ""
+		if details.Card != nil {
+			req.Card = &payment.Card{
+				Number:   details.Card.Number,
+				ExpMonth: details.Card.ExpMonth,
+				ExpYear:  details.Card.ExpYear,
+				CVC:      details.Card.CVC,
+			}
+			cardLast4 = req.Card.Last4()
+		}
+
+		result, err := server.payments.Authorize(ctx, req)
+		if err != nil {
+			return db.Payment{}, fmt.Errorf("cannot authorize payment with %s: %w", server.payments.Name(), err)
+		}
+
+		current, err = server.applyPaymentResult(ctx, tx, current, result, cardLast4)
+		if err != nil {
+			return db.Payment{}, err
+		}
+	}
+
+	if current.Status == db.PaymentStatusAuthorized {
+		current, err = server.capturePayment(ctx, tx, current)
+		if errors.Is(err, errCaptureFailed) {
+			if commitErr := tx.Commit(); commitErr != nil {
+				return db.Payment{}, commitErr
+			}
+			return db.Payment{}, err
+		}
+		if err != nil {
+			return db.Payment{}, err
+		}
+	}
+
+	return current, tx.Commit()
+}
+
+// capturePayment collects an authorized payment. It returns errCaptureFailed if the provider could
+// not capture it, in which case the payment is still authorized.
+func (server *Server) capturePayment(ctx context.Context, tx *sql.Tx, authorized db.Payment) (db.Payment, error) {
+	result, err := server.payments.Capture(ctx, authorized.ProviderPaymentID.String, authorized.Amount)
+	if err != nil {
+		return authorized, fmt.Errorf("%w with %s: %v", errCaptureFailed, server.payments.Name(), err)
+	}
+	return server.applyPaymentResult(ctx, tx, authorized, result, "")
+}
+
+// applyPaymentResult records what the provider reported about a payment. The provider's message is
+// kept as the failure message of declined payments and as the note of the status change.
+func (server *Server) applyPaymentResult(ctx context.Context, tx *sql.Tx, before db.Payment, result payment.Result, cardLast4 string) (db.Payment, error) {
+	arg := db.UpdatePaymentParams{
+		ID:                before.ID,
+		ProviderPaymentID: before.ProviderPaymentID,
+		Status:            db.PaymentStatus(result.Status),
+		CardLast4:         before.CardLast4,
+	}
+	if result.PaymentID != "" {
+		arg.ProviderPaymentID = sql.NullString{String: result.PaymentID, Valid: true}
+	}
+	if cardLast4 != "" {
+		arg.CardLast4 = sql.NullString{String: cardLast4, Valid: true}
+	}
+	if result.Status == payment.StatusDeclined {
+		arg.FailureMessage = sql.NullString{String: result.Message, Valid: result.Message != ""}
+	}
+
+	after, err := server.store.UpdatePaymentWithTx(ctx, tx, arg)
+	if err != nil {
+		return db.Payment{}, err
+	}
+
+	err = server.recordPaymentStatus(ctx, tx, before, after, result.Message)
+	return after, err
+}
+
+// recordPaymentStatus adds a payment's status change to its history, if the status changed
+func (server *Server) recordPaymentStatus(ctx context.Context, tx *sql.Tx, before db.Payment, after db.Payment, note string) error {
+	if before.Status == after.Status {
+		return nil
+	}
+
+	arg := db.CreatePaymentStatusChangeParams{
+		PaymentID:  after.ID,
+		FromStatus: db.NullPaymentStatus{PaymentStatus: before.Status, Valid: true},
+		ToStatus:   after.Status,
+		Note: sql.NullString{
+			String: note,
+			Valid:  note != "",
+		},
+	}
+
+	return server.store.CreatePaymentStatusChangeWithTx(ctx, tx, arg)
+}
+
+// checkOrderPaid returns errOrderNotPaid unless the order's payment has been captured
+func (server *Server) checkOrderPaid(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) error {
+	current, err := server.store.GetPaymentByOrderForUpdateWithTx(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+	if !isPaid(current.Status) {
+		return fmt.Errorf("%w, its payment is %s", errOrderNotPaid, current.Status)
+	}
+	return nil
+}
+
+// settleShopOrderCancellation takes a cancelled shop order off its order's payment. Until the payment
+// is captured, the amount left to charge goes down by the shop order's subtotal. After that, the
+// subtotal is refunded. Nothing is asked of the payment provider here, as the transaction may still
+// roll back: settlePayment voids the authorization or pays the refund back once it commits.
+func (server *Server) settleShopOrderCancellation(ctx context.Context, tx *sql.Tx, shopOrder db.ShopOrder) error {
+	current, err := server.store.GetPaymentByOrderForUpdateWithTx(ctx, tx, shopOrder.OrderID)
+	if err != nil {
+		return err
+	}
+
+	switch current.Status {
+	case db.PaymentStatusCaptured:
+		_, err = server.refundPayment(ctx, tx, current, shopOrder.Subtotal, uuid.NullUUID{})
+		return err
+	case db.PaymentStatusRefunded, db.PaymentStatusVoided:
+		return nil
+	}
+
+	arg := db.ReducePaymentAmountParams{
+		ID:        current.ID,
+		Reduction: shopOrder.Subtotal,
+	}
+
+	reduced, err := server.store.ReducePaymentAmountWithTx(ctx, tx, arg)
+	if err != nil {
+		return err
+	}
+	// Authorizations made with the configured provider are voided by settlePayment, and those made
+	// with another provider are left to expire
+	if reduced.Amount > 0 || server.voidable(reduced) {
+		return nil
+	}
+
+	result := payment.Result{
+		Status:  payment.StatusVoided,
+		Message: "The order was cancelled",
+	}
+
+	_, err = server.applyPaymentResult(ctx, tx, reduced, result, "")
+	return err
+}
+
+// refundPayment records a refund of part of a captured payment. The refund is created pending, and
+// settlePayment pays it back through the provider once the transaction commits. Payments that were
+// not taken through the configured provider keep their refunds pending, to be paid back by hand.
+func (server *Server) refundPayment(ctx context.Context, tx *sql.Tx, current db.Payment, amount money.Amount, returnRequestID uuid.NullUUID) (db.Refund, error) {
+	paymentID := uuid.NullUUID{UUID: current.ID, Valid: true}
+
+	unsettled, err := server.store.GetUnsettledRefundAmountWithTx(ctx, tx, paymentID)
+	if err != nil {
+		return db.Refund{}, err
+	}
+
+	left := current.Amount - current.RefundedAmount - unsettled
+	if amount > left {
+		return db.Refund{}, fmt.Errorf("%w, only %s is left", errRefundExceedsPayment, left)
+	}
+
+	arg := db.CreateRefundParams{
+		OrderID:         current.OrderID,
+		ReturnRequestID: returnRequestID,
+		PaymentID:       paymentID,
+		Amount:          amount,
+		Status:          db.RefundStatusPending,
+	}
+
+	return server.store.CreateRefundWithTx(ctx, tx, arg)
+}
+
+// voidable reports whether a payment is an authorization with nothing left to charge, which
+// settlePayment voids with the provider
+func (server *Server) voidable(current db.Payment) bool {
+	return current.Status == db.PaymentStatusAuthorized && current.Amount <= 0 && current.Provider == server.payments.Name()
+}
+
+// refundable reports whether a payment's refunds are paid back through the configured provider
+func (server *Server) refundable(current db.Payment) bool {
+	return current.Provider == server.payments.Name() && current.ProviderPaymentID.Valid
+}
+
+// settlePayment carries out with the payment provider what a committed transaction decided about an
+// order's payment: an authorization with nothing left to charge is voided, and pending refunds are
+// paid back. Each of them gets a transaction of its own. Failures are only logged, as the change
+// they follow has already been made. A refund that the provider turns down is marked as failed and
+// can be retried.
+func (server *Server) settlePayment(ctx context.Context, orderID uuid.UUID) {
+	current, err := server.store.GetPaymentByOrder(ctx, orderID)
+	if err != nil {
+		log.Printf("Warning: cannot settle the payment of order %s: %v", orderID, err)
+		return
+	}
+
+	if server.voidable(current) {
+		err = server.voidPayment(ctx, orderID)
+		if err != nil {
+			log.Printf("Warning: cannot void payment %s: %v", current.ID, err)
+		}
+		return
+	}
+
+	if !server.refundable(current) {
+		return
+	}
+
+	refundIDs, err := server.store.ListPendingRefundIDs(ctx, uuid.NullUUID{UUID: current.ID, Valid: true})
+	if err != nil {
+		log.Printf("Warning: cannot list the pending refunds of payment %s: %v", current.ID, err)
+		return
+	}
+
+	for _, refundID := range refundIDs {
+		_, err = server.payOutRefund(ctx, orderID, refundID)
+		if err != nil {
+			log.Printf("Warning: cannot pay back refund %s: %v", refundID, err)
+		}
+	}
+}
+
+// voidPayment cancels the authorization of an order's payment with the provider, if there is still
+// nothing left to charge. The provider is called without holding the payment's lock. Voiding is
+// idempotent, so an authorization whose void was not saved is simply voided again the next time.
+func (server *Server) voidPayment(ctx context.Context, orderID uuid.UUID) error {
+	current, err := server.store.GetPaymentByOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if !server.voidable(current) {
+		return nil
+	}
+
+	result, err := server.payments.Void(ctx, current.ProviderPaymentID.String)
+	if err != nil {
+		return fmt.Errorf("cannot void payment with %s: %w", server.payments.Name(), err)
+	}
+	result.Message = "The order was cancelled"
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err = server.store.GetPaymentByOrderForUpdateWithTx(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+	if !server.voidable(current) {
+		return nil
+	}
+
+	_, err = server.applyPaymentResult(ctx, tx, current, result, "")
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getRefundForUpdate locks an order's payment and then one of its refunds, in that order
+func (server *Server) getRefundForUpdate(ctx context.Context, tx *sql.Tx, orderID uuid.UUID, refundID uuid.UUID) (db.Payment, db.Refund, error) {
+	current, err := server.store.GetPaymentByOrderForUpdateWithTx(ctx, tx, orderID)
+	if err != nil {
+		return db.Payment{}, db.Refund{}, err
+	}
+
+	refund, err := server.store.GetRefundForUpdateWithTx(ctx, tx, refundID)
+	if err != nil {
+		return db.Payment{}, db.Refund{}, err
+	}
+	if refund.PaymentID.UUID != current.ID {
+		return db.Payment{}, db.Refund{}, fmt.Errorf("refund %s is not of payment %s", refund.ID, current.ID)
+	}
+	return current, refund, nil
+}
+
+// refundIdempotencyKey identifies an attempt to pay back a refund, so that the provider pays each
+// attempt only once however many times it is sent
+func refundIdempotencyKey(refund db.Refund) string {
+	return fmt.Sprintf("%s-%d", refund.ID, refund.Attempt)
+}
+
+// payOutRefund pays back a pending refund of an order's payment through the provider. The refund is
+// marked processing before the provider is called, and no lock is held during the call. A refund
+// left processing because its outcome could not be saved is sent again under the same idempotency
+// key, so it is still paid only once. A refund that the provider turns down is marked as failed,
+// with the provider's reason.
+func (server *Server) payOutRefund(ctx context.Context, orderID uuid.UUID, refundID uuid.UUID) (db.Refund, error) {
+	current, refund, err := server.startRefund(ctx, orderID, refundID)
+	if err != nil || refund.Status != db.RefundStatusProcessing {
+		return refund, err
+	}
+
+	providerRefundID, refundErr := server.payments.Refund(ctx, current.ProviderPaymentID.String, refund.Amount, refundIdempotencyKey(refund))
+	return server.finishRefund(ctx, orderID, refundID, providerRefundID, refundErr)
+}
+
+// startRefund marks a pending refund as processing. Refunds in any other status, and those of
+// payments that are paid back by hand, are returned unchanged.
+func (server *Server) startRefund(ctx context.Context, orderID uuid.UUID, refundID uuid.UUID) (db.Payment, db.Refund, error) {
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		return db.Payment{}, db.Refund{}, err
+	}
+	defer tx.Rollback()
+
+	current, refund, err := server.getRefundForUpdate(ctx, tx, orderID, refundID)
+	if err != nil {
+		return db.Payment{}, db.Refund{}, err
+	}
+	if refund.Status != db.RefundStatusPending || !server.refundable(current) {
+		return current, refund, nil
+	}
+
+	arg := db.UpdateRefundParams{
+		ID:     refund.ID,
+		Status: db.RefundStatusProcessing,
+	}
+
+	refund, err = server.store.UpdateRefundWithTx(ctx, tx, arg)
+	if err != nil {
+		return db.Payment{}, db.Refund{}, err
+	}
+	return current, refund, tx.Commit()
+}
+
+// finishRefund records what the provider answered to a processing refund. A refund that is no
+// longer processing was finished by another call and is returned unchanged.
+func (server *Server) finishRefund(ctx context.Context, orderID uuid.UUID, refundID uuid.UUID, providerRefundID string, refundErr error) (db.Refund, error) {
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		return db.Refund{}, err
+	}
+	defer tx.Rollback()
+
+	current, refund, err := server.getRefundForUpdate(ctx, tx, orderID, refundID)
+	if err != nil {
+		return db.Refund{}, err
+	}
+	if refund.Status != db.RefundStatusProcessing {
+		return refund, nil
+	}
+
+	arg := db.UpdateRefundParams{
+		ID:     refund.ID,
+		Status: db.RefundStatusSucceeded,
+	}
+
+	if refundErr != nil {
+		arg.Status = db.RefundStatusFailed
+		arg.FailureMessage = sql.NullString{String: refundErr.Error(), Valid: true}
+	} else {
+		arg.ProviderRefundID = sql.NullString{String: providerRefundID, Valid: true}
+
+		refundArg := db.AddPaymentRefundParams{
+			ID:           current.ID,
+			RefundAmount: refund.Amount,
+		}
+
+		after, err := server.store.AddPaymentRefundWithTx(ctx, tx, refundArg)
+		if err != nil {
+			return db.Refund{}, err
+		}
+
+		err = server.recordPaymentStatus(ctx, tx, current, after, "Fully refunded")
+		if err != nil {
+			return db.Refund{}, err
+		}
+	}
+
+	refund, err = server.store.UpdateRefundWithTx(ctx, tx, arg)
+	if err != nil {
+		return db.Refund{}, err
+	}
+	return refund, tx.Commit()
+}
+
+// retryRefund pays back again a refund of an order's payment that the provider turned down, under a
+// new attempt, or one that was left pending or processing, under its current attempt. It returns
+// errRefundPaidBack for refunds that already succeeded.
+func (server *Server) retryRefund(ctx context.Context, orderID uuid.UUID, refundID uuid.UUID) (db.Refund, error) {
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		return db.Refund{}, err
+	}
+	defer tx.Rollback()
+
+	current, refund, err := server.getRefundForUpdate(ctx, tx, orderID, refundID)
+	if err != nil {
+		return db.Refund{}, err
+	}
+	if refund.Status == db.RefundStatusSucceeded {
+		return db.Refund{}, errRefundPaidBack
+	}
+	if !server.refundable(current) {
+		return db.Refund{}, fmt.Errorf("%w, it was taken with %s", errRefundByHand, current.Provider)
+	}
+
+	if refund.Status == db.RefundStatusFailed {
+		_, err = server.store.RetryRefundWithTx(ctx, tx, refund.ID)
+		if err != nil {
+			return db.Refund{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return db.Refund{}, err
+	}
+	return server.payOutRefund(ctx, orderID, refundID)
+}
+
+type paymentStatusChangeResponse struct {
+	FromStatus string `json:"from_status,omitempty"`
+	ToStatus   string `json:"to_status"`
+	Note       string `json:"note,omitempty"`
+	ChangedAt  string `json:"changed_at"`
+}
+
+type paymentResponse struct {
+	ID             uuid.UUID                     `json:"id"`
+	Provider       string                        `json:"provider"`
+	Status         string                        `json:"status"`
+	Amount         money.Amount                  `json:"amount"`
+	RefundedAmount money.Amount                  `json:"refunded_amount"`
+	CardLast4      string                        `json:"card_last4,omitempty"`
+	FailureMessage string                        `json:"failure_message,omitempty"`
+	CreatedAt      string                        `json:"created_at"`
+	UpdatedAt      string                        `json:"updated_at"`
+	StatusHistory  []paymentStatusChangeResponse `json:"status_history,omitempty"`
+}
+
+func newPaymentResponse(current db.Payment) paymentResponse {
+	return paymentResponse{
+		ID:             current.ID,
+		Provider:       current.Provider,
+		Status:         string(current.Status),
+		Amount:         current.Amount,
+		RefundedAmount: current.RefundedAmount,
+		CardLast4:      current.CardLast4.String,
+		FailureMessage: current.FailureMessage.String,
+		CreatedAt:      current.CreatedAt.String(),
+		UpdatedAt:      current.UpdatedAt.String(),
+	}
+}
+
+// getPaymentResponse gets an order's payment along with its status history
+func (server *Server) getPaymentResponse(ctx context.Context, orderID uuid.UUID) (paymentResponse, error) {
+	current, err := server.store.GetPaymentByOrder(ctx, orderID)
+	if err != nil {
+		return paymentResponse{}, err
+	}
+
+	history, err := server.store.ListPaymentStatusHistory(ctx, current.ID)
+	if err != nil {
+		return paymentResponse{}, err
+	}
+
+	rsp := newPaymentResponse(current)
+	rsp.StatusHistory = make([]paymentStatusChangeResponse, len(history))
+	for i, change := range history {
+		rsp.StatusHistory[i] = paymentStatusChangeResponse{
+			ToStatus:  string(change.ToStatus),
+			Note:      change.Note.String,
+			ChangedAt: change.CreatedAt.String(),
+		}
+		// The payment was created with the first change
+		if change.FromStatus.Valid {
+			rsp.StatusHistory[i].FromStatus = string(change.FromStatus.PaymentStatus)
+		}
+	}
+	return rsp, nil
+}
+
+type payOrderRequest struct {
+	paymentDetails
+}
+
+// payOrder tries the payment of an order again, after it was declined or needed 3-D Secure. The
+// response has the payment's new status, which is declined again if the provider refused it.
+func (server *Server) payOrder(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Only card payments need details, so an empty body is allowed
+	var req payOrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	order, err := server.store.GetOrder(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("order not found")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := req.check(order.PaymentMethod); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	before, err := server.store.GetPaymentByOrder(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	after, err := server.attemptPayment(ctx, order, req.paymentDetails)
+	if err != nil {
+		if errors.Is(err, errPaymentNotPayable) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := newPaymentResponse(after)
+	server.audit(ctx, auditOrderPay, auditTargetPayment, after.ID, newPaymentResponse(before), rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/payment"
+)
+
+// maxWebhookSize bounds the size of the webhook payloads that are read
+const maxWebhookSize = 64 << 10
+
+// Outcomes of receiving a webhook event
+const (
+	webhookProcessed = "processed"
+	webhookDuplicate = "duplicate"
+	webhookIgnored   = "ignored"
+)
+
+type paymentWebhookResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// receivePaymentWebhook takes in an event that the payment provider named by the :provider path
+// parameter sends about a payment. Events are only accepted with a valid signature, and are stored as
+// received before they are processed. An event is processed once, so one that the provider sends
+// again is only acknowledged. When processing fails, the error is kept on the event and the request
+// fails, for the provider to send it again.
+func (server *Server) receivePaymentWebhook(ctx *gin.Context) {
+	provider := ctx.Param("provider")
+	if provider != server.payments.Name() {
+		err := fmt.Errorf("unknown payment provider %q", provider)
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxWebhookSize))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	signature := ctx.GetHeader(payment.SignatureHeader)
+	event, err := server.payments.ParseWebhook(payload, signature)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.CreatePaymentWebhookEventParams{
+		Provider:  provider,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   string(payload),
+		Signature: signature,
+	}
+
+	err = server.store.CreatePaymentWebhookEvent(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp, err := server.processPaymentWebhook(ctx, provider, event)
+	if err != nil {
+		errorArg := db.RecordPaymentWebhookEventErrorParams{
+			Provider: provider,
+			EventID:  event.ID,
+			Error:    sql.NullString{String: err.Error(), Valid: true},
+		}
+		if recordErr := server.store.RecordPaymentWebhookEventError(ctx, errorArg); recordErr != nil {
+			log.Printf("Warning: failed to record error of webhook event %s: %v", event.ID, recordErr)
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// processPaymentWebhook applies a stored webhook event to its payment and marks it as processed, all
+// in one transaction. Events for payments that are unknown, were tried again since or have already
+// moved on are ignored. A payment that becomes authorized is captured, and one that is voided cancels
+// its order if the order is still pending.
+func (server *Server) processPaymentWebhook(ctx context.Context, provider string, event payment.WebhookEvent) (paymentWebhookResponse, error) {
+	paymentArg := db.GetPaymentByProviderPaymentIDParams{
+		Provider:          provider,
+		ProviderPaymentID: sql.NullString{String: event.PaymentID, Valid: true},
+	}
+
+	// The payment is looked up first, so that its order can be locked before it like everywhere else
+	found, err := server.store.GetPaymentByProviderPaymentID(ctx, paymentArg)
+	if err != nil && err != sql.ErrNoRows {
+		return paymentWebhookResponse{}, err
+	}
+	unknown := err == sql.ErrNoRows
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		return paymentWebhookResponse{}, err
+	}
+	defer tx.Rollback()
+
+	eventArg := db.GetPaymentWebhookEventForUpdateParams{
+		Provider: provider,
+		EventID:  event.ID,
+	}
+
+	stored, err := server.store.GetPaymentWebhookEventForUpdateWithTx(ctx, tx, eventArg)
+	if err != nil {
+		return paymentWebhookResponse{}, err
+	}
+	if stored.ProcessedAt.Valid {
+		return paymentWebhookResponse{Status: webhookDuplicate}, nil
+	}
+
+	rsp := paymentWebhookResponse{Status: webhookProcessed}
+	if unknown {
+		rsp = paymentWebhookResponse{Status: webhookIgnored, Reason: fmt.Sprintf("no payment has %s payment ID %s", provider, event.PaymentID)}
+	} else {
+		rsp, err = server.applyPaymentWebhook(ctx, tx, found.OrderID, provider, event)
+		if err != nil {
+			return paymentWebhookResponse{}, err
+		}
+	}
+
+	processedArg := db.MarkPaymentWebhookEventProcessedParams{
+		ID:    stored.ID,
+		Error: sql.NullString{String: rsp.Reason, Valid: rsp.Reason != ""},
+	}
+
+	err = server.store.MarkPaymentWebhookEventProcessedWithTx(ctx, tx, processedArg)
+	if err != nil {
+		return paymentWebhookResponse{}, err
+	}
+
+	return rsp, tx.Commit()
+}
+
+// applyPaymentWebhook moves the payment of an order to the status reported by a webhook event
+func (server *Server) applyPaymentWebhook(ctx context.Context, tx *sql.Tx, orderID uuid.UUID, provider string, event payment.WebhookEvent) (paymentWebhookResponse, error) {
+	order, err := server.store.GetOrderForUpdateWithTx(ctx, tx, orderID)
+	if err != nil {
+		return paymentWebhookResponse{}, err
+	}
+
+	current, err := server.store.GetPaymentByOrderForUpdateWithTx(ctx, tx, order.ID)
+	if err != nil {
+		return paymentWebhookResponse{}, err
+	}
+
+	if current.ProviderPaymentID.String != event.PaymentID {
+		reason := fmt.Sprintf("payment was tried again as %s", current.ProviderPaymentID.String)
+		return paymentWebhookResponse{Status: webhookIgnored, Reason: reason}, nil
+	}
+	if !payableStatuses[current.Status] || current.Status == db.PaymentStatus(event.Status) {
+		reason := fmt.Sprintf("payment is already %s", current.Status)
+		return paymentWebhookResponse{Status: webhookIgnored, Reason: reason}, nil
+	}
+
+	message := event.Message
+	if message == "" {
+		message = fmt.Sprintf("Reported by %s event %s", provider, event.ID)
+	}
+
+	result := payment.Result{
+		PaymentID: event.PaymentID,
+		Status:    event.Status,
+		Message:   message,
+	}
+
+	current, err = server.applyPaymentResult(ctx, tx, current, result, "")
+	if err != nil {
+		return paymentWebhookResponse{}, err
+	}
+
+	switch current.Status {
+	case db.PaymentStatusAuthorized:
+		// The buyer can capture it by paying again
+		_, err = server.capturePayment(ctx, tx, current)
+		if errors.Is(err, errCaptureFailed) {
+			return paymentWebhookResponse{Status: webhookProcessed, Reason: err.Error()}, nil
+		}
+		if err != nil {
+			return paymentWebhookResponse{}, err
+		}
+	case db.PaymentStatusVoided:
+		if order.Status != db.OrderStatusPending {
+			break
+		}
+
+		note := fmt.Sprintf("Payment was voided by %s", provider)
+		_, err = server.changeOrderStatus(ctx, tx, order, db.OrderStatusCancelled, uuid.Nil, note, shipmentDetails{})
+		if err != nil {
+			return paymentWebhookResponse{}, err
+		}
+	}
+
+	return paymentWebhookResponse{Status: webhookProcessed}, nil
+}
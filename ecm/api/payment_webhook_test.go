@@ -0,0 +1,254 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/money"
+	"github.com/qhh/ecm/payment"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// createTestPayment places a pending order for a new buyer with a payment that the mock provider knows
+// as providerPaymentID and that waits for 3-D Secure. They are deleted after the test.
+func createTestPayment(t *testing.T, store db.Store, providerPaymentID string) db.Payment {
+	t.Helper()
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+
+	buyer, err := store.CreateUser(ctx, db.CreateUserParams{
+		Username:     "buyer_" + suffix,
+		Email:        "buyer_" + suffix + "@example.com",
+		PasswordHash: "not a hash",
+		Role:         db.UserRoleBuyer,
+	})
+	if err != nil {
+		t.Fatalf("cannot create buyer: %v", err)
+	}
+	t.Cleanup(func() { store.DeleteUser(ctx, buyer.ID) })
+
+	order, err := store.CreateOrder(ctx, db.CreateOrderParams{
+		UserID:          buyer.ID,
+		TotalAmount:     money.MustParse("9.99"),
+		ShippingAddress: "1 Test Street",
+		PaymentMethod:   "credit_card",
+	})
+	if err != nil {
+		t.Fatalf("cannot create order: %v", err)
+	}
+
+	created, err := store.CreatePayment(ctx, db.CreatePaymentParams{
+		OrderID:  order.ID,
+		Provider: "mock",
+		Amount:   order.TotalAmount,
+	})
+	if err != nil {
+		t.Fatalf("cannot create payment: %v", err)
+	}
+
+	return updateTestPayment(t, store, created, providerPaymentID, db.PaymentStatusRequiresAction)
+}
+
+// updateTestPayment gives a payment a new provider payment ID and status, as trying it again does
+func updateTestPayment(t *testing.T, store db.Store, current db.Payment, providerPaymentID string, status db.PaymentStatus) db.Payment {
+	t.Helper()
+
+	updated, err := store.UpdatePayment(context.Background(), db.UpdatePaymentParams{
+		ID:                current.ID,
+		ProviderPaymentID: sql.NullString{String: providerPaymentID, Valid: true},
+		Status:            status,
+		CardLast4:         sql.NullString{String: "3220", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("cannot update payment: %v", err)
+	}
+	return updated
+}
+
+// sendTestWebhook posts a webhook event from the mock provider, signed now, and returns the response
+func sendTestWebhook(t *testing.T, router *gin.Engine, eventID string, providerPaymentID string, status payment.Status) (int, paymentWebhookResponse) {
+	t.Helper()
+
+	body, err := json.Marshal(gin.H{
+		"id":      eventID,
+		"type":    "payment." + string(status),
+		"created": time.Now().Unix(),
+		"data":    gin.H{"payment_id": providerPaymentID, "status": status},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/webhooks/payments/mock", bytes.NewReader(body))
+	request.Header.Set(payment.SignatureHeader, payment.Sign(body, testWebhookSecret, time.Now()))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var rsp paymentWebhookResponse
+	if recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &rsp); err != nil {
+			t.Fatalf("cannot parse response %s: %v", recorder.Body, err)
+		}
+	}
+	return recorder.Code, rsp
+}
+
+func newTestWebhookRouter(server *Server) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhooks/payments/:provider", server.receivePaymentWebhook)
+	return router
+}
+
+// TestPaymentWebhookDuplicate sends the same event twice and checks that it changes the payment once
+func TestPaymentWebhookDuplicate(t *testing.T) {
+	store := newTestStore(t)
+	server := &Server{store: store, payments: payment.NewMockProvider(testWebhookSecret)}
+	router := newTestWebhookRouter(server)
+
+	providerPaymentID := "mock_pay_" + uuid.NewString()
+	current := createTestPayment(t, store, providerPaymentID)
+	eventID := "evt_" + uuid.NewString()
+
+	code, rsp := sendTestWebhook(t, router, eventID, providerPaymentID, payment.StatusDeclined)
+	if code != http.StatusOK || rsp.Status != webhookProcessed {
+		t.Fatalf("first delivery = %d %+v; want %d %s", code, rsp, http.StatusOK, webhookProcessed)
+	}
+
+	code, rsp = sendTestWebhook(t, router, eventID, providerPaymentID, payment.StatusDeclined)
+	if code != http.StatusOK || rsp.Status != webhookDuplicate {
+		t.Fatalf("second delivery = %d %+v; want %d %s", code, rsp, http.StatusOK, webhookDuplicate)
+	}
+
+	ctx := context.Background()
+	updated, err := store.GetPaymentByOrder(ctx, current.OrderID)
+	if err != nil {
+		t.Fatalf("cannot get payment: %v", err)
+	}
+	if updated.Status != db.PaymentStatusDeclined {
+		t.Errorf("payment is %s; want %s", updated.Status, db.PaymentStatusDeclined)
+	}
+
+	history, err := store.ListPaymentStatusHistory(ctx, current.ID)
+	if err != nil {
+		t.Fatalf("cannot list payment history: %v", err)
+	}
+	declines := 0
+	for _, change := range history {
+		if change.ToStatus == db.PaymentStatusDeclined {
+			declines++
+		}
+	}
+	if declines != 1 {
+		t.Errorf("payment was declined %d times; want 1", declines)
+	}
+
+	stored, err := store.GetPaymentWebhookEventForUpdate(ctx, db.GetPaymentWebhookEventForUpdateParams{Provider: "mock", EventID: eventID})
+	if err != nil {
+		t.Fatalf("cannot get webhook event: %v", err)
+	}
+	if !stored.ProcessedAt.Valid || stored.Error.Valid {
+		t.Errorf("event processed at %v with error %q; want processed without error", stored.ProcessedAt, stored.Error.String)
+	}
+}
+
+// TestPaymentWebhookRetriedPayment checks that an event about an earlier attempt of a payment that was
+// tried again since leaves the payment alone
+func TestPaymentWebhookRetriedPayment(t *testing.T) {
+	store := newTestStore(t)
+	server := &Server{store: store, payments: payment.NewMockProvider(testWebhookSecret)}
+	router := newTestWebhookRouter(server)
+	ctx := context.Background()
+
+	firstAttempt := "mock_pay_" + uuid.NewString()
+	secondAttempt := "mock_pay_" + uuid.NewString()
+	current := createTestPayment(t, store, firstAttempt)
+	current = updateTestPayment(t, store, current, secondAttempt, db.PaymentStatusRequiresAction)
+
+	t.Run("Received", func(t *testing.T) {
+		eventID := "evt_" + uuid.NewString()
+
+		code, rsp := sendTestWebhook(t, router, eventID, firstAttempt, payment.StatusAuthorized)
+		if code != http.StatusOK || rsp.Status != webhookIgnored {
+			t.Fatalf("delivery = %d %+v; want %d %s", code, rsp, http.StatusOK, webhookIgnored)
+		}
+
+		stored, err := store.GetPaymentWebhookEventForUpdate(ctx, db.GetPaymentWebhookEventForUpdateParams{Provider: "mock", EventID: eventID})
+		if err != nil {
+			t.Fatalf("cannot get webhook event: %v", err)
+		}
+		if !stored.ProcessedAt.Valid || stored.Error.String != rsp.Reason {
+			t.Errorf("event processed at %v with %q; want processed with %q", stored.ProcessedAt, stored.Error.String, rsp.Reason)
+		}
+	})
+
+	// The payment can also be tried again between looking it up for the event and locking it
+	t.Run("RetriedWhileProcessing", func(t *testing.T) {
+		tx, err := store.BeginTx(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+
+		event := payment.WebhookEvent{
+			ID:        "evt_" + uuid.NewString(),
+			Type:      "payment.authorized",
+			PaymentID: firstAttempt,
+			Status:    payment.StatusAuthorized,
+		}
+		rsp, err := server.applyPaymentWebhook(ctx, tx, current.OrderID, "mock", event)
+		if err != nil {
+			t.Fatalf("applyPaymentWebhook returned error: %v", err)
+		}
+		if rsp.Status != webhookIgnored || !strings.Contains(rsp.Reason, secondAttempt) {
+			t.Errorf("applyPaymentWebhook = %+v; want %s because of %s", rsp, webhookIgnored, secondAttempt)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	updated, err := store.GetPaymentByOrder(ctx, current.OrderID)
+	if err != nil {
+		t.Fatalf("cannot get payment: %v", err)
+	}
+	if updated.Status != current.Status || updated.ProviderPaymentID != current.ProviderPaymentID {
+		t.Errorf("payment is %s as %s; want %s as %s", updated.Status, updated.ProviderPaymentID.String, current.Status, secondAttempt)
+	}
+
+	order, err := store.GetOrder(ctx, current.OrderID)
+	if err != nil {
+		t.Fatalf("cannot get order: %v", err)
+	}
+	if order.Status != db.OrderStatusPending {
+		t.Errorf("order is %s; want %s", order.Status, db.OrderStatusPending)
+	}
+}
+
+// TestPaymentWebhookBadSignature checks that unsigned events are refused before anything is stored
+func TestPaymentWebhookBadSignature(t *testing.T) {
+	server := &Server{payments: payment.NewMockProvider(testWebhookSecret)}
+	router := newTestWebhookRouter(server)
+
+	body := []byte(fmt.Sprintf(`{"id":"evt_1","type":"payment.captured","created":%d,"data":{"payment_id":"mock_pay_1","status":"captured"}}`, time.Now().Unix()))
+	request := httptest.NewRequest(http.MethodPost, "/webhooks/payments/mock", bytes.NewReader(body))
+	request.Header.Set(payment.SignatureHeader, payment.Sign(body, "whsec_other", time.Now()))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d; want %d", recorder.Code, http.StatusUnauthorized)
+	}
+}
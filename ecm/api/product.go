@@ -3,48 +3,44 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	db "github.com/qhh/ecm/db/sqlc"
-	"github.com/qhh/ecm/token"
+	"github.com/qhh/ecm/money"
 )
 
 type createProductRequest struct {
-	Name          string  `json:"name" binding:"required"`
-	Description   string  `json:"description"`
-	Price         float64 `json:"price" binding:"required,gt=0"`
-	StockQuantity int32   `json:"stock_quantity" binding:"required,gte=0"`
-	ShopID        string  `json:"shop_id" binding:"required"`
-	CategoryID    string  `json:"category_id" binding:"required"`
-	ImageURL      string  `json:"image_url"`
+	Name          string       `json:"name" binding:"required"`
+	Description   string       `json:"description"`
+	Price         money.Amount `json:"price" binding:"required,gt=0"`
+	StockQuantity int32        `json:"stock_quantity" binding:"required,gte=0"`
+	ShopID        string       `json:"shop_id" binding:"required"`
+	CategoryID    string       `json:"category_id" binding:"required"`
+	ImageURL      string       `json:"image_url"`
 }
 
 type productResponse struct {
-	ID            uuid.UUID `json:"id"`
-	Name          string    `json:"name"`
-	Description   string    `json:"description"`
-	Price         float64   `json:"price"`
-	StockQuantity int32     `json:"stock_quantity"`
-	ShopID        uuid.UUID `json:"shop_id"`
-	CategoryID    uuid.UUID `json:"category_id"`
-	ImageURL      string    `json:"image_url"`
-	CreatedAt     string    `json:"created_at"`
-	UpdatedAt     string    `json:"updated_at"`
+	ID            uuid.UUID    `json:"id"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description"`
+	Price         money.Amount `json:"price"`
+	StockQuantity int32        `json:"stock_quantity"`
+	ShopID        uuid.UUID    `json:"shop_id"`
+	CategoryID    uuid.UUID    `json:"category_id"`
+	ImageURL      string       `json:"image_url"`
+	CreatedAt     string       `json:"created_at"`
+	UpdatedAt     string       `json:"updated_at"`
 }
 
 func newProductResponse(product db.Product) productResponse {
-	price, err := strconv.ParseFloat(product.Price, 64)
-	if err != nil {
-		price = 0.0
-	}
 	return productResponse{
 		ID:            product.ID,
 		Name:          product.Name,
 		Description:   product.Description.String,
-		Price:         price,
+		Price:         product.Price,
 		StockQuantity: product.StockQuantity,
 		ShopID:        product.ShopID,
 		CategoryID:    product.CategoryID,
@@ -54,12 +50,19 @@ func newProductResponse(product db.Product) productResponse {
 	}
 }
 
+// errPriceTooHigh rejects prices that do not fit the DECIMAL(10, 2) column they are stored in
+var errPriceTooHigh = fmt.Errorf("price must not be more than %s", money.MaxDecimal)
+
 func (server *Server) createProduct(ctx *gin.Context) {
 	var req createProductRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if req.Price > money.MaxDecimal {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errPriceTooHigh))
+		return
+	}
 
 	shopID, err := uuid.Parse(req.ShopID)
 	if err != nil {
@@ -85,10 +88,7 @@ func (server *Server) createProduct(ctx *gin.Context) {
 	}
 
 	// Check if user is authorized to add products to this shop
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if shop.OwnerID != authPayload.UserID && authPayload.Role != "admin" {
-		err := errors.New("you don't have permission to add products to this shop")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	if !server.authorize(ctx, permProductWrite, shop.OwnerID) {
 		return
 	}
 
@@ -106,7 +106,7 @@ func (server *Server) createProduct(ctx *gin.Context) {
 	arg := db.CreateProductParams{
 		Name:          req.Name,
 		Description:   sql.NullString{String: req.Description, Valid: req.Description != ""},
-		Price:         strconv.FormatFloat(req.Price, 'f', -1, 64),
+		Price:         req.Price,
 		StockQuantity: req.StockQuantity,
 		ShopID:        shopID,
 		CategoryID:    categoryID,
@@ -119,7 +119,10 @@ func (server *Server) createProduct(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, newProductResponse(product))
+	rsp := newProductResponse(product)
+	server.audit(ctx, auditProductCreate, auditTargetProduct, product.ID, nil, rsp)
+
+	ctx.JSON(http.StatusCreated, rsp)
 }
 
 func (server *Server) getProduct(ctx *gin.Context) {
@@ -237,12 +240,12 @@ func (server *Server) searchProducts(ctx *gin.Context) {
 }
 
 type updateProductRequest struct {
-	Name          string  `json:"name" binding:"required"`
-	Description   string  `json:"description"`
-	Price         float64 `json:"price" binding:"required,gt=0"`
-	StockQuantity int32   `json:"stock_quantity" binding:"required,gte=0"`
-	CategoryID    string  `json:"category_id" binding:"required"`
-	ImageURL      string  `json:"image_url"`
+	Name          string       `json:"name" binding:"required"`
+	Description   string       `json:"description"`
+	Price         money.Amount `json:"price" binding:"required,gt=0"`
+	StockQuantity int32        `json:"stock_quantity" binding:"required,gte=0"`
+	CategoryID    string       `json:"category_id" binding:"required"`
+	ImageURL      string       `json:"image_url"`
 }
 
 func (server *Server) updateProduct(ctx *gin.Context) {
@@ -257,6 +260,10 @@ func (server *Server) updateProduct(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if req.Price > money.MaxDecimal {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errPriceTooHigh))
+		return
+	}
 
 	categoryID, err := uuid.Parse(req.CategoryID)
 	if err != nil {
@@ -264,39 +271,19 @@ func (server *Server) updateProduct(ctx *gin.Context) {
 		return
 	}
 
-	// Get the product to check shop ownership
-	product, err := server.store.GetProduct(ctx, id)
+	// Verify category exists
+	_, err = server.store.GetCategory(ctx, categoryID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("product not found")))
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("category not found")))
 			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	// Get the shop to check ownership
-	shop, err := server.store.GetShop(ctx, product.ShopID)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
-
-	// Check authorization
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if shop.OwnerID != authPayload.UserID && authPayload.Role != "admin" {
-		err := errors.New("you don't have permission to update this product")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
-		return
-	}
-
-	// Verify category exists
-	_, err = server.store.GetCategory(ctx, categoryID)
+	product, err := server.store.GetProduct(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("category not found")))
-			return
-		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -305,7 +292,7 @@ func (server *Server) updateProduct(ctx *gin.Context) {
 		ID:            id,
 		Name:          req.Name,
 		Description:   sql.NullString{String: req.Description, Valid: req.Description != ""},
-		Price:         strconv.FormatFloat(req.Price, 'f', -1, 64),
+		Price:         req.Price,
 		StockQuantity: req.StockQuantity,
 		CategoryID:    categoryID,
 		ImageUrl:      sql.NullString{String: req.ImageURL, Valid: req.ImageURL != ""},
@@ -317,7 +304,10 @@ func (server *Server) updateProduct(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, newProductResponse(updatedProduct))
+	rsp := newProductResponse(updatedProduct)
+	server.audit(ctx, auditProductUpdate, auditTargetProduct, updatedProduct.ID, newProductResponse(product), rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
 }
 
 func (server *Server) deleteProduct(ctx *gin.Context) {
@@ -327,38 +317,20 @@ func (server *Server) deleteProduct(ctx *gin.Context) {
 		return
 	}
 
-	// Get the product to check shop ownership
 	product, err := server.store.GetProduct(ctx, id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("product not found")))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
-
-	// Get the shop to check ownership
-	shop, err := server.store.GetShop(ctx, product.ShopID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	// Check authorization
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if shop.OwnerID != authPayload.UserID && authPayload.Role != "admin" {
-		err := errors.New("you don't have permission to delete this product")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
-		return
-	}
-
 	err = server.store.DeleteProduct(ctx, id)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	server.audit(ctx, auditProductDelete, auditTargetProduct, product.ID, newProductResponse(product), nil)
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "product deleted successfully"})
 }
 
@@ -369,27 +341,6 @@ func (server *Server) listProductsByShop(ctx *gin.Context) {
 		return
 	}
 
-	// Verify shop exists
-	shop, err := server.store.GetShop(ctx, shopID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("shop not found")))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
-
-	// Check if user is authorized to view shop products
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if shop.OwnerID != authPayload.UserID && authPayload.Role != "admin" {
-		// For normal users, we could filter only published products
-		// For now, we'll restrict to shop owner or admin
-		err := errors.New("you don't have permission to view these products")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
-		return
-	}
-
 	products, err := server.store.ListProductsByShop(ctx, shopID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
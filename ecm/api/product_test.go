@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TestProductPriceTooHigh checks that prices that do not fit DECIMAL(10, 2) are refused before
+// anything is looked up
+func TestProductPriceTooHigh(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{}
+
+	body := `{"name": "Yacht", "price": "100000000.00", "stock_quantity": 1, "shop_id": "` + uuid.NewString() + `", "category_id": "` + uuid.NewString() + `"}`
+
+	testCases := []struct {
+		name    string
+		handler gin.HandlerFunc
+		params  gin.Params
+	}{
+		{name: "Create", handler: server.createProduct},
+		{name: "Update", handler: server.updateProduct, params: gin.Params{{Key: "id", Value: uuid.NewString()}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+			ctx.Request.Header.Set("Content-Type", "application/json")
+			ctx.Params = tc.params
+
+			tc.handler(ctx)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d; want %d", recorder.Code, http.StatusBadRequest)
+			}
+			if !strings.Contains(recorder.Body.String(), errPriceTooHigh.Error()) {
+				t.Errorf("body = %s; want %q", recorder.Body, errPriceTooHigh)
+			}
+		})
+	}
+}
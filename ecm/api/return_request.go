@@ -0,0 +1,570 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/money"
+	"github.com/qhh/ecm/token"
+)
+
+var (
+	errReturnRequestNotFound = errors.New("return request not found")
+	errOrderItemNotFound     = errors.New("order item not found")
+	errItemNotDelivered      = errors.New("items can only be returned once they are delivered")
+	errReturnHasNoRefund     = errors.New("return request has no refund")
+)
+
+// returnStatusTransitions lists the statuses a return request can move to from each status. Rejected
+// and inspected requests are final.
+var returnStatusTransitions = map[db.ReturnStatus][]db.ReturnStatus{
+	db.ReturnStatusRequested: {db.ReturnStatusApproved, db.ReturnStatusRejected},
+	db.ReturnStatusApproved:  {db.ReturnStatusReceived},
+	db.ReturnStatusReceived:  {db.ReturnStatusInspected},
+	db.ReturnStatusRejected:  {},
+	db.ReturnStatusInspected: {},
+}
+
+// canChangeReturnStatus reports whether a return request can move from one status to another
+func canChangeReturnStatus(from db.ReturnStatus, to db.ReturnStatus) bool {
+	for _, status := range returnStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+type refundResponse struct {
+	ID             uuid.UUID    `json:"id"`
+	Amount         money.Amount `json:"amount"`
+	Status         string       `json:"status"`
+	FailureMessage string       `json:"failure_message,omitempty"`
+}
+
+type returnRequestResponse struct {
+	ID          uuid.UUID       `json:"id"`
+	OrderID     uuid.UUID       `json:"order_id"`
+	OrderItemID uuid.UUID       `json:"order_item_id"`
+	ShopID      uuid.UUID       `json:"shop_id"`
+	ProductName string          `json:"product_name,omitempty"`
+	Username    string          `json:"username,omitempty"`
+	Quantity    int32           `json:"quantity"`
+	Reason      string          `json:"reason"`
+	Status      string          `json:"status"`
+	Note        string          `json:"note,omitempty"`
+	Restocked   bool            `json:"restocked"`
+	Refund      *refundResponse `json:"refund,omitempty"`
+	CreatedAt   string          `json:"created_at"`
+	UpdatedAt   string          `json:"updated_at"`
+}
+
+func newReturnRequestResponse(request db.ReturnRequest) returnRequestResponse {
+	return returnRequestResponse{
+		ID:          request.ID,
+		OrderID:     request.OrderID,
+		OrderItemID: request.OrderItemID,
+		ShopID:      request.ShopID,
+		Quantity:    request.Quantity,
+		Reason:      request.Reason,
+		Status:      string(request.Status),
+		Note:        request.Note.String,
+		Restocked:   request.Restocked,
+		CreatedAt:   request.CreatedAt.String(),
+		UpdatedAt:   request.UpdatedAt.String(),
+	}
+}
+
+func newRefundResponse(refund db.Refund) *refundResponse {
+	return &refundResponse{
+		ID:             refund.ID,
+		Amount:         refund.Amount,
+		Status:         string(refund.Status),
+		FailureMessage: refund.FailureMessage.String,
+	}
+}
+
+// newJoinedRefundResponse builds the refund of a return request from the columns of a LEFT JOIN,
+// returning nil if the request has no refund
+func newJoinedRefundResponse(id uuid.NullUUID, amount money.NullAmount, status db.NullRefundStatus) *refundResponse {
+	if !id.Valid {
+		return nil
+	}
+	return &refundResponse{
+		ID:     id.UUID,
+		Amount: amount.Amount,
+		Status: string(status.RefundStatus),
+	}
+}
+
+type createReturnRequestRequest struct {
+	OrderItemID string `json:"order_item_id" binding:"required,uuid"`
+	Quantity    int32  `json:"quantity" binding:"required,min=1"`
+	Reason      string `json:"reason" binding:"required,max=1000"`
+}
+
+// createReturnRequest asks to return some of an order item. The item's shop order must have been
+// delivered, and all return requests for an item that were not rejected cannot add up to more than
+// was bought.
+func (server *Server) createReturnRequest(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req createReturnRequestRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	item, err := server.store.GetOrderItemForReturn(ctx, uuid.MustParse(req.OrderItemID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errOrderItemNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if item.OrderID != orderID {
+		ctx.JSON(http.StatusNotFound, errorResponse(errOrderItemNotFound))
+		return
+	}
+	if item.ShopOrderStatus != db.OrderStatusDelivered {
+		ctx.JSON(http.StatusConflict, errorResponse(errItemNotDelivered))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	// Locking the order keeps two requests for the same item from both fitting in its quantity
+	_, err = server.store.GetOrderForUpdateWithTx(ctx, tx, orderID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	returned, err := server.store.GetReturnedQuantityWithTx(ctx, tx, item.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if returned+req.Quantity > item.Quantity {
+		err := fmt.Errorf("only %d of this item can still be returned", item.Quantity-returned)
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	arg := db.CreateReturnRequestParams{
+		OrderID:     orderID,
+		OrderItemID: item.ID,
+		ShopID:      item.ShopID,
+		UserID:      authPayload.UserID,
+		Quantity:    req.Quantity,
+		Reason:      req.Reason,
+	}
+
+	request, err := server.store.CreateReturnRequestWithTx(ctx, tx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := newReturnRequestResponse(request)
+	server.audit(ctx, auditReturnCreate, auditTargetReturnRequest, request.ID, nil, rsp)
+
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// listOrderReturnRequests lists the return requests of an order, oldest first
+func (server *Server) listOrderReturnRequests(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	requests, err := server.store.ListReturnRequestsByOrder(ctx, orderID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]returnRequestResponse, len(requests))
+	for i, row := range requests {
+		request := db.ReturnRequest{
+			ID:          row.ID,
+			OrderID:     row.OrderID,
+			OrderItemID: row.OrderItemID,
+			ShopID:      row.ShopID,
+			UserID:      row.UserID,
+			Quantity:    row.Quantity,
+			Reason:      row.Reason,
+			Status:      row.Status,
+			Note:        row.Note,
+			HandledBy:   row.HandledBy,
+			Restocked:   row.Restocked,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+		}
+		response[i] = newReturnRequestResponse(request)
+		response[i].ProductName = row.ProductName
+		response[i].Refund = newJoinedRefundResponse(row.RefundID, row.RefundAmount, row.RefundStatus)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+type listShopReturnRequestsRequest struct {
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+	Status   string `form:"status" binding:"omitempty,oneof=requested approved rejected received inspected"`
+}
+
+// listShopReturnRequests lists the return requests for a shop's items, newest first, for its seller
+// to handle
+func (server *Server) listShopReturnRequests(ctx *gin.Context) {
+	shopID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listShopReturnRequestsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListReturnRequestsByShopParams{
+		ShopID: shopID,
+		Status: db.NullReturnStatus{
+			ReturnStatus: db.ReturnStatus(req.Status),
+			Valid:        req.Status != "",
+		},
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	requests, err := server.store.ListReturnRequestsByShop(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]returnRequestResponse, len(requests))
+	for i, row := range requests {
+		request := db.ReturnRequest{
+			ID:          row.ID,
+			OrderID:     row.OrderID,
+			OrderItemID: row.OrderItemID,
+			ShopID:      row.ShopID,
+			UserID:      row.UserID,
+			Quantity:    row.Quantity,
+			Reason:      row.Reason,
+			Status:      row.Status,
+			Note:        row.Note,
+			HandledBy:   row.HandledBy,
+			Restocked:   row.Restocked,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+		}
+		response[i] = newReturnRequestResponse(request)
+		response[i].ProductName = row.ProductName
+		response[i].Username = row.Username
+		response[i].Refund = newJoinedRefundResponse(row.RefundID, row.RefundAmount, row.RefundStatus)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+type handleReturnRequestRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+// approveReturnRequest agrees to take the items back, so the buyer can send them
+func (server *Server) approveReturnRequest(ctx *gin.Context) {
+	// The note is optional, so an empty body is allowed
+	var req handleReturnRequestRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.changeReturnRequestStatus(ctx, db.ReturnStatusApproved, req.Note, nil)
+}
+
+type rejectReturnRequestRequest struct {
+	Note string `json:"note" binding:"required,max=500"`
+}
+
+// rejectReturnRequest refuses a return. The note tells the buyer why.
+func (server *Server) rejectReturnRequest(ctx *gin.Context) {
+	var req rejectReturnRequestRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.changeReturnRequestStatus(ctx, db.ReturnStatusRejected, req.Note, nil)
+}
+
+// receiveReturnRequest records that the returned items have arrived
+func (server *Server) receiveReturnRequest(ctx *gin.Context) {
+	// The note is optional, so an empty body is allowed
+	var req handleReturnRequestRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.changeReturnRequestStatus(ctx, db.ReturnStatusReceived, req.Note, nil)
+}
+
+// returnInspection is the outcome of inspecting returned items
+type returnInspection struct {
+	// Restock puts the returned items back in stock
+	Restock bool `json:"restock"`
+	// RefundAmount defaults to the full price of the returned items. Zero means no refund.
+	RefundAmount *money.Amount `json:"refund_amount"`
+}
+
+type inspectReturnRequestRequest struct {
+	Note string `json:"note" binding:"max=500"`
+	returnInspection
+}
+
+// inspectReturnRequest records the inspection of the returned items, which completes the return. The
+// items can go back in stock, and a refund is created for the buyer unless the refund amount is zero.
+func (server *Server) inspectReturnRequest(ctx *gin.Context) {
+	// All fields are optional, so an empty body is allowed
+	var req inspectReturnRequestRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.changeReturnRequestStatus(ctx, db.ReturnStatusInspected, req.Note, &req.returnInspection)
+}
+
+// changeReturnRequestStatus moves the return request identified by the :returnId path parameter to a
+// new status, on behalf of the seller of its shop or an admin. An inspection is only given when the
+// request is inspected.
+func (server *Server) changeReturnRequestStatus(ctx *gin.Context, to db.ReturnStatus, note string, inspection *returnInspection) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	id, err := uuid.Parse(ctx.Param("returnId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := server.store.GetReturnRequestForUpdateWithTx(ctx, tx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errReturnRequestNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if before.OrderID != orderID {
+		ctx.JSON(http.StatusNotFound, errorResponse(errReturnRequestNotFound))
+		return
+	}
+
+	if !canChangeReturnStatus(before.Status, to) {
+		err := fmt.Errorf("return request is %s and cannot be %s", before.Status, to)
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	var refund *db.Refund
+	restocked := false
+	if inspection != nil {
+		item, err := server.store.GetOrderItemForReturn(ctx, before.OrderItemID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		maxRefund := item.Price.Mul(before.Quantity)
+		amount := maxRefund
+		if inspection.RefundAmount != nil {
+			amount = *inspection.RefundAmount
+		}
+		if amount < 0 || amount > maxRefund {
+			err := fmt.Errorf("refund_amount must be between 0.00 and %s", maxRefund)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		if inspection.Restock {
+			arg := db.UpdateProductStockParams{
+				ID:            item.ProductID,
+				StockQuantity: before.Quantity,
+			}
+
+			_, err = server.store.UpdateProductStockWithTx(ctx, tx, arg)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+			restocked = true
+		}
+
+		if amount > 0 {
+			current, err := server.store.GetPaymentByOrderForUpdateWithTx(ctx, tx, before.OrderID)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+
+			returnRequestID := uuid.NullUUID{UUID: before.ID, Valid: true}
+			created, err := server.refundPayment(ctx, tx, current, amount, returnRequestID)
+			if err != nil {
+				if errors.Is(err, errRefundExceedsPayment) {
+					ctx.JSON(http.StatusConflict, errorResponse(err))
+					return
+				}
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+			refund = &created
+		}
+	}
+
+	arg := db.UpdateReturnRequestStatusParams{
+		ID:     before.ID,
+		Status: to,
+		Note: sql.NullString{
+			String: note,
+			Valid:  note != "",
+		},
+		HandledBy: uuid.NullUUID{UUID: authPayload.UserID, Valid: true},
+		Restocked: restocked,
+	}
+
+	request, err := server.store.UpdateReturnRequestStatusWithTx(ctx, tx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if refund != nil {
+		settled, err := server.payOutRefund(ctx, request.OrderID, refund.ID)
+		if err != nil {
+			log.Printf("Warning: cannot pay back refund %s: %v", refund.ID, err)
+		} else {
+			refund = &settled
+		}
+	}
+
+	rsp := newReturnRequestResponse(request)
+	if refund != nil {
+		rsp.Refund = newRefundResponse(*refund)
+	}
+	server.audit(ctx, auditReturnStatusUpdate, auditTargetReturnRequest, request.ID, newReturnRequestResponse(before), rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// retryReturnRefund pays back again the refund of an inspected return request, after the payment
+// provider turned it down. Inspected requests are final, so this is how their refund is settled.
+func (server *Server) retryReturnRefund(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	id, err := uuid.Parse(ctx.Param("returnId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	request, err := server.store.GetReturnRequest(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errReturnRequestNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if request.OrderID != orderID {
+		ctx.JSON(http.StatusNotFound, errorResponse(errReturnRequestNotFound))
+		return
+	}
+
+	before, err := server.store.GetRefundByReturnRequest(ctx, uuid.NullUUID{UUID: request.ID, Valid: true})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errReturnHasNoRefund))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	refund, err := server.retryRefund(ctx, request.OrderID, before.ID)
+	if err != nil {
+		if errors.Is(err, errRefundPaidBack) || errors.Is(err, errRefundByHand) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := newReturnRequestResponse(request)
+	rsp.Refund = newRefundResponse(refund)
+
+	beforeRsp := newReturnRequestResponse(request)
+	beforeRsp.Refund = newRefundResponse(before)
+	server.audit(ctx, auditReturnRefundRetry, auditTargetReturnRequest, request.ID, beforeRsp, rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
+}
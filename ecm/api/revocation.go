@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/token"
+)
+
+const (
+	// revocationCacheTTL bounds how long a negative lookup is trusted, i.e. how long it may take for
+	// a revocation made by another server instance to be noticed here
+	revocationCacheTTL = 30 * time.Second
+
+	// revocationPruneInterval controls how often expired cache entries and denylist rows are dropped
+	revocationPruneInterval = 10 * time.Minute
+)
+
+type cachedTokenRevocation struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+type cachedUserRevocation struct {
+	revokedBefore time.Time
+	// blocked is set for users who are suspended or no longer exist, whose tokens are all revoked
+	blocked   bool
+	expiresAt time.Time
+}
+
+// revocationStore keeps track of revoked tokens in the database, with an in-memory cache in front
+// of it so authMiddleware does not need a database round trip for every request
+type revocationStore struct {
+	store db.Store
+
+	mu        sync.Mutex
+	tokens    map[uuid.UUID]cachedTokenRevocation
+	sessions  map[uuid.UUID]cachedTokenRevocation
+	users     map[uuid.UUID]cachedUserRevocation
+	lastPrune time.Time
+}
+
+func newRevocationStore(store db.Store) *revocationStore {
+	return &revocationStore{
+		store:     store,
+		tokens:    make(map[uuid.UUID]cachedTokenRevocation),
+		sessions:  make(map[uuid.UUID]cachedTokenRevocation),
+		users:     make(map[uuid.UUID]cachedUserRevocation),
+		lastPrune: time.Now(),
+	}
+}
+
+// IsRevoked reports whether the token was revoked on its own, by blocking its session or by a
+// revocation of all the user's tokens, or belongs to a user who is suspended or deleted
+func (rs *revocationStore) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	revoked, err := rs.isTokenRevoked(ctx, payload)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	revoked, err = rs.isSessionBlocked(ctx, payload)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	user, err := rs.userRevocation(ctx, payload.UserID)
+	if err != nil {
+		return false, err
+	}
+	return user.blocked || payload.IssuedAt.Before(user.revokedBefore), nil
+}
+
+// Revoke adds a single token to the denylist until it expires
+func (rs *revocationStore) Revoke(ctx context.Context, payload *token.Payload) error {
+	err := rs.store.RevokeToken(ctx, db.RevokeTokenParams{
+		ID:        payload.ID,
+		UserID:    payload.UserID,
+		ExpiresAt: payload.ExpiredAt.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	rs.mu.Lock()
+	rs.tokens[payload.ID] = cachedTokenRevocation{revoked: true, expiresAt: payload.ExpiredAt}
+	rs.mu.Unlock()
+	return nil
+}
+
+// RevokeUser revokes every token issued to the user up to now
+func (rs *revocationStore) RevokeUser(ctx context.Context, userID uuid.UUID) error {
+	err := rs.store.RevokeUserTokens(ctx, db.RevokeUserTokensParams{
+		UserID:        userID,
+		RevokedBefore: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	rs.ForgetUser(userID)
+	return nil
+}
+
+// RevokeUserWithTx revokes every token issued to the user up to now, as part of a transaction.
+// ForgetUser must be called once it commits.
+func (rs *revocationStore) RevokeUserWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	return rs.store.RevokeUserTokensWithTx(ctx, tx, db.RevokeUserTokensParams{
+		UserID:        userID,
+		RevokedBefore: time.Now().UTC(),
+	})
+}
+
+// SessionBlocked records that a session was blocked, so that the access tokens issued for it are
+// refused here straight away. Other server instances notice it within revocationCacheTTL.
+func (rs *revocationStore) SessionBlocked(session db.Session) {
+	rs.mu.Lock()
+	rs.sessions[session.ID] = cachedTokenRevocation{revoked: true, expiresAt: session.ExpiresAt}
+	rs.mu.Unlock()
+}
+
+// ForgetUser drops what is cached about a user, so that a change to their tokens or suspension takes
+// effect here straight away. Other server instances notice it within revocationCacheTTL.
+func (rs *revocationStore) ForgetUser(userID uuid.UUID) {
+	rs.mu.Lock()
+	delete(rs.users, userID)
+	rs.mu.Unlock()
+}
+
+func (rs *revocationStore) isTokenRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	now := time.Now()
+
+	rs.mu.Lock()
+	rs.pruneLocked(now)
+	cached, ok := rs.tokens[payload.ID]
+	rs.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.revoked, nil
+	}
+
+	revoked, err := rs.store.IsTokenRevoked(ctx, payload.ID)
+	if err != nil {
+		return false, err
+	}
+
+	// A revocation is permanent, so it can be cached for as long as the token would be valid
+	entry := cachedTokenRevocation{revoked: revoked, expiresAt: now.Add(revocationCacheTTL)}
+	if revoked {
+		entry.expiresAt = payload.ExpiredAt
+	}
+
+	rs.mu.Lock()
+	rs.tokens[payload.ID] = entry
+	rs.mu.Unlock()
+	return revoked, nil
+}
+
+// isSessionBlocked reports whether the token's session was blocked or no longer exists. Tokens that
+// belong to no session, such as MFA challenge tokens, are not affected.
+func (rs *revocationStore) isSessionBlocked(ctx context.Context, payload *token.Payload) (bool, error) {
+	if payload.SessionID == uuid.Nil {
+		return false, nil
+	}
+	now := time.Now()
+
+	rs.mu.Lock()
+	cached, ok := rs.sessions[payload.SessionID]
+	rs.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.revoked, nil
+	}
+
+	session, err := rs.store.GetSession(ctx, payload.SessionID)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	blocked := err == sql.ErrNoRows || session.IsBlocked || session.UserID != payload.UserID
+
+	// Sessions are never unblocked, so a blocked one can be cached for as long as the token is valid
+	entry := cachedTokenRevocation{revoked: blocked, expiresAt: now.Add(revocationCacheTTL)}
+	if blocked {
+		entry.expiresAt = payload.ExpiredAt
+	}
+
+	rs.mu.Lock()
+	rs.sessions[payload.SessionID] = entry
+	rs.mu.Unlock()
+	return blocked, nil
+}
+
+func (rs *revocationStore) userRevocation(ctx context.Context, userID uuid.UUID) (cachedUserRevocation, error) {
+	now := time.Now()
+
+	rs.mu.Lock()
+	cached, ok := rs.users[userID]
+	rs.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached, nil
+	}
+
+	status, err := rs.store.GetUserTokenStatus(ctx, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return cachedUserRevocation{}, err
+	}
+
+	entry := cachedUserRevocation{
+		revokedBefore: status.RevokedBefore.Time,
+		blocked:       err == sql.ErrNoRows || status.SuspendedAt.Valid,
+		expiresAt:     now.Add(revocationCacheTTL),
+	}
+
+	rs.mu.Lock()
+	rs.users[userID] = entry
+	rs.mu.Unlock()
+	return entry, nil
+}
+
+// pruneLocked drops stale cache entries and, in the background, expired denylist rows.
+// rs.mu must be held.
+func (rs *revocationStore) pruneLocked(now time.Time) {
+	if now.Sub(rs.lastPrune) < revocationPruneInterval {
+		return
+	}
+	rs.lastPrune = now
+
+	for id, entry := range rs.tokens {
+		if now.After(entry.expiresAt) {
+			delete(rs.tokens, id)
+		}
+	}
+	for id, entry := range rs.sessions {
+		if now.After(entry.expiresAt) {
+			delete(rs.sessions, id)
+		}
+	}
+	for id, entry := range rs.users {
+		if now.After(entry.expiresAt) {
+			delete(rs.users, id)
+		}
+	}
+
+	go func() {
+		if err := rs.store.DeleteExpiredRevokedTokens(context.Background()); err != nil {
+			log.Println("Warning: failed to delete expired revoked tokens:", err)
+		}
+	}()
+}
@@ -0,0 +1,335 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/mail"
+	"github.com/qhh/ecm/token"
+)
+
+var (
+	errSellerApplicationNotFound = errors.New("seller application not found")
+	errSellerApplicationReviewed = errors.New("seller application has already been reviewed")
+)
+
+type createSellerApplicationRequest struct {
+	BusinessName    string `json:"business_name" binding:"required,max=255"`
+	BusinessEmail   string `json:"business_email" binding:"required,email,max=255"`
+	BusinessPhone   string `json:"business_phone" binding:"required,max=50"`
+	BusinessAddress string `json:"business_address" binding:"required"`
+	TaxID           string `json:"tax_id" binding:"required,max=100"`
+	Description     string `json:"description"`
+}
+
+type sellerApplicationResponse struct {
+	ID              uuid.UUID `json:"id"`
+	UserID          uuid.UUID `json:"user_id"`
+	BusinessName    string    `json:"business_name"`
+	BusinessEmail   string    `json:"business_email"`
+	BusinessPhone   string    `json:"business_phone"`
+	BusinessAddress string    `json:"business_address"`
+	TaxID           string    `json:"tax_id"`
+	Description     string    `json:"description"`
+	Status          string    `json:"status"`
+	ReviewNote      string    `json:"review_note,omitempty"`
+	ReviewedAt      string    `json:"reviewed_at,omitempty"`
+	CreatedAt       string    `json:"created_at"`
+	UpdatedAt       string    `json:"updated_at"`
+}
+
+func newSellerApplicationResponse(application db.SellerApplication) sellerApplicationResponse {
+	rsp := sellerApplicationResponse{
+		ID:              application.ID,
+		UserID:          application.UserID,
+		BusinessName:    application.BusinessName,
+		BusinessEmail:   application.BusinessEmail,
+		BusinessPhone:   application.BusinessPhone,
+		BusinessAddress: application.BusinessAddress,
+		TaxID:           application.TaxID,
+		Description:     application.Description.String,
+		Status:          string(application.Status),
+		ReviewNote:      application.ReviewNote.String,
+		CreatedAt:       application.CreatedAt.String(),
+		UpdatedAt:       application.UpdatedAt.String(),
+	}
+	if application.ReviewedAt.Valid {
+		rsp.ReviewedAt = application.ReviewedAt.Time.String()
+	}
+	return rsp
+}
+
+// createSellerApplication asks for the authenticated buyer to become a seller. The role only
+// changes once an admin approves the application.
+func (server *Server) createSellerApplication(ctx *gin.Context) {
+	var req createSellerApplicationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if !server.requireVerifiedEmail(ctx, authPayload.UserID) {
+		return
+	}
+
+	arg := db.CreateSellerApplicationParams{
+		UserID:          authPayload.UserID,
+		BusinessName:    req.BusinessName,
+		BusinessEmail:   req.BusinessEmail,
+		BusinessPhone:   req.BusinessPhone,
+		BusinessAddress: req.BusinessAddress,
+		TaxID:           req.TaxID,
+		Description: sql.NullString{
+			String: req.Description,
+			Valid:  req.Description != "",
+		},
+	}
+
+	application, err := server.store.CreateSellerApplication(ctx, arg)
+	if err != nil {
+		if isUniqueViolation(err, "seller_applications_pending_user_id_key") {
+			ctx.JSON(http.StatusConflict, errorResponse(errors.New("you already have a seller application waiting for review")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newSellerApplicationResponse(application))
+}
+
+// getMySellerApplication returns the authenticated user's most recent seller application
+func (server *Server) getMySellerApplication(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	application, err := server.store.GetLatestSellerApplicationByUser(ctx, authPayload.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errSellerApplicationNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newSellerApplicationResponse(application))
+}
+
+type listSellerApplicationsRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending approved rejected"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (server *Server) listSellerApplications(ctx *gin.Context) {
+	var req listSellerApplicationsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListSellerApplicationsParams{
+		Status: db.NullSellerApplicationStatus{
+			SellerApplicationStatus: db.SellerApplicationStatus(req.Status),
+			Valid:                   req.Status != "",
+		},
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	applications, err := server.store.ListSellerApplications(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]sellerApplicationResponse, len(applications))
+	for i, application := range applications {
+		response[i] = newSellerApplicationResponse(application)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (server *Server) getSellerApplication(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	application, err := server.store.GetSellerApplication(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errSellerApplicationNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newSellerApplicationResponse(application))
+}
+
+type approveSellerApplicationRequest struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+// approveSellerApplication approves a pending application and makes the applicant a seller. The new
+// role shows up in the applicant's access token the next time it is renewed.
+func (server *Server) approveSellerApplication(ctx *gin.Context) {
+	// The note is optional, so an empty body is allowed
+	var req approveSellerApplicationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.reviewSellerApplication(ctx, db.SellerApplicationStatusApproved, req.Note)
+}
+
+type rejectSellerApplicationRequest struct {
+	Note string `json:"note" binding:"required,max=1000"`
+}
+
+// rejectSellerApplication rejects a pending application. The note tells the applicant why.
+func (server *Server) rejectSellerApplication(ctx *gin.Context) {
+	var req rejectSellerApplicationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.reviewSellerApplication(ctx, db.SellerApplicationStatusRejected, req.Note)
+}
+
+// reviewSellerApplication records the decision on the pending application identified by the :id path
+// parameter, promotes the applicant on approval, and lets them know the outcome
+func (server *Server) reviewSellerApplication(ctx *gin.Context, status db.SellerApplicationStatus, note string) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	pending, err := server.store.GetSellerApplication(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errSellerApplicationNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if pending.Status != db.SellerApplicationStatusPending {
+		ctx.JSON(http.StatusConflict, errorResponse(errSellerApplicationReviewed))
+		return
+	}
+
+	applicant, err := server.store.GetUser(ctx, pending.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	arg := db.ReviewSellerApplicationParams{
+		ID:         pending.ID,
+		Status:     status,
+		ReviewedBy: uuid.NullUUID{UUID: authPayload.UserID, Valid: true},
+		ReviewNote: sql.NullString{
+			String: note,
+			Valid:  note != "",
+		},
+	}
+
+	// Another admin may have reviewed the application since it was read
+	application, err := server.store.ReviewSellerApplicationWithTx(ctx, tx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(errSellerApplicationReviewed))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Only buyers are promoted, so approving an old application never demotes an admin. The role is
+	// checked by the update itself, in case it changed since the applicant was read.
+	var promoted *db.User
+	if status == db.SellerApplicationStatusApproved {
+		seller, err := server.store.PromoteBuyerToSellerWithTx(ctx, tx, applicant.ID)
+		if err != nil && err != sql.ErrNoRows {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if err == nil {
+			promoted = &seller
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := newSellerApplicationResponse(application)
+	server.audit(ctx, auditSellerApplicationReview, auditTargetSellerApplication, application.ID, newSellerApplicationResponse(pending), rsp)
+	if promoted != nil {
+		buyer := *promoted
+		buyer.Role = db.UserRoleBuyer
+		server.audit(ctx, auditUserRoleUpdate, auditTargetUser, promoted.ID, newUserResponse(buyer), newUserResponse(*promoted))
+	}
+
+	// The decision is recorded either way; the applicant can still see it in their profile
+	err = server.sendSellerApplicationDecision(ctx, applicant, application)
+	if err != nil {
+		log.Println("Warning: failed to send seller application decision:", err)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// sendSellerApplicationDecision emails the applicant the outcome of their seller application
+func (server *Server) sendSellerApplicationDecision(ctx *gin.Context, applicant db.User, application db.SellerApplication) error {
+	var body string
+	if application.Status == db.SellerApplicationStatusApproved {
+		body = fmt.Sprintf(
+			"Hi %s,\n\nYour application to sell as %s has been approved. You can now set up your shop.\n",
+			applicant.Username, application.BusinessName,
+		)
+	} else {
+		body = fmt.Sprintf(
+			"Hi %s,\n\nYour application to sell as %s has not been approved.\n",
+			applicant.Username, application.BusinessName,
+		)
+	}
+	if application.ReviewNote.Valid {
+		body += fmt.Sprintf("\nNote from our team:\n%s\n", application.ReviewNote.String)
+	}
+
+	msg := mail.Message{
+		To:      applicant.Email,
+		Subject: "Your seller application has been reviewed",
+		Body:    body,
+	}
+	return server.mailer.Send(ctx, msg)
+}
@@ -1,38 +1,89 @@
 package api
 
 import (
+	"fmt"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/mail"
+	"github.com/qhh/ecm/payment"
+	"github.com/qhh/ecm/shipping"
 	"github.com/qhh/ecm/token"
 	"github.com/qhh/ecm/util"
 )
 
 // Server serves HTTP requests for our e-commerce service
 type Server struct {
-	config     util.Config
-	store      db.Store
-	tokenMaker token.Maker
-	router     *gin.Engine
+	config        util.Config
+	store         db.Store
+	tokenMaker    token.Maker
+	revocations   *revocationStore
+	loginThrottle *loginThrottle
+	authorizer    *authorizer
+	mailer        mail.Sender
+	carrier       shipping.Carrier
+	payments      payment.Provider
+	router        *gin.Engine
 }
 
 // NewServer creates a new HTTP server and setup routing
 func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewJWTMaker(config.JWTSecret)
+	tokenMaker, err := newTokenMaker(config)
+	if err != nil {
+		return nil, err
+	}
+
+	mailer, err := mail.NewSender(config.MailDriver, config.MailFrom, config.MailDir)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := payment.NewProvider(config.PaymentProvider, config.PaymentWebhookSecret)
 	if err != nil {
 		return nil, err
 	}
 
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:        config,
+		store:         store,
+		tokenMaker:    tokenMaker,
+		revocations:   newRevocationStore(store),
+		loginThrottle: newLoginThrottle(store),
+		authorizer:    newAuthorizer(store),
+		mailer:        mailer,
+		payments:      payments,
+	}
+
+	server.carrier, err = shipping.NewCarrier(config.ShippingCarrier, config.ShippingEventInterval, server.ingestTrackingEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.UnpaidOrderExpiry > 0 {
+		go server.expireUnpaidOrders(config.UnpaidOrderExpiry)
 	}
 
 	server.setupRouter()
 	return server, nil
 }
 
+// newTokenMaker creates the token maker selected by config.TokenType
+func newTokenMaker(config util.Config) (token.Maker, error) {
+	switch config.TokenType {
+	case "jwt":
+		return token.NewJWTMaker(config.JWTSecret)
+	case "jwt_asymmetric":
+		return token.NewAsymmetricJWTMaker(config.JWTKeyDir)
+	case "paseto_local":
+		return token.NewPasetoMaker(config.TokenSymmetricKey)
+	case "paseto_public":
+		return token.NewPasetoPublicMaker(config.TokenPrivateKey)
+	default:
+		return nil, fmt.Errorf("unsupported token type %q", config.TokenType)
+	}
+}
+
 // setupRouter sets up all the routes for our server
 func (server *Server) setupRouter() {
 	router := gin.Default()
@@ -41,15 +92,25 @@ func (server *Server) setupRouter() {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-Request-ID"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           86400, // Maximum value not ignored by any major browser (1 day)
 	}))
+	router.Use(requestIDMiddleware())
 
 	// Public routes
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
+	router.POST("/users/login/mfa", server.loginMFA)
+	router.POST("/users/login/mfa/enroll", server.loginMFAEnroll)
+	router.GET("/users/verify", server.verifyEmail)
+	router.POST("/users/password/forgot", server.forgotPassword)
+	router.POST("/users/password/reset", server.resetPassword)
+	router.POST("/tokens/renew_access", server.renewAccessToken)
+	if publisher, ok := server.tokenMaker.(token.KeyPublisher); ok {
+		router.GET("/.well-known/jwks.json", server.getJWKS(publisher))
+	}
 	router.GET("/categories", server.listCategories)
 	router.GET("/categories/:id", server.getCategory)
 	router.GET("/products", server.listProducts)
@@ -57,30 +118,49 @@ func (server *Server) setupRouter() {
 	router.GET("/products/search", server.searchProducts)
 	router.GET("/categories/:id/products", server.listProductsByCategory)
 
+	// Payment providers sign their webhooks instead of authenticating
+	router.POST("/webhooks/payments/:provider", server.receivePaymentWebhook)
+
 	// Routes that require authentication
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.revocations))
 
 	// User routes
 	authRoutes.GET("/users/me", server.getCurrentUser)
-	authRoutes.PATCH("/users/role", server.updateUserRole)
+	authRoutes.PATCH("/users/me", server.updateCurrentUser)
+	authRoutes.PUT("/users/me/password", server.changePassword)
+	authRoutes.POST("/users/verify/resend", server.resendVerificationEmail)
+	authRoutes.POST("/users/logout", server.logoutUser)
+	authRoutes.POST("/users/logout_all", server.logoutAllUser)
+	authRoutes.GET("/users/sessions", server.listSessions)
+	authRoutes.DELETE("/users/sessions/:id", server.revokeSession)
+	authRoutes.POST("/users/2fa/enroll", server.enrollTOTP)
+	authRoutes.POST("/users/2fa/confirm", server.confirmTOTP)
+	authRoutes.POST("/users/2fa/disable", server.disableTOTP)
+
+	// Seller application routes
+	authRoutes.POST("/seller-applications", server.requirePermission(permSellerApplicationCreate), server.createSellerApplication)
+	authRoutes.GET("/seller-applications/me", server.getMySellerApplication)
 
 	// Shop routes
-	authRoutes.POST("/shops", server.createShop)
+	authRoutes.POST("/shops", server.requirePermission(permShopCreate), server.createShop)
 	authRoutes.GET("/shops", server.listShops)
 	authRoutes.GET("/shops/:id", server.getShop)
-	authRoutes.PUT("/shops/:id", server.updateShop)
-	authRoutes.DELETE("/shops/:id", server.deleteShop)
-	authRoutes.GET("/shops/:id/products", server.listProductsByShop)
+	authRoutes.PUT("/shops/:id", server.requireOwnedPermission(permShopWrite, shopOwner), server.updateShop)
+	authRoutes.DELETE("/shops/:id", server.requireOwnedPermission(permShopWrite, shopOwner), server.deleteShop)
+	authRoutes.GET("/shops/:id/products", server.requireOwnedPermission(permShopRead, shopOwner), server.listProductsByShop)
+	authRoutes.GET("/shops/:id/orders", server.requireOwnedPermission(permShopRead, shopOwner), server.listShopOrders)
+	authRoutes.PATCH("/shops/:id/orders/:subOrderId/status", server.requireOwnedPermission(permShopOrderStatus, shopOwner), server.updateShopOrderStatus)
+	authRoutes.GET("/shops/:id/returns", server.requireOwnedPermission(permShopRead, shopOwner), server.listShopReturnRequests)
 
 	// Product routes
 	authRoutes.POST("/products", server.createProduct)
-	authRoutes.PUT("/products/:id", server.updateProduct)
-	authRoutes.DELETE("/products/:id", server.deleteProduct)
+	authRoutes.PUT("/products/:id", server.requireOwnedPermission(permProductWrite, productOwner), server.updateProduct)
+	authRoutes.DELETE("/products/:id", server.requireOwnedPermission(permProductWrite, productOwner), server.deleteProduct)
 
-	// Category routes (admin only)
-	authRoutes.POST("/categories", server.createCategory)
-	authRoutes.PUT("/categories/:id", server.updateCategory)
-	authRoutes.DELETE("/categories/:id", server.deleteCategory)
+	// Category routes
+	authRoutes.POST("/categories", server.requirePermission(permCategoryManage), server.createCategory)
+	authRoutes.PUT("/categories/:id", server.requirePermission(permCategoryManage), server.updateCategory)
+	authRoutes.DELETE("/categories/:id", server.requirePermission(permCategoryManage), server.deleteCategory)
 
 	// Cart routes
 	authRoutes.GET("/cart", server.getCart)
@@ -90,10 +170,38 @@ func (server *Server) setupRouter() {
 	authRoutes.DELETE("/cart", server.clearCart)
 
 	// Order routes
-	authRoutes.POST("/orders", server.createOrder)
+	authRoutes.POST("/orders", server.requirePermission(permOrderCreate), server.createOrder)
 	authRoutes.GET("/orders", server.getUserOrders)
-	authRoutes.GET("/orders/:id", server.getOrder)
-	authRoutes.PATCH("/orders/:id/status", server.updateOrderStatus)
+	authRoutes.GET("/orders/all", server.requirePermission(permOrderReadAny), server.listAllOrders)
+	authRoutes.GET("/orders/:id", server.requireOwnedPermission(permOrderRead, orderOwner), server.getOrder)
+	authRoutes.PATCH("/orders/:id/status", server.requirePermission(permOrderStatusAny), server.updateOrderStatus)
+	authRoutes.POST("/orders/:id/cancel", server.requireOwnedPermission(permOrderCancel, orderOwner), server.cancelOrder)
+	authRoutes.POST("/orders/:id/payment", server.requireOwnedPermission(permOrderPay, orderOwner), server.payOrder)
+	authRoutes.GET("/orders/:id/tracking", server.requireOwnedPermission(permOrderRead, orderOwner), server.getOrderTracking)
+
+	// Return routes
+	authRoutes.POST("/orders/:id/returns", server.requireOwnedPermission(permReturnCreate, orderOwner), server.createReturnRequest)
+	authRoutes.GET("/orders/:id/returns", server.requireOwnedPermission(permOrderRead, orderOwner), server.listOrderReturnRequests)
+	authRoutes.POST("/orders/:id/returns/:returnId/approve", server.requireOwnedPermission(permReturnReview, returnOwner), server.approveReturnRequest)
+	authRoutes.POST("/orders/:id/returns/:returnId/reject", server.requireOwnedPermission(permReturnReview, returnOwner), server.rejectReturnRequest)
+	authRoutes.POST("/orders/:id/returns/:returnId/receive", server.requireOwnedPermission(permReturnReview, returnOwner), server.receiveReturnRequest)
+	authRoutes.POST("/orders/:id/returns/:returnId/inspect", server.requireOwnedPermission(permReturnReview, returnOwner), server.inspectReturnRequest)
+	authRoutes.POST("/orders/:id/returns/:returnId/refund/retry", server.requireOwnedPermission(permReturnReview, returnOwner), server.retryReturnRefund)
+
+	// Admin routes
+	authRoutes.GET("/users", server.requirePermission(permUserManage), server.listUsers)
+	authRoutes.GET("/users/:id", server.requirePermission(permUserManage), server.getUser)
+	authRoutes.PATCH("/users/:id/role", server.requirePermission(permUserManage), server.updateUserRole)
+	authRoutes.DELETE("/users/:id", server.requirePermission(permUserManage), server.deleteUser)
+	authRoutes.POST("/admin/users/:id/suspend", server.requirePermission(permUserManage), server.suspendUser)
+	authRoutes.POST("/admin/users/:id/unsuspend", server.requirePermission(permUserManage), server.unsuspendUser)
+	authRoutes.POST("/admin/users/:id/unlock", server.requirePermission(permUserManage), server.unlockUser)
+	authRoutes.GET("/admin/seller-applications", server.requirePermission(permSellerApplicationReview), server.listSellerApplications)
+	authRoutes.GET("/admin/seller-applications/:id", server.requirePermission(permSellerApplicationReview), server.getSellerApplication)
+	authRoutes.POST("/admin/seller-applications/:id/approve", server.requirePermission(permSellerApplicationReview), server.approveSellerApplication)
+	authRoutes.POST("/admin/seller-applications/:id/reject", server.requirePermission(permSellerApplicationReview), server.rejectSellerApplication)
+	authRoutes.GET("/admin/stats", server.requirePermission(permStatsRead), server.getAdminStats)
+	authRoutes.GET("/admin/audit", server.requirePermission(permAuditRead), server.listAuditEvents)
 
 	server.router = router
 }
@@ -102,9 +210,3 @@ func (server *Server) setupRouter() {
 func (server *Server) Start(address string) error {
 	return server.router.Run(address)
 }
-
-// Helper method to check if user is admin
-func (server *Server) isAdmin(ctx *gin.Context) bool {
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	return authPayload.Role == "admin"
-}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/token"
+)
+
+// maxUserAgentLength matches the size of sessions.user_agent
+const maxUserAgentLength = 512
+
+type sessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIP   string    `json:"client_ip"`
+	Current    bool      `json:"current"`
+	ExpiresAt  string    `json:"expires_at"`
+	LastUsedAt string    `json:"last_used_at"`
+	CreatedAt  string    `json:"created_at"`
+}
+
+func newSessionResponse(session db.Session, currentSessionID uuid.UUID) sessionResponse {
+	return sessionResponse{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		ClientIP:   session.ClientIp,
+		Current:    session.ID == currentSessionID,
+		ExpiresAt:  session.ExpiresAt.String(),
+		LastUsedAt: session.LastUsedAt.String(),
+		CreatedAt:  session.CreatedAt.String(),
+	}
+}
+
+// clientUserAgent returns the request's user agent, cut to fit the sessions table
+func clientUserAgent(ctx *gin.Context) string {
+	userAgent := ctx.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return userAgent
+}
+
+func (server *Server) listSessions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	sessions, err := server.store.ListActiveSessionsByUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]sessionResponse, len(sessions))
+	for i, session := range sessions {
+		response[i] = newSessionResponse(session, authPayload.SessionID)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (server *Server) revokeSession(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.BlockSessionParams{
+		ID:     id,
+		UserID: authPayload.UserID,
+	}
+
+	session, err := server.store.BlockSession(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("session not found")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocations.SessionBlocked(session)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// logoutUser revokes the access token used for the request and blocks the session it belongs to,
+// so neither it nor the session's refresh token can be used again
+func (server *Server) logoutUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err := server.revocations.Revoke(ctx, authPayload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.BlockSessionParams{
+		ID:     authPayload.SessionID,
+		UserID: authPayload.UserID,
+	}
+
+	session, err := server.store.BlockSession(ctx, arg)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil {
+		server.revocations.SessionBlocked(session)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// logoutAllUser revokes every token issued to the user so far and blocks all of their sessions
+func (server *Server) logoutAllUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err := server.revokeAllUserSessions(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "logged out of all sessions"})
+}
+
+// revokeAllUserSessions invalidates every access and refresh token issued to the user
+func (server *Server) revokeAllUserSessions(ctx *gin.Context, userID uuid.UUID) error {
+	err := server.revocations.RevokeUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return server.store.BlockUserSessions(ctx, userID)
+}
+
+// revokeAllUserSessionsWithTx is revokeAllUserSessions as part of a transaction.
+// server.revocations.ForgetUser must be called once it commits.
+func (server *Server) revokeAllUserSessionsWithTx(ctx *gin.Context, tx *sql.Tx, userID uuid.UUID) error {
+	err := server.revocations.RevokeUserWithTx(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	return server.store.BlockUserSessionsWithTx(ctx, tx, userID)
+}
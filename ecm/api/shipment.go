@@ -0,0 +1,323 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/shipping"
+)
+
+var errShipmentDetailsNotShipped = errors.New("carrier, tracking_number and estimated_delivery can only be given when shipping")
+
+// shipmentDetails describes the parcel of a shop order that is being shipped. Without a tracking
+// number the parcel is booked with the server's carrier instead.
+type shipmentDetails struct {
+	Carrier           string     `json:"carrier" binding:"required_with=TrackingNumber,max=50"`
+	TrackingNumber    string     `json:"tracking_number" binding:"required_with=Carrier,max=100"`
+	EstimatedDelivery *time.Time `json:"estimated_delivery"`
+}
+
+func (details shipmentDetails) isEmpty() bool {
+	return details.Carrier == "" && details.TrackingNumber == "" && details.EstimatedDelivery == nil
+}
+
+// shipShopOrder creates the shipment of a shop order that is being shipped, holding all of its items.
+// Parcels without a tracking number are booked with server.carrier, and are handed over to it by
+// dispatchShipments once the transaction commits. The carrier then reports their tracking events to
+// ingestTrackingEvent.
+func (server *Server) shipShopOrder(ctx context.Context, tx *sql.Tx, order db.Order, shopOrder db.ShopOrder, details shipmentDetails) error {
+	carrier := details.Carrier
+	trackingNumber := details.TrackingNumber
+	var estimatedDelivery sql.NullTime
+	if details.EstimatedDelivery != nil {
+		estimatedDelivery = sql.NullTime{Time: *details.EstimatedDelivery, Valid: true}
+	}
+
+	if trackingNumber == "" {
+		parcel := shipping.Parcel{
+			Reference: shopOrder.ID.String(),
+			Address:   order.ShippingAddress,
+		}
+
+		label, err := server.carrier.CreateShipment(ctx, parcel)
+		if err != nil {
+			return fmt.Errorf("cannot book shipment with %s: %w", server.carrier.Name(), err)
+		}
+
+		carrier = server.carrier.Name()
+		trackingNumber = label.TrackingNumber
+		if !estimatedDelivery.Valid {
+			estimatedDelivery = sql.NullTime{Time: label.EstimatedDelivery, Valid: true}
+		}
+	}
+
+	arg := db.CreateShipmentParams{
+		OrderID:           shopOrder.OrderID,
+		ShopOrderID:       shopOrder.ID,
+		Carrier:           carrier,
+		TrackingNumber:    trackingNumber,
+		EstimatedDelivery: estimatedDelivery,
+	}
+
+	shipment, err := server.store.CreateShipmentWithTx(ctx, tx, arg)
+	if err != nil {
+		return err
+	}
+
+	itemsArg := db.AddShopOrderItemsToShipmentParams{
+		ShipmentID:  shipment.ID,
+		ShopOrderID: shopOrder.ID,
+	}
+
+	err = server.store.AddShopOrderItemsToShipmentWithTx(ctx, tx, itemsArg)
+	if err != nil {
+		return err
+	}
+
+	eventArg := db.CreateTrackingEventParams{
+		ShipmentID:  shipment.ID,
+		Status:      db.ShipmentStatusLabelCreated,
+		Description: "Shipping label created",
+		OccurredAt:  shipment.CreatedAt,
+	}
+
+	_, err = server.store.CreateTrackingEventWithTx(ctx, tx, eventArg)
+	return err
+}
+
+// dispatchShipments hands the parcels of an order that were booked with server.carrier over to it, once
+// the transaction that saved their shipments has committed. The shipments are already saved, so a
+// failure is only logged.
+func (server *Server) dispatchShipments(ctx context.Context, orderID uuid.UUID) {
+	shipments, err := server.store.ListShipmentsByOrder(ctx, orderID)
+	if err != nil {
+		log.Printf("Warning: failed to list shipments of order %s to dispatch: %v", orderID, err)
+		return
+	}
+
+	for _, shipment := range shipments {
+		if shipment.Carrier != server.carrier.Name() || shipment.Status != db.ShipmentStatusLabelCreated {
+			continue
+		}
+
+		err = server.carrier.Dispatch(ctx, shipment.TrackingNumber)
+		if err != nil {
+			log.Printf("Warning: failed to dispatch shipment %s with %s: %v", shipment.ID, shipment.Carrier, err)
+		}
+	}
+}
+
+// ingestTrackingEvent records a tracking event reported by a carrier and updates the status of the
+// shipments with its tracking number. Events that were already recorded are ignored, so carriers can
+// safely report them again. Once a parcel is delivered, its shop order is marked as delivered too.
+func (server *Server) ingestTrackingEvent(ctx context.Context, event shipping.Event) error {
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	arg := db.ListShipmentsByTrackingNumberForUpdateParams{
+		Carrier:        event.Carrier,
+		TrackingNumber: event.TrackingNumber,
+	}
+
+	shipments, err := server.store.ListShipmentsByTrackingNumberForUpdateWithTx(ctx, tx, arg)
+	if err != nil {
+		return err
+	}
+	if len(shipments) == 0 {
+		return fmt.Errorf("no shipment with %s tracking number %s", event.Carrier, event.TrackingNumber)
+	}
+
+	for _, shipment := range shipments {
+		eventArg := db.CreateTrackingEventParams{
+			ShipmentID:  shipment.ID,
+			Status:      db.ShipmentStatus(event.Status),
+			Location:    event.Location,
+			Description: event.Description,
+			OccurredAt:  event.OccurredAt,
+		}
+
+		added, err := server.store.CreateTrackingEventWithTx(ctx, tx, eventArg)
+		if err != nil {
+			return err
+		}
+		if added == 0 {
+			continue
+		}
+
+		shipment, err = server.store.RefreshShipmentStatusWithTx(ctx, tx, shipment.ID)
+		if err != nil {
+			return err
+		}
+
+		if shipment.Status == db.ShipmentStatusDelivered {
+			err = server.deliverShopOrder(ctx, tx, shipment)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// deliverShopOrder marks the shop order of a delivered shipment as delivered, unless a seller or admin
+// already did
+func (server *Server) deliverShopOrder(ctx context.Context, tx *sql.Tx, shipment db.Shipment) error {
+	order, err := server.store.GetOrderForUpdateWithTx(ctx, tx, shipment.OrderID)
+	if err != nil {
+		return err
+	}
+
+	shopOrder, err := server.store.GetShopOrderForUpdateWithTx(ctx, tx, shipment.ShopOrderID)
+	if err != nil {
+		return err
+	}
+	if shopOrder.Status != db.OrderStatusShipped {
+		return nil
+	}
+
+	note := fmt.Sprintf("Delivered according to %s", shipment.Carrier)
+	_, err = server.changeShopOrderStatus(ctx, tx, order, shopOrder, db.OrderStatusDelivered, uuid.Nil, note, shipmentDetails{})
+	if err != nil {
+		return err
+	}
+
+	_, err = server.syncOrderStatus(ctx, tx, order, uuid.Nil, note)
+	return err
+}
+
+type trackingEventResponse struct {
+	Status      string `json:"status"`
+	Location    string `json:"location,omitempty"`
+	Description string `json:"description,omitempty"`
+	OccurredAt  string `json:"occurred_at"`
+}
+
+type shipmentItemResponse struct {
+	OrderItemID uuid.UUID `json:"order_item_id"`
+	ProductID   uuid.UUID `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	Quantity    int32     `json:"quantity"`
+}
+
+type shipmentResponse struct {
+	ID                uuid.UUID               `json:"id"`
+	ShopOrderID       uuid.UUID               `json:"shop_order_id"`
+	Carrier           string                  `json:"carrier"`
+	TrackingNumber    string                  `json:"tracking_number"`
+	Status            string                  `json:"status"`
+	EstimatedDelivery string                  `json:"estimated_delivery,omitempty"`
+	DeliveredAt       string                  `json:"delivered_at,omitempty"`
+	CreatedAt         string                  `json:"created_at"`
+	Items             []shipmentItemResponse  `json:"items"`
+	Events            []trackingEventResponse `json:"events"`
+}
+
+func newShipmentResponse(shipment db.Shipment) shipmentResponse {
+	rsp := shipmentResponse{
+		ID:             shipment.ID,
+		ShopOrderID:    shipment.ShopOrderID,
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+		Status:         string(shipment.Status),
+		CreatedAt:      shipment.CreatedAt.String(),
+		Items:          []shipmentItemResponse{},
+		Events:         []trackingEventResponse{},
+	}
+	if shipment.EstimatedDelivery.Valid {
+		rsp.EstimatedDelivery = shipment.EstimatedDelivery.Time.String()
+	}
+	if shipment.DeliveredAt.Valid {
+		rsp.DeliveredAt = shipment.DeliveredAt.Time.String()
+	}
+	return rsp
+}
+
+type orderTrackingResponse struct {
+	OrderID   uuid.UUID          `json:"order_id"`
+	Status    string             `json:"status"`
+	Shipments []shipmentResponse `json:"shipments"`
+}
+
+// getOrderTracking shows where the parcels of an order are, with their tracking events newest first.
+// Orders that have not shipped yet have no shipments.
+func (server *Server) getOrderTracking(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	order, err := server.store.GetOrder(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("order not found")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	shipments, err := server.store.ListShipmentsByOrder(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	items, err := server.store.ListShipmentItemsByOrder(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	events, err := server.store.ListTrackingEventsByOrder(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := orderTrackingResponse{
+		OrderID:   order.ID,
+		Status:    string(order.Status),
+		Shipments: make([]shipmentResponse, len(shipments)),
+	}
+
+	positions := make(map[uuid.UUID]int, len(shipments))
+	for i, shipment := range shipments {
+		response.Shipments[i] = newShipmentResponse(shipment)
+		positions[shipment.ID] = i
+	}
+
+	for _, item := range items {
+		i := positions[item.ShipmentID]
+		response.Shipments[i].Items = append(response.Shipments[i].Items, shipmentItemResponse{
+			OrderItemID: item.OrderItemID,
+			ProductID:   item.ProductID,
+			ProductName: item.ProductName,
+			Quantity:    item.Quantity,
+		})
+	}
+
+	for _, event := range events {
+		i := positions[event.ShipmentID]
+		response.Shipments[i].Events = append(response.Shipments[i].Events, trackingEventResponse{
+			Status:      string(event.Status),
+			Location:    event.Location,
+			Description: event.Description,
+			OccurredAt:  event.OccurredAt.String(),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
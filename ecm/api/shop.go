@@ -50,10 +50,7 @@ func (server *Server) createShop(ctx *gin.Context) {
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	// Only sellers and admins can create shops
-	if authPayload.Role != "seller" && authPayload.Role != "admin" {
-		err := errors.New("only sellers can create shops")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	if !server.requireVerifiedEmail(ctx, authPayload.UserID) {
 		return
 	}
 
@@ -72,7 +69,10 @@ func (server *Server) createShop(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, newShopResponse(shop))
+	rsp := newShopResponse(shop)
+	server.audit(ctx, auditShopCreate, auditTargetShop, shop.ID, nil, rsp)
+
+	ctx.JSON(http.StatusCreated, rsp)
 }
 
 func (server *Server) getShop(ctx *gin.Context) {
@@ -109,11 +109,25 @@ func (server *Server) listShops(ctx *gin.Context) {
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
+	readAny, err := server.can(ctx, permShopRead+":any")
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	readOwn, err := server.can(ctx, permShopRead+":own")
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !readAny && !readOwn {
+		ctx.JSON(http.StatusForbidden, errorResponse(errPermissionDenied))
+		return
+	}
+
 	var shops []db.Shop
-	var err error
 
-	// If user is admin, list all shops, otherwise list only user's shops
-	if authPayload.Role == "admin" {
+	// Users who may read any shop see all of them, everyone else only their own
+	if readAny {
 		arg := db.ListShopsParams{
 			Limit:  req.PageSize,
 			Offset: (req.PageID - 1) * req.PageSize,
@@ -155,21 +169,10 @@ func (server *Server) updateShop(ctx *gin.Context) {
 
 	shop, err := server.store.GetShop(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("shop not found")))
-			return
-		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if shop.OwnerID != authPayload.UserID && authPayload.Role != "admin" {
-		err := errors.New("you don't have permission to update this shop")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
-		return
-	}
-
 	arg := db.UpdateShopParams{
 		ID:   id,
 		Name: req.Name,
@@ -185,7 +188,10 @@ func (server *Server) updateShop(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, newShopResponse(updatedShop))
+	rsp := newShopResponse(updatedShop)
+	server.audit(ctx, auditShopUpdate, auditTargetShop, updatedShop.ID, newShopResponse(shop), rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
 }
 
 func (server *Server) deleteShop(ctx *gin.Context) {
@@ -197,26 +203,17 @@ func (server *Server) deleteShop(ctx *gin.Context) {
 
 	shop, err := server.store.GetShop(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("shop not found")))
-			return
-		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if shop.OwnerID != authPayload.UserID && authPayload.Role != "admin" {
-		err := errors.New("you don't have permission to delete this shop")
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
-		return
-	}
-
 	err = server.store.DeleteShop(ctx, id)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	server.audit(ctx, auditShopDelete, auditTargetShop, shop.ID, newShopResponse(shop), nil)
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "shop deleted successfully"})
 }
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/money"
+	"github.com/qhh/ecm/token"
+)
+
+var errShopOrderNotFound = errors.New("shop order not found")
+
+type shopOrderResponse struct {
+	ID              uuid.UUID           `json:"id"`
+	OrderID         uuid.UUID           `json:"order_id"`
+	ShopID          uuid.UUID           `json:"shop_id"`
+	ShopName        string              `json:"shop_name,omitempty"`
+	Status          string              `json:"status"`
+	Subtotal        money.Amount        `json:"subtotal"`
+	Username        string              `json:"username,omitempty"`
+	ShippingAddress string              `json:"shipping_address,omitempty"`
+	CreatedAt       string              `json:"created_at"`
+	UpdatedAt       string              `json:"updated_at"`
+	Items           []orderItemResponse `json:"items,omitempty"`
+}
+
+func newShopOrderResponse(shopOrder db.ShopOrder) shopOrderResponse {
+	return shopOrderResponse{
+		ID:        shopOrder.ID,
+		OrderID:   shopOrder.OrderID,
+		ShopID:    shopOrder.ShopID,
+		Status:    string(shopOrder.Status),
+		Subtotal:  shopOrder.Subtotal,
+		CreatedAt: shopOrder.CreatedAt.String(),
+		UpdatedAt: shopOrder.UpdatedAt.String(),
+	}
+}
+
+type listShopOrdersRequest struct {
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+	Status   string `form:"status" binding:"omitempty,oneof=pending processing shipped delivered cancelled"`
+}
+
+// listShopOrders lists the parts of orders that a shop has to fulfill, newest first, with the buyer
+// and the address to ship the items to
+func (server *Server) listShopOrders(ctx *gin.Context) {
+	shopID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listShopOrdersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListShopOrdersParams{
+		ShopID: shopID,
+		Status: db.NullOrderStatus{
+			OrderStatus: db.OrderStatus(req.Status),
+			Valid:       req.Status != "",
+		},
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	shopOrders, err := server.store.ListShopOrders(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	shopOrderIDs := make([]uuid.UUID, len(shopOrders))
+	for i, row := range shopOrders {
+		shopOrderIDs[i] = row.ID
+	}
+
+	items, err := server.store.ListShopOrderItemsByShopOrders(ctx, shopOrderIDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]shopOrderResponse, len(shopOrders))
+	positions := make(map[uuid.UUID]int, len(shopOrders))
+	for i, row := range shopOrders {
+		shopOrder := db.ShopOrder{
+			ID:        row.ID,
+			OrderID:   row.OrderID,
+			ShopID:    row.ShopID,
+			Status:    row.Status,
+			Subtotal:  row.Subtotal,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+		}
+		response[i] = newShopOrderResponse(shopOrder)
+		response[i].Username = row.Username
+		response[i].ShippingAddress = row.ShippingAddress
+		response[i].Items = []orderItemResponse{}
+		positions[row.ID] = i
+	}
+
+	for _, item := range items {
+		i := positions[item.ShopOrderID]
+		response[i].Items = append(response[i].Items, newOrderItemResponse(db.GetOrderItemsRow(item)))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+type updateShopOrderStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=pending processing shipped delivered cancelled"`
+	Note   string `json:"note" binding:"max=500"`
+	shipmentDetails
+}
+
+// updateShopOrderStatus moves a shop's part of an order to the next status, following the same
+// transitions as whole orders. The order's own status then follows from the statuses of its parts.
+// Like updateOrderStatus, shipping can come with the carrier and tracking number of the parcel.
+func (server *Server) updateShopOrderStatus(ctx *gin.Context) {
+	shopID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	shopOrderID, err := uuid.Parse(ctx.Param("subOrderId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateShopOrderStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !req.shipmentDetails.isEmpty() && req.Status != string(db.OrderStatusShipped) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errShipmentDetailsNotShipped))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	shopOrder, err := server.store.GetShopOrder(ctx, shopOrderID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errShopOrderNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if shopOrder.ShopID != shopID {
+		ctx.JSON(http.StatusNotFound, errorResponse(errShopOrderNotFound))
+		return
+	}
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	// The order is locked before its parts, like changeOrderStatus does
+	order, err := server.store.GetOrderForUpdateWithTx(ctx, tx, shopOrder.OrderID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	before, err := server.store.GetShopOrderForUpdateWithTx(ctx, tx, shopOrder.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	updated, err := server.changeShopOrderStatus(ctx, tx, order, before, db.OrderStatus(req.Status), authPayload.UserID, req.Note, req.shipmentDetails)
+	if err != nil {
+		var transitionErr *orderStatusTransitionError
+		if errors.As(err, &transitionErr) || errors.Is(err, errOrderNotPaid) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.syncOrderStatus(ctx, tx, order, authPayload.UserID, req.Note)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	switch updated.Status {
+	case db.OrderStatusShipped:
+		server.dispatchShipments(ctx, updated.OrderID)
+	case db.OrderStatusCancelled:
+		server.settlePayment(ctx, updated.OrderID)
+	}
+
+	rsp := newShopOrderResponse(updated)
+	server.audit(ctx, auditShopOrderStatusUpdate, auditTargetShopOrder, updated.ID, newShopOrderResponse(before), rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
+}
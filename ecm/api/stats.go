@@ -0,0 +1,220 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/money"
+)
+
+const (
+	// defaultStatsRange is how many days of revenue are reported when no range is given
+	defaultStatsRange = 30
+
+	// maxStatsRange is the most days a range can cover, so that a request cannot list revenue for
+	// every day of centuries
+	maxStatsRange = 366
+
+	// defaultTopCount is how many products and shops are ranked when no count is given
+	defaultTopCount = 5
+)
+
+type getAdminStatsRequest struct {
+	From     time.Time `form:"from" time_format:"2006-01-02" time_utc:"1"`
+	To       time.Time `form:"to" time_format:"2006-01-02" time_utc:"1"`
+	Interval string    `form:"interval" binding:"omitempty,oneof=day week month"`
+	Top      int32     `form:"top" binding:"omitempty,min=1,max=50"`
+}
+
+type revenuePeriodResponse struct {
+	PeriodStart string       `json:"period_start"`
+	OrderCount  int64        `json:"order_count"`
+	Revenue     money.Amount `json:"revenue"`
+}
+
+type revenueResponse struct {
+	Interval string                  `json:"interval"`
+	From     string                  `json:"from"`
+	To       string                  `json:"to"`
+	Total    money.Amount            `json:"total"`
+	Periods  []revenuePeriodResponse `json:"periods"`
+}
+
+type topProductResponse struct {
+	ID        uuid.UUID    `json:"id"`
+	Name      string       `json:"name"`
+	ShopID    uuid.UUID    `json:"shop_id"`
+	UnitsSold int64        `json:"units_sold"`
+	Revenue   money.Amount `json:"revenue"`
+}
+
+type topShopResponse struct {
+	ID         uuid.UUID    `json:"id"`
+	Name       string       `json:"name"`
+	OrderCount int64        `json:"order_count"`
+	UnitsSold  int64        `json:"units_sold"`
+	Revenue    money.Amount `json:"revenue"`
+}
+
+type adminStatsResponse struct {
+	TotalUsers     int64                `json:"total_users"`
+	UsersByRole    map[string]int64     `json:"users_by_role"`
+	TotalShops     int64                `json:"total_shops"`
+	TotalProducts  int64                `json:"total_products"`
+	TotalOrders    int64                `json:"total_orders"`
+	OrdersByStatus map[string]int64     `json:"orders_by_status"`
+	Revenue        revenueResponse      `json:"revenue"`
+	TopProducts    []topProductResponse `json:"top_products"`
+	TopShops       []topShopResponse    `json:"top_shops"`
+}
+
+// getAdminStats reports store-wide totals, plus revenue and the best selling products and shops over a
+// range of days. The range defaults to the last 30 days, both of its days are included and it covers
+// at most maxStatsRange days. Revenue is what was captured from the payments of each period less what
+// was refunded, and every period of the range is listed, even those without payments. A payment falls
+// in the period it was started in rather than the one it was captured in, so a payment that waited
+// for 3-D Secure or a webhook over midnight counts towards the day it was made. Products and shops are
+// ranked by the shop orders that were not cancelled.
+func (server *Server) getAdminStats(ctx *gin.Context) {
+	var req getAdminStatsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	to := req.To
+	if to.IsZero() {
+		to = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	from := req.From
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -(defaultStatsRange - 1))
+	}
+	if from.After(to) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("from must not be after to")))
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+	if end.Sub(from) > maxStatsRange*24*time.Hour {
+		err := fmt.Errorf("from and to must not cover more than %d days", maxStatsRange)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	interval := req.Interval
+	if interval == "" {
+		interval = "day"
+	}
+	top := req.Top
+	if top == 0 {
+		top = defaultTopCount
+	}
+
+	rsp := adminStatsResponse{
+		UsersByRole:    make(map[string]int64),
+		OrdersByStatus: make(map[string]int64),
+		Revenue: revenueResponse{
+			Interval: interval,
+			From:     from.Format("2006-01-02"),
+			To:       to.Format("2006-01-02"),
+		},
+	}
+
+	usersByRole, err := server.store.CountUsersByRole(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	for _, row := range usersByRole {
+		rsp.UsersByRole[string(row.Role)] = row.Count
+		rsp.TotalUsers += row.Count
+	}
+
+	rsp.TotalShops, err = server.store.CountShops(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp.TotalProducts, err = server.store.CountProducts(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ordersByStatus, err := server.store.CountOrdersByStatus(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	for _, row := range ordersByStatus {
+		rsp.OrdersByStatus[string(row.Status)] = row.Count
+		rsp.TotalOrders += row.Count
+	}
+
+	periods, err := server.store.GetRevenueByPeriod(ctx, db.GetRevenueByPeriodParams{
+		Period:      interval,
+		CreatedFrom: from,
+		CreatedTo:   end,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	rsp.Revenue.Periods = make([]revenuePeriodResponse, len(periods))
+	for i, period := range periods {
+		rsp.Revenue.Periods[i] = revenuePeriodResponse{
+			PeriodStart: period.PeriodStart.Format("2006-01-02"),
+			OrderCount:  period.OrderCount,
+			Revenue:     period.Revenue,
+		}
+		rsp.Revenue.Total += period.Revenue
+	}
+
+	products, err := server.store.ListTopProducts(ctx, db.ListTopProductsParams{
+		CreatedFrom: from,
+		CreatedTo:   end,
+		Limit:       top,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	rsp.TopProducts = make([]topProductResponse, len(products))
+	for i, product := range products {
+		rsp.TopProducts[i] = topProductResponse{
+			ID:        product.ID,
+			Name:      product.Name,
+			ShopID:    product.ShopID,
+			UnitsSold: product.UnitsSold,
+			Revenue:   product.Revenue,
+		}
+	}
+
+	shops, err := server.store.ListTopShops(ctx, db.ListTopShopsParams{
+		CreatedFrom: from,
+		CreatedTo:   end,
+		Limit:       top,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	rsp.TopShops = make([]topShopResponse, len(shops))
+	for i, shop := range shops {
+		rsp.TopShops[i] = topShopResponse{
+			ID:         shop.ID,
+			Name:       shop.Name,
+			OrderCount: shop.OrderCount,
+			UnitsSold:  shop.UnitsSold,
+			Revenue:    shop.Revenue,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
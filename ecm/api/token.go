@@ -0,0 +1,129 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qhh/ecm/token"
+	"github.com/qhh/ecm/util"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken, token.TokenTypeRefresh)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	revoked, err := server.revocations.IsRevoked(ctx, refreshPayload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if revoked {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("token has been revoked")))
+		return
+	}
+
+	session, err := server.store.GetSession(ctx, refreshPayload.SessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("session not found")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if session.IsBlocked {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("session is blocked")))
+		return
+	}
+
+	if session.UserID != refreshPayload.UserID {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("incorrect session user")))
+		return
+	}
+
+	if session.RefreshTokenHash != util.HashToken(req.RefreshToken) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("mismatched session token")))
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("session has expired")))
+		return
+	}
+
+	// The role and username may have changed since the refresh token was issued, e.g. when a seller
+	// application is approved, so the new access token is built from the current user
+	user, err := server.store.GetUser(ctx, session.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("user not found")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.SuspendedAt.Valid {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errAccountSuspended))
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(
+		token.TokenTypeAccess,
+		session.ID,
+		user.ID,
+		user.Username,
+		string(user.Role),
+		server.config.AccessTokenDuration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.TouchSession(ctx, session.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := renewAccessTokenResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// jwksMaxAge is how long verifiers may cache the key set; keys are published before they start signing
+const jwksMaxAge = 5 * time.Minute
+
+// getJWKS serves the public keys other services use to verify our tokens
+func (server *Server) getJWKS(publisher token.KeyPublisher) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+		ctx.JSON(http.StatusOK, publisher.JWKS())
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qhh/ecm/token"
+)
+
+func TestGetJWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Now().UTC()
+
+	current := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	retired := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, ed25519.SeedSize))
+
+	dir := t.TempDir()
+	for name, key := range map[string]ed25519.PrivateKey{"current.pem": current, "retired.pem": retired} {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, name), block, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest := fmt.Sprintf(`{"keys": [
+		{"kid": "retired", "file": "retired.pem", "not_before": %q, "not_after": %q},
+		{"kid": "current", "file": "current.pem", "not_before": %q}
+	]}`, now.Add(-2*time.Hour).Format(time.RFC3339), now.Add(-time.Hour).Format(time.RFC3339), now.Add(-time.Hour).Format(time.RFC3339))
+	if err := os.WriteFile(filepath.Join(dir, "keys.json"), []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	maker, err := token.NewAsymmetricJWTMaker(dir)
+	if err != nil {
+		t.Fatalf("cannot create maker: %v", err)
+	}
+	publisher, ok := maker.(token.KeyPublisher)
+	if !ok {
+		t.Fatalf("%T does not publish its keys", maker)
+	}
+
+	server := &Server{tokenMaker: maker}
+	router := gin.New()
+	router.GET("/.well-known/jwks.json", server.getJWKS(publisher))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", recorder.Code, http.StatusOK)
+	}
+	if got, want := recorder.Header().Get("Cache-Control"), "public, max-age=300"; got != want {
+		t.Errorf("Cache-Control = %q; want %q", got, want)
+	}
+
+	var set token.JSONWebKeySet
+	if err := json.Unmarshal(recorder.Body.Bytes(), &set); err != nil {
+		t.Fatalf("cannot parse JWKS: %v", err)
+	}
+	want := token.JSONWebKey{
+		KeyType:   "OKP",
+		KeyID:     "current",
+		Use:       "sig",
+		Algorithm: "EdDSA",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(current.Public().(ed25519.PublicKey)),
+	}
+	if len(set.Keys) != 1 || set.Keys[0] != want {
+		t.Fatalf("JWKS = %+v; want only %+v", set.Keys, want)
+	}
+
+	// A token from the maker verifies with the published key alone
+	accessToken, _, err := maker.CreateToken(token.TokenTypeAccess, uuid.New(), uuid.New(), "alice", "buyer", time.Minute)
+	if err != nil {
+		t.Fatalf("CreateToken returned error: %v", err)
+	}
+	dot := strings.LastIndex(accessToken, ".")
+	signature, err := base64.RawURLEncoding.DecodeString(accessToken[dot+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := base64.RawURLEncoding.DecodeString(set.Keys[0].X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(publicKey, []byte(accessToken[:dot]), signature) {
+		t.Error("published key does not verify the token's signature")
+	}
+}
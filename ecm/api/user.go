@@ -2,7 +2,14 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -18,18 +25,22 @@ type createUserRequest struct {
 }
 
 type userResponse struct {
-	ID       uuid.UUID `json:"id"`
-	Username string    `json:"username"`
-	Email    string    `json:"email"`
-	Role     string    `json:"role"`
+	ID            uuid.UUID `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Role          string    `json:"role"`
+	Suspended     bool      `json:"suspended"`
 }
 
 func newUserResponse(user db.User) userResponse {
 	return userResponse{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     string(user.Role),
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		Role:          string(user.Role),
+		Suspended:     user.SuspendedAt.Valid,
 	}
 }
 
@@ -55,21 +66,39 @@ func (server *Server) createUser(ctx *gin.Context) {
 
 	user, err := server.store.CreateUser(ctx, arg)
 	if err != nil {
+		if isUniqueViolation(err, "") {
+			ctx.JSON(http.StatusConflict, errorResponse(errors.New("username or email is already in use")))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	// The account exists either way; the user can ask for another email if this one fails
+	err = server.sendVerificationEmail(ctx, user, user.Email)
+	if err != nil {
+		log.Println("Warning: failed to send verification email:", err)
+	}
+
 	ctx.JSON(http.StatusCreated, newUserResponse(user))
 }
 
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// loginUserRequest accepts either the username or the email address in Username
 type loginUserRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
+	RecoveryCodes         []string     `json:"recovery_codes,omitempty"`
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
@@ -79,38 +108,182 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
-	user, err := server.store.GetUserByUsername(ctx, req.Username)
+	user, err := server.getUserByLogin(ctx, req.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	found := err == nil
+
+	// Failures are counted per account, whichever identifier was used
+	usernameKey := usernameThrottleKey(req.Username)
+	if found {
+		usernameKey = usernameThrottleKey(user.Username)
+	}
+	ipKey := ipThrottleKey(ctx.ClientIP())
+
+	retryAfter, err := server.beginLoginAttempt(ctx, usernameKey, ipKey)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
-			return
-		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	if retryAfter > 0 {
+		tooManyLoginAttempts(ctx, retryAfter)
+		return
+	}
 
-	err = util.CheckPassword(req.Password, user.PasswordHash)
+	// Unknown users and wrong passwords get the same answer after the same amount of work. The
+	// attempt was already counted as a failure.
+	if found {
+		err = util.CheckPassword(req.Password, user.PasswordHash)
+	} else {
+		err = util.CheckDummyPassword(req.Password)
+	}
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+
+	err = server.loginSucceeded(ctx, usernameKey, ipKey)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	accessToken, err := server.tokenMaker.CreateToken(
+	// Only tell someone who knows the password that the account is suspended
+	if user.SuspendedAt.Valid {
+		ctx.JSON(http.StatusForbidden, errorResponse(errAccountSuspended))
+		return
+	}
+
+	challenge, required, err := server.mfaChallenge(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if required {
+		ctx.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	rsp, err := server.createSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// getUserByLogin looks a user up by email if the identifier looks like one, and by username otherwise.
+// Usernames are alphanumeric, so they never contain an @.
+func (server *Server) getUserByLogin(ctx *gin.Context, identifier string) (db.User, error) {
+	if strings.Contains(identifier, "@") {
+		return server.store.GetUserByEmail(ctx, identifier)
+	}
+	return server.store.GetUserByUsername(ctx, identifier)
+}
+
+// beginLoginAttempt counts an attempt against the client IP and then the username, before the
+// password is checked, and returns how long to wait if either is locked out. An attempt refused for
+// the username is taken back from the client IP. Until loginSucceeded is called, the attempt counts
+// as a failure.
+func (server *Server) beginLoginAttempt(ctx *gin.Context, usernameKey string, ipKey string) (time.Duration, error) {
+	retryAfter, err := server.loginThrottle.Attempt(ctx, ipKey, ipThrottlePolicy)
+	if err != nil || retryAfter > 0 {
+		return retryAfter, err
+	}
+
+	retryAfter, err = server.loginThrottle.Attempt(ctx, usernameKey, usernameThrottlePolicy)
+	if err != nil || retryAfter > 0 {
+		if forgiveErr := server.loginThrottle.Forgive(ctx, ipKey, ipThrottlePolicy); forgiveErr != nil {
+			log.Println("Warning: failed to take back login attempt:", forgiveErr)
+		}
+	}
+	return retryAfter, err
+}
+
+// loginSucceeded takes back an attempt counted by beginLoginAttempt once the password was right. The
+// username's failures are forgotten, and the attempt no longer counts against the client IP.
+func (server *Server) loginSucceeded(ctx *gin.Context, usernameKey string, ipKey string) error {
+	err := server.loginThrottle.Reset(ctx, usernameKey)
+	if err != nil {
+		return err
+	}
+	return server.loginThrottle.Forgive(ctx, ipKey, ipThrottlePolicy)
+}
+
+// recordLoginFailure counts a failed attempt that was not started with beginLoginAttempt, such as a
+// wrong two-factor code, against the username and the client IP. The caller still answers with
+// invalid credentials if this fails, so errors are only logged.
+func (server *Server) recordLoginFailure(ctx *gin.Context, usernameKey string, ipKey string) {
+	if _, err := server.loginThrottle.Attempt(ctx, usernameKey, usernameThrottlePolicy); err != nil {
+		log.Println("Warning: failed to record login failure:", err)
+	}
+	if _, err := server.loginThrottle.Attempt(ctx, ipKey, ipThrottlePolicy); err != nil {
+		log.Println("Warning: failed to record login failure:", err)
+	}
+}
+
+// tooManyLoginAttempts rejects a login while its username or client is locked out
+func tooManyLoginAttempts(ctx *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(seconds))
+	err := fmt.Errorf("too many failed login attempts, try again in %d seconds", seconds)
+	ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+}
+
+// createSession issues a refresh token bound to a new session for the client, plus an access token for it
+func (server *Server) createSession(ctx *gin.Context, user db.User) (loginUserResponse, error) {
+	sessionID, err := uuid.NewRandom()
+	if err != nil {
+		return loginUserResponse{}, err
+	}
+
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(
+		token.TokenTypeRefresh,
+		sessionID,
+		user.ID,
+		user.Username,
+		string(user.Role),
+		server.config.RefreshTokenDuration,
+	)
+	if err != nil {
+		return loginUserResponse{}, err
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(
+		token.TokenTypeAccess,
+		sessionID,
 		user.ID,
 		user.Username,
 		string(user.Role),
 		server.config.AccessTokenDuration,
 	)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		return loginUserResponse{}, err
 	}
 
-	rsp := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
+	_, err = server.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:               sessionID,
+		UserID:           user.ID,
+		RefreshTokenHash: util.HashToken(refreshToken),
+		UserAgent:        clientUserAgent(ctx),
+		ClientIp:         ctx.ClientIP(),
+		ExpiresAt:        refreshPayload.ExpiredAt.UTC(),
+	})
+	if err != nil {
+		return loginUserResponse{}, err
 	}
-	ctx.JSON(http.StatusOK, rsp)
+
+	return loginUserResponse{
+		SessionID:             sessionID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
+	}, nil
 }
 
 func (server *Server) getCurrentUser(ctx *gin.Context) {
@@ -125,12 +298,90 @@ func (server *Server) getCurrentUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, newUserResponse(user))
 }
 
-type updateUserRoleRequest struct {
-	Role string `json:"role" binding:"required,oneof=buyer seller"`
+type updateCurrentUserRequest struct {
+	Username *string `json:"username" binding:"omitempty,alphanum"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+}
+
+type updateCurrentUserResponse struct {
+	User         userResponse `json:"user"`
+	PendingEmail string       `json:"pending_email,omitempty"`
+}
+
+// updateCurrentUser changes the user's username right away. A new email only replaces the current
+// one once it has been verified through the link sent to it.
+func (server *Server) updateCurrentUser(ctx *gin.Context) {
+	var req updateCurrentUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.Username == nil && req.Email == nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("nothing to update")))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if req.Email != nil && *req.Email != user.Email {
+		_, err := server.store.GetUserByEmail(ctx, *req.Email)
+		if err == nil {
+			ctx.JSON(http.StatusConflict, errorResponse(errors.New("email is already in use")))
+			return
+		}
+		if err != sql.ErrNoRows {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	if req.Username != nil && *req.Username != user.Username {
+		arg := db.UpdateUserUsernameParams{
+			ID:       user.ID,
+			Username: *req.Username,
+		}
+
+		user, err = server.store.UpdateUserUsername(ctx, arg)
+		if err != nil {
+			if isUniqueViolation(err, "users_username_key") {
+				ctx.JSON(http.StatusConflict, errorResponse(errors.New("username is already taken")))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	rsp := updateCurrentUserResponse{User: newUserResponse(user)}
+
+	if req.Email != nil && *req.Email != user.Email {
+		err = server.sendVerificationEmail(ctx, user, *req.Email)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		rsp.PendingEmail = *req.Email
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
 }
 
-func (server *Server) updateUserRole(ctx *gin.Context) {
-	var req updateUserRoleRequest
+// changePassword replaces the user's password and signs out every other session. The current client
+// gets a fresh session in the response, since its tokens are revoked along with the others.
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
@@ -138,16 +389,85 @@ func (server *Server) updateUserRole(ctx *gin.Context) {
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	arg := db.UpdateUserRoleParams{
-		ID:   authPayload.UserID,
-		Role: db.UserRole(req.Role),
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	user, err := server.store.UpdateUserRole(ctx, arg)
+	// A stolen access token must not allow guessing the password any faster than the login does
+	usernameKey := usernameThrottleKey(user.Username)
+	ipKey := ipThrottleKey(ctx.ClientIP())
+
+	retryAfter, err := server.beginLoginAttempt(ctx, usernameKey, ipKey)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	if retryAfter > 0 {
+		tooManyLoginAttempts(ctx, retryAfter)
+		return
+	}
 
-	ctx.JSON(http.StatusOK, newUserResponse(user))
+	err = util.CheckPassword(req.CurrentPassword, user.PasswordHash)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("current password is incorrect")))
+		return
+	}
+
+	err = server.loginSucceeded(ctx, usernameKey, ipKey)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	arg := db.UpdateUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: hashedPassword,
+	}
+
+	user, err = server.store.UpdateUserPasswordWithTx(ctx, tx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.InvalidatePasswordResetTokensWithTx(ctx, tx, user.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.revokeAllUserSessions(ctx, user.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp, err := server.createSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
 }
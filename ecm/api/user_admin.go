@@ -0,0 +1,347 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+)
+
+var (
+	errUserNotFound     = errors.New("user not found")
+	errAccountSuspended = errors.New("account is suspended")
+	errLastAdmin        = errors.New("the last active admin cannot be demoted, suspended or deleted")
+)
+
+// likeEscaper escapes the wildcards of a LIKE pattern so a search matches them literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type listUsersRequest struct {
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+	Search   string `form:"search" binding:"max=100"`
+	Role     string `form:"role" binding:"omitempty,oneof=admin seller buyer"`
+}
+
+// listUsers lists users, optionally matching a part of their username or email and a role
+func (server *Server) listUsers(ctx *gin.Context) {
+	var req listUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListUsersParams{
+		Search: sql.NullString{
+			String: likeEscaper.Replace(req.Search),
+			Valid:  req.Search != "",
+		},
+		Role: db.NullUserRole{
+			UserRole: db.UserRole(req.Role),
+			Valid:    req.Role != "",
+		},
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	users, err := server.store.ListUsers(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]userResponse, len(users))
+	for i, user := range users {
+		response[i] = newUserResponse(user)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (server *Server) getUser(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errUserNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin seller buyer"`
+}
+
+// updateUserRole changes a user's role. The access tokens issued to them so far are revoked, so that
+// a demoted user cannot keep using the old role until they expire. The new role is in the next access
+// token their session is renewed with.
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	before, err := server.store.GetUser(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errUserNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	if db.UserRole(req.Role) != db.UserRoleAdmin && !server.keepAnAdmin(ctx, tx, id) {
+		return
+	}
+
+	arg := db.UpdateUserRoleParams{
+		ID:   id,
+		Role: db.UserRole(req.Role),
+	}
+
+	user, err := server.store.UpdateUserRoleWithTx(ctx, tx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errUserNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.Role != before.Role {
+		err = server.revocations.RevokeUserWithTx(ctx, tx, user.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocations.ForgetUser(user.ID)
+
+	rsp := newUserResponse(user)
+	server.audit(ctx, auditUserRoleUpdate, auditTargetUser, user.ID, newUserResponse(before), rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// deleteUser permanently deletes a user together with their shops, orders and sessions
+func (server *Server) deleteUser(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errUserNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	if !server.keepAnAdmin(ctx, tx, id) {
+		return
+	}
+
+	err = server.store.DeleteUserWithTx(ctx, tx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocations.ForgetUser(user.ID)
+
+	server.audit(ctx, auditUserDelete, auditTargetUser, user.ID, newUserResponse(user), nil)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
+}
+
+// suspendUser disables an account without deleting it. Suspended users cannot log in, and their
+// sessions are blocked and tokens revoked in the same transaction. authMiddleware also turns away the
+// tokens of suspended users on its own.
+func (server *Server) suspendUser(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	before, err := server.store.GetUser(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errUserNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	if !server.keepAnAdmin(ctx, tx, id) {
+		return
+	}
+
+	user, err := server.store.SuspendUserWithTx(ctx, tx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errUserNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.revokeAllUserSessionsWithTx(ctx, tx, user.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocations.ForgetUser(user.ID)
+
+	rsp := newUserResponse(user)
+	server.audit(ctx, auditUserSuspend, auditTargetUser, user.ID, newUserResponse(before), rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// unsuspendUser lets a suspended user log in again
+func (server *Server) unsuspendUser(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	before, err := server.store.GetUser(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errUserNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.UnsuspendUser(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errUserNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.revocations.ForgetUser(user.ID)
+
+	rsp := newUserResponse(user)
+	server.audit(ctx, auditUserUnsuspend, auditTargetUser, user.ID, newUserResponse(before), rsp)
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// keepAnAdmin refuses to go on if the user is the only active admin left. The active admins stay
+// locked until the transaction ends, so two admins cannot demote each other at the same time. It
+// returns false if a response has already been written.
+func (server *Server) keepAnAdmin(ctx *gin.Context, tx *sql.Tx, userID uuid.UUID) bool {
+	adminIDs, err := server.store.ListActiveAdminIDsForUpdateWithTx(ctx, tx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if len(adminIDs) == 1 && adminIDs[0] == userID {
+		ctx.JSON(http.StatusConflict, errorResponse(errLastAdmin))
+		return false
+	}
+	return true
+}
+
+// unlockUser clears the failed login attempts of a locked out account
+func (server *Server) unlockUser(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errUserNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.loginThrottle.Reset(ctx, usernameThrottleKey(user.Username))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.audit(ctx, auditUserUnlock, auditTargetUser, user.ID, nil, nil)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "user unlocked"})
+}
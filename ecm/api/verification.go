@@ -0,0 +1,153 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/mail"
+	"github.com/qhh/ecm/token"
+	"github.com/qhh/ecm/util"
+)
+
+// verificationTokenSize is the number of random bytes in an email verification token
+const verificationTokenSize = 32
+
+// sendVerificationEmail emails a link that verifies the given address for the user
+func (server *Server) sendVerificationEmail(ctx *gin.Context, user db.User, email string) error {
+	verificationToken, err := util.RandomToken(verificationTokenSize)
+	if err != nil {
+		return err
+	}
+
+	arg := db.CreateEmailVerificationTokenParams{
+		UserID:    user.ID,
+		Email:     email,
+		TokenHash: util.HashToken(verificationToken),
+		ExpiresAt: time.Now().Add(server.config.EmailVerificationDuration).UTC(),
+	}
+
+	_, err = server.store.CreateEmailVerificationToken(ctx, arg)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/users/verify?token=%s", server.config.APIBaseURL, url.QueryEscape(verificationToken))
+	msg := mail.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm this email address by opening the link below. It expires in %s.\n\n%s\n",
+			user.Username, server.config.EmailVerificationDuration, link,
+		),
+	}
+	return server.mailer.Send(ctx, msg)
+}
+
+type verifyEmailRequest struct {
+	Token string `form:"token" binding:"required"`
+}
+
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	tx, err := server.store.BeginTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer tx.Rollback()
+
+	verificationToken, err := server.store.ConsumeEmailVerificationTokenWithTx(ctx, tx, util.HashToken(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("verification token is invalid or has expired")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.MarkUserEmailVerifiedParams{
+		ID:    verificationToken.UserID,
+		Email: verificationToken.Email,
+	}
+
+	user, err := server.store.MarkUserEmailVerifiedWithTx(ctx, tx, arg)
+	if err != nil {
+		if isUniqueViolation(err, "users_email_key") {
+			ctx.JSON(http.StatusConflict, errorResponse(errors.New("email is already in use")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.InvalidateEmailVerificationTokensWithTx(ctx, tx, user.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+func (server *Server) resendVerificationEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("email is already verified")))
+		return
+	}
+
+	err = server.sendVerificationEmail(ctx, user, user.Email)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+// requireVerifiedEmail rejects the request when email verification is enforced and the user has
+// not verified their email yet. It returns false if a response has already been written.
+func (server *Server) requireVerifiedEmail(ctx *gin.Context, userID uuid.UUID) bool {
+	if !server.config.RequireEmailVerification {
+		return true
+	}
+
+	user, err := server.store.GetUser(ctx, userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if !user.EmailVerifiedAt.Valid {
+		err := errors.New("please verify your email address first")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return false
+	}
+	return true
+}
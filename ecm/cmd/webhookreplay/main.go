@@ -0,0 +1,162 @@
+// Command webhookreplay sends payment webhook events to a running server, signed with
+// PAYMENT_WEBHOOK_SECRET, to test how the server processes them. It replays the events stored in the
+// database by default, or the raw payloads in the files given as arguments ("-" reads standard input).
+//
+//	go run ./cmd/webhookreplay -limit 10
+//	go run ./cmd/webhookreplay -event evt_1
+//	go run ./cmd/webhookreplay captured.json
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	db "github.com/qhh/ecm/db/sqlc"
+	"github.com/qhh/ecm/payment"
+	"github.com/qhh/ecm/util"
+)
+
+// capturedEvent is a webhook payload to send again, along with where it came from
+type capturedEvent struct {
+	source  string
+	payload []byte
+}
+
+func main() {
+	// Load environment variables
+	err := godotenv.Load()
+	if err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	config, err := util.LoadConfig()
+	if err != nil {
+		log.Fatal("Cannot load config:", err)
+	}
+
+	serverURL := flag.String("url", config.APIBaseURL, "base URL of the server")
+	provider := flag.String("provider", config.PaymentProvider, "payment provider the events come from")
+	eventID := flag.String("event", "", "replay only the stored event with this provider event ID")
+	limit := flag.Int("limit", 50, "maximum number of stored events to replay, oldest first")
+	flag.Parse()
+
+	if config.PaymentWebhookSecret == "" {
+		log.Fatal("PAYMENT_WEBHOOK_SECRET must be set to sign the events")
+	}
+
+	var events []capturedEvent
+	if flag.NArg() > 0 {
+		events, err = readFiles(flag.Args())
+	} else {
+		events, err = readStoredEvents(config, *provider, *eventID, int32(*limit))
+	}
+	if err != nil {
+		log.Fatal("Cannot read events:", err)
+	}
+	if len(events) == 0 {
+		log.Println("No events to replay")
+		return
+	}
+
+	url := strings.TrimSuffix(*serverURL, "/") + "/webhooks/payments/" + *provider
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	failed := 0
+	for _, event := range events {
+		status, body, err := send(client, url, event.payload, config.PaymentWebhookSecret)
+		if err != nil {
+			log.Printf("%s: %v", event.source, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: %d %s\n", event.source, status, strings.TrimSpace(body))
+		if status < 200 || status >= 300 {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		log.Fatalf("%d of %d events were not accepted", failed, len(events))
+	}
+}
+
+// readFiles reads one raw payload from each file
+func readFiles(paths []string) ([]capturedEvent, error) {
+	events := make([]capturedEvent, 0, len(paths))
+	for _, path := range paths {
+		var payload []byte
+		var err error
+		if path == "-" {
+			payload, err = io.ReadAll(os.Stdin)
+		} else {
+			payload, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, capturedEvent{source: path, payload: bytes.TrimSpace(payload)})
+	}
+	return events, nil
+}
+
+// readStoredEvents reads the payloads of the events that the server stored for a provider
+func readStoredEvents(config util.Config, provider string, eventID string, limit int32) ([]capturedEvent, error) {
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	arg := db.ListPaymentWebhookEventsParams{
+		Provider: provider,
+		EventID:  sql.NullString{String: eventID, Valid: eventID != ""},
+		Limit:    limit,
+	}
+
+	stored, err := db.New(conn).ListPaymentWebhookEvents(context.Background(), arg)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]capturedEvent, len(stored))
+	for i, event := range stored {
+		events[i] = capturedEvent{
+			source:  fmt.Sprintf("%s (%s)", event.EventID, event.EventType),
+			payload: []byte(event.Payload),
+		}
+	}
+	return events, nil
+}
+
+// send posts a payload to url with a fresh signature and returns the response
+func send(client *http.Client, url string, payload []byte, secret string) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(payment.SignatureHeader, payment.Sign(payload, secret, time.Now()))
+
+	rsp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer rsp.Body.Close()
+
+	body, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return 0, "", err
+	}
+	return rsp.StatusCode, string(body), nil
+}
@@ -0,0 +1,8 @@
+CREATE TABLE login_throttles (
+  throttle_key VARCHAR(320) PRIMARY KEY,
+  failed_attempts INT NOT NULL DEFAULT 0,
+  locked_until TIMESTAMP,
+  last_failed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_login_throttles_last_failed_at ON login_throttles(last_failed_at);
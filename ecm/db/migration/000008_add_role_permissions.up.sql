@@ -0,0 +1,26 @@
+CREATE TABLE role_permissions (
+  role user_role NOT NULL,
+  permission VARCHAR(100) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (role, permission)
+);
+
+-- Permissions ending in :own apply to resources the user owns, :any to all of them
+INSERT INTO role_permissions (role, permission) VALUES
+  ('buyer', 'order:create'),
+  ('buyer', 'order:read:own'),
+  ('seller', 'order:create'),
+  ('seller', 'order:read:own'),
+  ('seller', 'shop:create'),
+  ('seller', 'shop:read:own'),
+  ('seller', 'shop:write:own'),
+  ('seller', 'product:write:own'),
+  ('admin', 'order:create'),
+  ('admin', 'order:read:any'),
+  ('admin', 'order:status:any'),
+  ('admin', 'shop:create'),
+  ('admin', 'shop:read:any'),
+  ('admin', 'shop:write:any'),
+  ('admin', 'product:write:any'),
+  ('admin', 'category:manage'),
+  ('admin', 'user:manage');
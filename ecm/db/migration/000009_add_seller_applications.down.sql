@@ -0,0 +1,5 @@
+DELETE FROM role_permissions WHERE permission IN ('seller_application:create', 'seller_application:review');
+
+DROP TABLE IF EXISTS seller_applications;
+
+DROP TYPE IF EXISTS seller_application_status;
@@ -0,0 +1,26 @@
+CREATE TYPE seller_application_status AS ENUM ('pending', 'approved', 'rejected');
+
+CREATE TABLE seller_applications (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  business_name VARCHAR(255) NOT NULL,
+  business_email VARCHAR(255) NOT NULL,
+  business_phone VARCHAR(50) NOT NULL,
+  business_address TEXT NOT NULL,
+  tax_id VARCHAR(100) NOT NULL,
+  description TEXT,
+  status seller_application_status NOT NULL DEFAULT 'pending',
+  reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  review_note TEXT,
+  reviewed_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- A user can have only one application waiting for review at a time
+CREATE UNIQUE INDEX seller_applications_pending_user_id_key ON seller_applications(user_id) WHERE status = 'pending';
+CREATE INDEX idx_seller_applications_status ON seller_applications(status, created_at);
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('buyer', 'seller_application:create'),
+  ('admin', 'seller_application:review');
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+
+CREATE INDEX idx_users_role ON users(role);
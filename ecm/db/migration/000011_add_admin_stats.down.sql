@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'stats:read';
+
+DROP INDEX IF EXISTS idx_orders_created_at;
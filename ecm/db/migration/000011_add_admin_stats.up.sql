@@ -0,0 +1,4 @@
+CREATE INDEX idx_orders_created_at ON orders(created_at);
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'stats:read');
@@ -0,0 +1,21 @@
+CREATE TABLE audit_events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  -- Not a foreign key, so the log still shows who acted after their account is deleted
+  actor_id UUID NOT NULL,
+  actor_username VARCHAR(255) NOT NULL,
+  action VARCHAR(100) NOT NULL,
+  target_type VARCHAR(50) NOT NULL,
+  target_id UUID NOT NULL,
+  before JSONB NOT NULL DEFAULT '{}',
+  after JSONB NOT NULL DEFAULT '{}',
+  client_ip VARCHAR(64) NOT NULL,
+  request_id VARCHAR(64) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX idx_audit_events_actor_id ON audit_events(actor_id, created_at);
+CREATE INDEX idx_audit_events_target ON audit_events(target_type, target_id, created_at);
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'audit:read');
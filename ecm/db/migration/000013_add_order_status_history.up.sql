@@ -0,0 +1,11 @@
+CREATE TABLE order_status_history (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  -- NULL when the order was placed
+  from_status order_status,
+  to_status order_status NOT NULL,
+  changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_status_history_order_id ON order_status_history(order_id, created_at);
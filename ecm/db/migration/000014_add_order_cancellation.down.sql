@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'order:cancel:own';
+
+ALTER TABLE order_status_history DROP COLUMN IF EXISTS note;
@@ -0,0 +1,6 @@
+ALTER TABLE order_status_history ADD COLUMN note TEXT;
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('buyer', 'order:cancel:own'),
+  ('seller', 'order:cancel:own'),
+  ('admin', 'order:cancel:own');
@@ -0,0 +1,14 @@
+-- Concurrent orders could oversell a product before the check existed. Such stock is not set to 0
+-- here, which would hide how many units were sold without stock, so the migration stops until it
+-- has been corrected by hand.
+DO $$
+DECLARE
+  oversold INTEGER;
+BEGIN
+  SELECT COUNT(*) INTO oversold FROM products WHERE stock_quantity < 0;
+  IF oversold > 0 THEN
+    RAISE EXCEPTION '% products have a negative stock_quantity, correct their stock before migrating', oversold;
+  END IF;
+END $$;
+
+ALTER TABLE products ADD CONSTRAINT products_stock_quantity_check CHECK (stock_quantity >= 0);
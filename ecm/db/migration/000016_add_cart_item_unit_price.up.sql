@@ -0,0 +1,8 @@
+-- The price the buyer saw when adding the item, so checkout can tell if it has changed since
+ALTER TABLE cart_items ADD COLUMN unit_price DECIMAL(10, 2);
+
+UPDATE cart_items c SET unit_price = p.price
+FROM products p
+WHERE p.id = c.product_id;
+
+ALTER TABLE cart_items ALTER COLUMN unit_price SET NOT NULL;
@@ -0,0 +1,6 @@
+DELETE FROM role_permissions WHERE permission IN ('shop_order:status:own', 'shop_order:status:any');
+
+ALTER TABLE order_status_history DROP COLUMN IF EXISTS shop_order_id;
+ALTER TABLE order_items DROP COLUMN IF EXISTS shop_order_id;
+
+DROP TABLE IF EXISTS shop_orders;
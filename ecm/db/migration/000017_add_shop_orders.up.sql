@@ -0,0 +1,39 @@
+-- Orders are split into one shop order per shop, which the shop's seller fulfills on their own. The
+-- status of the order follows from the statuses of its shop orders.
+CREATE TABLE shop_orders (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  shop_id UUID NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+  status order_status NOT NULL DEFAULT 'pending',
+  subtotal DECIMAL(10, 2) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (order_id, shop_id)
+);
+
+CREATE INDEX idx_shop_orders_shop_id ON shop_orders(shop_id, created_at);
+
+-- Split the orders placed so far, giving each part the status of its order
+INSERT INTO shop_orders (order_id, shop_id, status, subtotal, created_at, updated_at)
+SELECT o.id, p.shop_id, o.status, SUM(oi.price * oi.quantity), o.created_at, o.updated_at
+FROM orders o
+JOIN order_items oi ON oi.order_id = o.id
+JOIN products p ON p.id = oi.product_id
+GROUP BY o.id, p.shop_id;
+
+ALTER TABLE order_items ADD COLUMN shop_order_id UUID REFERENCES shop_orders(id) ON DELETE CASCADE;
+
+UPDATE order_items oi SET shop_order_id = so.id
+FROM products p, shop_orders so
+WHERE p.id = oi.product_id AND so.order_id = oi.order_id AND so.shop_id = p.shop_id;
+
+ALTER TABLE order_items ALTER COLUMN shop_order_id SET NOT NULL;
+
+CREATE INDEX idx_order_items_shop_order_id ON order_items(shop_order_id);
+
+-- Changes to a single shop order are kept in the history of its order
+ALTER TABLE order_status_history ADD COLUMN shop_order_id UUID REFERENCES shop_orders(id) ON DELETE CASCADE;
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('seller', 'shop_order:status:own'),
+  ('admin', 'shop_order:status:any');
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS tracking_events;
+DROP TABLE IF EXISTS shipment_items;
+DROP TABLE IF EXISTS shipments;
+DROP TYPE IF EXISTS shipment_status;
@@ -0,0 +1,37 @@
+CREATE TYPE shipment_status AS ENUM ('label_created', 'in_transit', 'out_for_delivery', 'delivered', 'exception');
+
+-- A shop order gets a shipment when it is shipped, holding the parcel's carrier and tracking number
+CREATE TABLE shipments (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  shop_order_id UUID NOT NULL REFERENCES shop_orders(id) ON DELETE CASCADE,
+  carrier VARCHAR(50) NOT NULL,
+  tracking_number VARCHAR(100) NOT NULL,
+  status shipment_status NOT NULL DEFAULT 'label_created',
+  estimated_delivery TIMESTAMP,
+  delivered_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_shipments_order_id ON shipments(order_id);
+CREATE INDEX idx_shipments_tracking_number ON shipments(carrier, tracking_number);
+
+-- The order items packed in each shipment
+CREATE TABLE shipment_items (
+  shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+  order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+  PRIMARY KEY (shipment_id, order_item_id)
+);
+
+-- Tracking events reported by the carrier. A carrier may report the same event more than once.
+CREATE TABLE tracking_events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+  status shipment_status NOT NULL,
+  location VARCHAR(255) NOT NULL DEFAULT '',
+  description TEXT NOT NULL DEFAULT '',
+  occurred_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (shipment_id, status, occurred_at)
+);
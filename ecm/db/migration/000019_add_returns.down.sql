@@ -0,0 +1,6 @@
+DELETE FROM role_permissions WHERE permission IN ('return:create:own', 'return:review:own', 'return:review:any');
+
+DROP TABLE IF EXISTS refunds;
+DROP TYPE IF EXISTS refund_status;
+DROP TABLE IF EXISTS return_requests;
+DROP TYPE IF EXISTS return_status;
@@ -0,0 +1,46 @@
+CREATE TYPE return_status AS ENUM ('requested', 'approved', 'rejected', 'received', 'inspected');
+
+-- Buyers ask to return some or all of an order item once its shop order is delivered. The shop's
+-- seller approves or rejects the request, then receives and inspects the returned items.
+CREATE TABLE return_requests (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+  shop_id UUID NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  quantity INT NOT NULL CHECK (quantity > 0),
+  reason TEXT NOT NULL,
+  status return_status NOT NULL DEFAULT 'requested',
+  -- The latest note of whoever handled the request, and who that was
+  note TEXT,
+  handled_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  restocked BOOLEAN NOT NULL DEFAULT false,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_return_requests_order_id ON return_requests(order_id, created_at);
+CREATE INDEX idx_return_requests_order_item_id ON return_requests(order_item_id);
+CREATE INDEX idx_return_requests_shop_id ON return_requests(shop_id, created_at);
+
+CREATE TYPE refund_status AS ENUM ('pending', 'succeeded', 'failed');
+
+-- Money owed back to a buyer, such as for an inspected return
+CREATE TABLE refunds (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  return_request_id UUID UNIQUE REFERENCES return_requests(id) ON DELETE SET NULL,
+  amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+  status refund_status NOT NULL DEFAULT 'pending',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refunds_order_id ON refunds(order_id);
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('buyer', 'return:create:own'),
+  ('seller', 'return:create:own'),
+  ('seller', 'return:review:own'),
+  ('admin', 'return:create:own'),
+  ('admin', 'return:review:any');
@@ -0,0 +1,10 @@
+DELETE FROM role_permissions WHERE permission = 'order:pay:own';
+
+ALTER TABLE refunds
+  DROP COLUMN IF EXISTS failure_message,
+  DROP COLUMN IF EXISTS provider_refund_id,
+  DROP COLUMN IF EXISTS payment_id;
+
+DROP TABLE IF EXISTS payment_status_history;
+DROP TABLE IF EXISTS payments;
+DROP TYPE IF EXISTS payment_status;
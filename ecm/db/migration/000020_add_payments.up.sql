@@ -0,0 +1,62 @@
+CREATE TYPE payment_status AS ENUM ('pending', 'requires_action', 'authorized', 'captured', 'declined', 'voided', 'refunded');
+
+-- Every order has one payment, which is tried again after a decline. Orders stay pending until it
+-- is captured.
+CREATE TABLE payments (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+  provider VARCHAR(50) NOT NULL,
+  -- The provider's ID for the latest attempt, if there was one
+  provider_payment_id VARCHAR(255),
+  amount DECIMAL(10, 2) NOT NULL,
+  refunded_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+  status payment_status NOT NULL DEFAULT 'pending',
+  card_last4 VARCHAR(4),
+  failure_message TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  CHECK (refunded_amount <= amount)
+);
+
+CREATE INDEX idx_payments_provider_payment_id ON payments(provider, provider_payment_id);
+
+CREATE TABLE payment_status_history (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+  -- NULL when the payment was created
+  from_status payment_status,
+  to_status payment_status NOT NULL,
+  note TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_payment_status_history_payment_id ON payment_status_history(payment_id, created_at);
+
+-- Orders placed so far were never charged through a provider, so they count as paid, except for
+-- cancelled orders, which were never collected
+INSERT INTO payments (order_id, provider, amount, status, created_at, updated_at)
+SELECT id, 'legacy', total_amount,
+  CASE WHEN status = 'cancelled' THEN 'voided' ELSE 'captured' END::payment_status,
+  created_at, created_at
+FROM orders;
+
+INSERT INTO payment_status_history (payment_id, to_status, note, created_at)
+SELECT id, status,
+  CASE WHEN status = 'voided' THEN 'Cancelled before payments were taken online' ELSE 'Paid before payments were taken online' END,
+  created_at
+FROM payments;
+
+-- Refunds are paid back through the provider of the order's payment
+ALTER TABLE refunds
+  ADD COLUMN payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+  ADD COLUMN provider_refund_id VARCHAR(255),
+  ADD COLUMN failure_message TEXT;
+
+UPDATE refunds r SET payment_id = p.id
+FROM payments p
+WHERE p.order_id = r.order_id;
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('buyer', 'order:pay:own'),
+  ('seller', 'order:pay:own'),
+  ('admin', 'order:pay:own');
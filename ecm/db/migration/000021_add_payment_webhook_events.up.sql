@@ -0,0 +1,18 @@
+-- Every webhook event a payment provider sends, kept as received. An event is processed once, so a
+-- provider sending it again is only acknowledged.
+CREATE TABLE payment_webhook_events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  provider VARCHAR(50) NOT NULL,
+  -- The provider's ID for the event
+  event_id VARCHAR(255) NOT NULL,
+  event_type VARCHAR(100) NOT NULL,
+  payload TEXT NOT NULL,
+  signature TEXT NOT NULL,
+  -- Why processing the event failed the last time it was tried
+  error TEXT,
+  processed_at TIMESTAMP,
+  received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (provider, event_id)
+);
+
+CREATE INDEX idx_payment_webhook_events_received_at ON payment_webhook_events(received_at);
@@ -0,0 +1,11 @@
+ALTER TABLE refunds DROP COLUMN IF EXISTS attempt;
+
+UPDATE refunds SET status = 'pending' WHERE status = 'processing';
+
+ALTER TYPE refund_status RENAME TO refund_status_old;
+CREATE TYPE refund_status AS ENUM ('pending', 'succeeded', 'failed');
+ALTER TABLE refunds
+  ALTER COLUMN status DROP DEFAULT,
+  ALTER COLUMN status TYPE refund_status USING status::text::refund_status,
+  ALTER COLUMN status SET DEFAULT 'pending';
+DROP TYPE refund_status_old;
@@ -0,0 +1,6 @@
+-- Refunds are marked processing while the provider is paying them back, so that a refund whose
+-- outcome was not saved is sent again under the same idempotency key instead of being paid twice
+ALTER TYPE refund_status ADD VALUE 'processing' AFTER 'pending';
+
+-- A refund is sent to the provider under a new idempotency key each time it is retried after failing
+ALTER TABLE refunds ADD COLUMN attempt INT NOT NULL DEFAULT 1;
@@ -0,0 +1,16 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (
+  actor_id, actor_username, action, target_type, target_id, before, after, client_ip, request_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE (sqlc.narg(actor_id)::uuid IS NULL OR actor_id = sqlc.narg(actor_id))
+  AND (sqlc.narg(action)::text IS NULL OR action = sqlc.narg(action))
+  AND (sqlc.narg(target_type)::text IS NULL OR target_type = sqlc.narg(target_type))
+  AND (sqlc.narg(target_id)::uuid IS NULL OR target_id = sqlc.narg(target_id))
+  AND (sqlc.narg(request_id)::text IS NULL OR request_id = sqlc.narg(request_id))
+  AND (sqlc.narg(created_from)::timestamp IS NULL OR created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_to)::timestamp IS NULL OR created_at < sqlc.narg(created_to))
+ORDER BY created_at DESC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
@@ -1,8 +1,8 @@
 -- name: AddToCart :one
-INSERT INTO cart_items (user_id, product_id, quantity)
-VALUES ($1, $2, $3)
+INSERT INTO cart_items (user_id, product_id, quantity, unit_price)
+VALUES ($1, $2, $3, $4)
 ON CONFLICT (user_id, product_id) 
-DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = NOW()
+DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, unit_price = EXCLUDED.unit_price, updated_at = NOW()
 RETURNING *;
 
 -- name: UpdateCartQuantity :one
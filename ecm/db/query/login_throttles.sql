@@ -0,0 +1,41 @@
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE throttle_key = $1;
+
+-- name: RecordLoginAttempt :one
+INSERT INTO login_throttles (throttle_key, failed_attempts, last_failed_at)
+VALUES (sqlc.arg(throttle_key), 1, sqlc.arg(attempted_at))
+ON CONFLICT (throttle_key)
+DO UPDATE SET
+  failed_attempts = CASE
+    WHEN login_throttles.last_failed_at < sqlc.arg(reset_before) THEN 1
+    ELSE login_throttles.failed_attempts + 1
+  END,
+  locked_until = CASE
+    WHEN login_throttles.last_failed_at < sqlc.arg(reset_before) THEN NULL
+    WHEN login_throttles.failed_attempts >= sqlc.arg(free_attempts)::int THEN sqlc.arg(attempted_at) + make_interval(secs => LEAST(
+      sqlc.arg(backoff_base_seconds)::float8 * power(2, LEAST(login_throttles.failed_attempts - sqlc.arg(free_attempts)::int, 30)),
+      sqlc.arg(max_lockout_seconds)::float8
+    ))
+    ELSE NULL
+  END,
+  last_failed_at = EXCLUDED.last_failed_at
+WHERE login_throttles.locked_until IS NULL OR login_throttles.locked_until <= sqlc.arg(attempted_at)
+RETURNING *;
+
+-- name: ForgiveLoginAttempt :exec
+UPDATE login_throttles
+SET failed_attempts = GREATEST(failed_attempts - 1, 0),
+  locked_until = CASE
+    WHEN failed_attempts - 1 <= sqlc.arg(free_attempts)::int THEN NULL
+    ELSE locked_until
+  END
+WHERE throttle_key = sqlc.arg(throttle_key);
+
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE throttle_key = $1;
+
+-- name: DeleteStaleLoginThrottles :exec
+DELETE FROM login_throttles
+WHERE last_failed_at < $1 AND (locked_until IS NULL OR locked_until < $1);
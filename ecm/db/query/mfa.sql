@@ -0,0 +1,39 @@
+-- name: UpsertPendingTOTPCredential :one
+INSERT INTO totp_credentials (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id)
+DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+WHERE totp_credentials.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetTOTPCredential :one
+SELECT * FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: ConfirmTOTPCredential :one
+UPDATE totp_credentials
+SET confirmed_at = NOW()
+WHERE user_id = $1 AND confirmed_at IS NULL
+RETURNING *;
+
+-- name: UseTOTPStep :execrows
+UPDATE totp_credentials
+SET last_used_step = $2
+WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: DeleteTOTPCredential :exec
+DELETE FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (user_id, code_hash)
+VALUES ($1, $2);
+
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
@@ -0,0 +1,10 @@
+-- name: CreateOrderStatusChange :exec
+INSERT INTO order_status_history (order_id, shop_order_id, from_status, to_status, changed_by, note)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListOrderStatusHistory :many
+SELECT h.*, u.username AS changed_by_username
+FROM order_status_history h
+LEFT JOIN users u ON u.id = h.changed_by
+WHERE h.order_id = $1
+ORDER BY h.created_at, h.id;
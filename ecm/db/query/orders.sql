@@ -4,19 +4,47 @@ VALUES ($1, $2, $3, $4)
 RETURNING *;
 
 -- name: CreateOrderItem :one
-INSERT INTO order_items (order_id, product_id, quantity, price)
-VALUES ($1, $2, $3, $4)
+INSERT INTO order_items (order_id, shop_order_id, product_id, quantity, price)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetOrder :one
 SELECT * FROM orders
 WHERE id = $1;
 
+-- name: GetOrderForUpdate :one
+SELECT * FROM orders
+WHERE id = $1
+FOR UPDATE;
+
 -- name: GetOrdersByUser :many
 SELECT * FROM orders
 WHERE user_id = $1
 ORDER BY created_at DESC;
 
+-- name: ListAllOrders :many
+SELECT o.*, u.username,
+  (SELECT COALESCE(SUM(oi.quantity), 0) FROM order_items oi WHERE oi.order_id = o.id)::int AS item_count
+FROM orders o
+JOIN users u ON u.id = o.user_id
+WHERE (sqlc.narg(status)::order_status IS NULL OR o.status = sqlc.narg(status))
+  AND (sqlc.narg(user_id)::uuid IS NULL OR o.user_id = sqlc.narg(user_id))
+  AND (sqlc.narg(shop_id)::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM order_items oi
+    JOIN products p ON p.id = oi.product_id
+    WHERE oi.order_id = o.id AND p.shop_id = sqlc.narg(shop_id)
+  ))
+  AND (sqlc.narg(created_from)::timestamp IS NULL OR o.created_at >= sqlc.narg(created_from))
+  AND (sqlc.narg(created_to)::timestamp IS NULL OR o.created_at < sqlc.narg(created_to))
+  AND (sqlc.narg(min_total)::numeric IS NULL OR o.total_amount >= sqlc.narg(min_total))
+  AND (sqlc.narg(max_total)::numeric IS NULL OR o.total_amount <= sqlc.narg(max_total))
+ORDER BY
+  CASE WHEN sqlc.arg(sort_by)::text = 'created_at_asc' THEN o.created_at END ASC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'total_amount_asc' THEN o.total_amount END ASC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'total_amount_desc' THEN o.total_amount END DESC,
+  o.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
 -- name: GetOrderItems :many
 SELECT oi.*, p.name as product_name, p.image_url
 FROM order_items oi
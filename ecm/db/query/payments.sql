@@ -0,0 +1,82 @@
+-- name: CreatePayment :one
+INSERT INTO payments (order_id, provider, amount)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetPaymentByOrder :one
+SELECT * FROM payments
+WHERE order_id = $1;
+
+-- name: GetPaymentByOrderForUpdate :one
+SELECT * FROM payments
+WHERE order_id = $1
+FOR UPDATE;
+
+-- name: UpdatePayment :one
+UPDATE payments
+SET provider_payment_id = $2, status = $3, card_last4 = $4, failure_message = $5, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: AddPaymentRefund :one
+UPDATE payments
+SET refunded_amount = refunded_amount + sqlc.arg(refund_amount),
+  status = CASE WHEN refunded_amount + sqlc.arg(refund_amount) >= amount THEN 'refunded' ELSE status END,
+  updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: CreatePaymentStatusChange :exec
+INSERT INTO payment_status_history (payment_id, from_status, to_status, note)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListPaymentStatusHistory :many
+SELECT * FROM payment_status_history
+WHERE payment_id = $1
+ORDER BY created_at, id;
+
+-- name: ReducePaymentAmount :one
+UPDATE payments
+SET amount = amount - sqlc.arg(reduction), updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetPaymentByProviderPaymentID :one
+SELECT * FROM payments
+WHERE provider = $1 AND provider_payment_id = $2;
+
+-- name: ListExpiredUnpaidOrderIDs :many
+SELECT o.id FROM orders o
+JOIN payments p ON p.order_id = o.id
+WHERE o.status = 'pending'
+  AND p.status IN ('pending', 'requires_action', 'declined', 'authorized')
+  AND o.created_at < $1
+ORDER BY o.created_at
+LIMIT $2;
+
+-- name: CreatePaymentWebhookEvent :exec
+INSERT INTO payment_webhook_events (provider, event_id, event_type, payload, signature)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (provider, event_id) DO NOTHING;
+
+-- name: GetPaymentWebhookEventForUpdate :one
+SELECT * FROM payment_webhook_events
+WHERE provider = $1 AND event_id = $2
+FOR UPDATE;
+
+-- name: MarkPaymentWebhookEventProcessed :exec
+UPDATE payment_webhook_events
+SET processed_at = NOW(), error = $2
+WHERE id = $1;
+
+-- name: RecordPaymentWebhookEventError :exec
+UPDATE payment_webhook_events
+SET error = $3
+WHERE provider = $1 AND event_id = $2;
+
+-- name: ListPaymentWebhookEvents :many
+SELECT * FROM payment_webhook_events
+WHERE provider = sqlc.arg(provider)
+  AND (sqlc.narg(event_id)::varchar IS NULL OR event_id = sqlc.narg(event_id))
+ORDER BY received_at
+LIMIT sqlc.arg('limit');
@@ -51,3 +51,9 @@ UPDATE products
 SET stock_quantity = stock_quantity + $2, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
+
+-- name: DecreaseProductStock :one
+UPDATE products
+SET stock_quantity = stock_quantity - sqlc.arg(quantity), updated_at = NOW()
+WHERE id = sqlc.arg(id) AND stock_quantity >= sqlc.arg(quantity)
+RETURNING *;
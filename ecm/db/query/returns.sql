@@ -0,0 +1,89 @@
+-- name: GetOrderItemForReturn :one
+SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price, so.shop_id, so.status AS shop_order_status
+FROM order_items oi
+JOIN shop_orders so ON so.id = oi.shop_order_id
+WHERE oi.id = $1;
+
+-- name: GetReturnedQuantity :one
+SELECT COALESCE(SUM(quantity), 0)::int AS returned_quantity
+FROM return_requests
+WHERE order_item_id = $1 AND status <> 'rejected';
+
+-- name: CreateReturnRequest :one
+INSERT INTO return_requests (order_id, order_item_id, shop_id, user_id, quantity, reason)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetReturnRequest :one
+SELECT * FROM return_requests
+WHERE id = $1;
+
+-- name: GetReturnRequestForUpdate :one
+SELECT * FROM return_requests
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListReturnRequestsByOrder :many
+SELECT rr.*, p.name AS product_name,
+  r.id AS refund_id, r.amount AS refund_amount, r.status AS refund_status
+FROM return_requests rr
+JOIN order_items oi ON oi.id = rr.order_item_id
+JOIN products p ON p.id = oi.product_id
+LEFT JOIN refunds r ON r.return_request_id = rr.id
+WHERE rr.order_id = $1
+ORDER BY rr.created_at;
+
+-- name: ListReturnRequestsByShop :many
+SELECT rr.*, p.name AS product_name, u.username,
+  r.id AS refund_id, r.amount AS refund_amount, r.status AS refund_status
+FROM return_requests rr
+JOIN order_items oi ON oi.id = rr.order_item_id
+JOIN products p ON p.id = oi.product_id
+JOIN users u ON u.id = rr.user_id
+LEFT JOIN refunds r ON r.return_request_id = rr.id
+WHERE rr.shop_id = sqlc.arg(shop_id)
+  AND (sqlc.narg(status)::return_status IS NULL OR rr.status = sqlc.narg(status))
+ORDER BY rr.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: UpdateReturnRequestStatus :one
+UPDATE return_requests
+SET status = $2, note = $3, handled_by = $4, restocked = $5, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateRefund :one
+INSERT INTO refunds (order_id, return_request_id, payment_id, amount, status, provider_refund_id, failure_message)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetRefundByReturnRequest :one
+SELECT * FROM refunds
+WHERE return_request_id = $1;
+
+-- name: GetRefundForUpdate :one
+SELECT * FROM refunds
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetUnsettledRefundAmount :one
+SELECT COALESCE(SUM(amount), 0)::numeric AS unsettled_amount
+FROM refunds
+WHERE payment_id = $1 AND status <> 'succeeded';
+
+-- name: ListPendingRefundIDs :many
+SELECT id FROM refunds
+WHERE payment_id = $1 AND status = 'pending'
+ORDER BY created_at;
+
+-- name: UpdateRefund :one
+UPDATE refunds
+SET status = $2, provider_refund_id = $3, failure_message = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RetryRefund :one
+UPDATE refunds
+SET status = 'pending', attempt = attempt + 1, failure_message = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,26 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (id, user_id, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS (
+  SELECT 1 FROM revoked_tokens
+  WHERE id = $1
+);
+
+-- name: DeleteExpiredRevokedTokens :exec
+DELETE FROM revoked_tokens
+WHERE expires_at < NOW();
+
+-- name: RevokeUserTokens :exec
+INSERT INTO user_token_revocations (user_id, revoked_before)
+VALUES ($1, $2)
+ON CONFLICT (user_id)
+DO UPDATE SET revoked_before = EXCLUDED.revoked_before;
+
+-- name: GetUserTokenStatus :one
+SELECT u.suspended_at, r.revoked_before
+FROM users u
+LEFT JOIN user_token_revocations r ON r.user_id = u.id
+WHERE u.id = $1;
@@ -0,0 +1,4 @@
+-- name: ListRolePermissions :many
+SELECT permission FROM role_permissions
+WHERE role = $1
+ORDER BY permission;
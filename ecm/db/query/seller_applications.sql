@@ -0,0 +1,27 @@
+-- name: CreateSellerApplication :one
+INSERT INTO seller_applications (
+  user_id, business_name, business_email, business_phone, business_address, tax_id, description
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetSellerApplication :one
+SELECT * FROM seller_applications
+WHERE id = $1;
+
+-- name: GetLatestSellerApplicationByUser :one
+SELECT * FROM seller_applications
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ListSellerApplications :many
+SELECT * FROM seller_applications
+WHERE sqlc.narg(status)::seller_application_status IS NULL OR status = sqlc.narg(status)
+ORDER BY created_at
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ReviewSellerApplication :one
+UPDATE seller_applications
+SET status = $2, reviewed_by = $3, review_note = $4, reviewed_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
@@ -0,0 +1,29 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, refresh_token_hash, user_agent, client_ip, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE id = $1;
+
+-- name: ListActiveSessionsByUser :many
+SELECT * FROM sessions
+WHERE user_id = $1 AND is_blocked = false AND expires_at > NOW()
+ORDER BY last_used_at DESC;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = NOW()
+WHERE id = $1;
+
+-- name: BlockSession :one
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: BlockUserSessions :exec
+UPDATE sessions
+SET is_blocked = true
+WHERE user_id = $1 AND is_blocked = false;
@@ -0,0 +1,56 @@
+-- name: CreateShipment :one
+INSERT INTO shipments (order_id, shop_order_id, carrier, tracking_number, estimated_delivery)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: AddShopOrderItemsToShipment :exec
+INSERT INTO shipment_items (shipment_id, order_item_id)
+SELECT sqlc.arg(shipment_id)::uuid, oi.id
+FROM order_items oi
+WHERE oi.shop_order_id = sqlc.arg(shop_order_id);
+
+-- name: ListShipmentsByOrder :many
+SELECT * FROM shipments
+WHERE order_id = $1
+ORDER BY created_at;
+
+-- name: ListShipmentsByTrackingNumberForUpdate :many
+SELECT * FROM shipments
+WHERE carrier = $1 AND tracking_number = $2
+ORDER BY id
+FOR UPDATE;
+
+-- name: ListShipmentItemsByOrder :many
+SELECT si.shipment_id, oi.id AS order_item_id, oi.product_id, p.name AS product_name, oi.quantity
+FROM shipment_items si
+JOIN order_items oi ON oi.id = si.order_item_id
+JOIN products p ON p.id = oi.product_id
+WHERE oi.order_id = $1
+ORDER BY p.name;
+
+-- name: CreateTrackingEvent :execrows
+INSERT INTO tracking_events (shipment_id, status, location, description, occurred_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (shipment_id, status, occurred_at) DO NOTHING;
+
+-- name: ListTrackingEventsByOrder :many
+SELECT te.* FROM tracking_events te
+JOIN shipments s ON s.id = te.shipment_id
+WHERE s.order_id = $1
+ORDER BY te.occurred_at DESC;
+
+-- name: RefreshShipmentStatus :one
+UPDATE shipments
+SET status = (
+    SELECT te.status FROM tracking_events te
+    WHERE te.shipment_id = shipments.id
+    ORDER BY te.occurred_at DESC
+    LIMIT 1
+  ),
+  delivered_at = (
+    SELECT MIN(te.occurred_at) FROM tracking_events te
+    WHERE te.shipment_id = shipments.id AND te.status = 'delivered'
+  ),
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,54 @@
+-- name: CreateShopOrder :one
+INSERT INTO shop_orders (order_id, shop_id, subtotal)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetShopOrder :one
+SELECT * FROM shop_orders
+WHERE id = $1;
+
+-- name: GetShopOrderForUpdate :one
+SELECT * FROM shop_orders
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListShopOrdersByOrder :many
+SELECT so.*, s.name AS shop_name
+FROM shop_orders so
+JOIN shops s ON s.id = so.shop_id
+WHERE so.order_id = $1
+ORDER BY s.name;
+
+-- name: ListShopOrdersByOrderForUpdate :many
+SELECT * FROM shop_orders
+WHERE order_id = $1
+ORDER BY id
+FOR UPDATE;
+
+-- name: ListShopOrders :many
+SELECT so.*, u.username, o.shipping_address
+FROM shop_orders so
+JOIN orders o ON o.id = so.order_id
+JOIN users u ON u.id = o.user_id
+WHERE so.shop_id = sqlc.arg(shop_id)
+  AND (sqlc.narg(status)::order_status IS NULL OR so.status = sqlc.narg(status))
+ORDER BY so.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetShopOrderItems :many
+SELECT oi.*, p.name as product_name, p.image_url
+FROM order_items oi
+JOIN products p ON oi.product_id = p.id
+WHERE oi.shop_order_id = $1;
+
+-- name: ListShopOrderItemsByShopOrders :many
+SELECT oi.*, p.name as product_name, p.image_url
+FROM order_items oi
+JOIN products p ON oi.product_id = p.id
+WHERE oi.shop_order_id = ANY(@shop_order_ids::uuid[]);
+
+-- name: UpdateShopOrderStatus :one
+UPDATE shop_orders
+SET status = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,63 @@
+-- name: CountUsersByRole :many
+SELECT role, COUNT(*) AS count
+FROM users
+GROUP BY role
+ORDER BY role;
+
+-- name: CountShops :one
+SELECT COUNT(*) FROM shops;
+
+-- name: CountProducts :one
+SELECT COUNT(*) FROM products;
+
+-- name: CountOrdersByStatus :many
+SELECT status, COUNT(*) AS count
+FROM orders
+GROUP BY status
+ORDER BY status;
+
+-- name: GetRevenueByPeriod :many
+SELECT periods.period_start::timestamp AS period_start,
+  COUNT(p.id) AS order_count,
+  COALESCE(SUM(p.amount - p.refunded_amount), 0)::numeric AS revenue
+FROM generate_series(
+  date_trunc(sqlc.arg(period)::text, sqlc.arg(created_from)::timestamp),
+  sqlc.arg(created_to)::timestamp - INTERVAL '1 microsecond',
+  ('1 ' || sqlc.arg(period)::text)::interval
+) AS periods(period_start)
+LEFT JOIN payments p
+  ON date_trunc(sqlc.arg(period)::text, p.created_at) = periods.period_start
+  AND p.status IN ('captured', 'refunded')
+  AND p.created_at >= sqlc.arg(created_from)
+  AND p.created_at < sqlc.arg(created_to)
+GROUP BY periods.period_start
+ORDER BY periods.period_start;
+
+-- name: ListTopProducts :many
+SELECT p.id, p.name, p.shop_id,
+  SUM(oi.quantity)::bigint AS units_sold,
+  SUM(oi.price * oi.quantity)::numeric AS revenue
+FROM order_items oi
+JOIN shop_orders so ON so.id = oi.shop_order_id
+JOIN products p ON p.id = oi.product_id
+WHERE so.status <> 'cancelled'
+  AND so.created_at >= sqlc.arg(created_from)
+  AND so.created_at < sqlc.arg(created_to)
+GROUP BY p.id, p.name, p.shop_id
+ORDER BY revenue DESC, units_sold DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListTopShops :many
+SELECT s.id, s.name,
+  COUNT(DISTINCT so.id) AS order_count,
+  SUM(oi.quantity)::bigint AS units_sold,
+  SUM(oi.price * oi.quantity)::numeric AS revenue
+FROM order_items oi
+JOIN shop_orders so ON so.id = oi.shop_order_id
+JOIN shops s ON s.id = so.shop_id
+WHERE so.status <> 'cancelled'
+  AND so.created_at >= sqlc.arg(created_from)
+  AND so.created_at < sqlc.arg(created_to)
+GROUP BY s.id, s.name
+ORDER BY revenue DESC, order_count DESC
+LIMIT sqlc.arg('limit');
@@ -21,7 +21,59 @@ SET role = $2, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
+-- name: PromoteBuyerToSeller :one
+UPDATE users
+SET role = 'seller', updated_at = NOW()
+WHERE id = $1 AND role = 'buyer'
+RETURNING *;
+
 -- name: ListUsers :many
 SELECT * FROM users
+WHERE (
+  sqlc.narg(search)::text IS NULL
+  OR username ILIKE '%' || sqlc.narg(search) || '%'
+  OR email ILIKE '%' || sqlc.narg(search) || '%'
+)
+AND (sqlc.narg(role)::user_role IS NULL OR role = sqlc.narg(role))
 ORDER BY created_at
-LIMIT $1 OFFSET $2;
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: MarkUserEmailVerified :one
+UPDATE users
+SET email = $2, email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateUserUsername :one
+UPDATE users
+SET username = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
+
+-- name: ListActiveAdminIDsForUpdate :many
+SELECT id FROM users
+WHERE role = 'admin' AND suspended_at IS NULL
+ORDER BY id
+FOR UPDATE;
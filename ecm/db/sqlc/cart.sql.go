@@ -11,24 +11,31 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/qhh/ecm/money"
 )
 
 const addToCart = `-- name: AddToCart :one
-INSERT INTO cart_items (user_id, product_id, quantity)
-VALUES ($1, $2, $3)
+INSERT INTO cart_items (user_id, product_id, quantity, unit_price)
+VALUES ($1, $2, $3, $4)
 ON CONFLICT (user_id, product_id) 
-DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = NOW()
-RETURNING id, user_id, product_id, quantity, created_at, updated_at
+DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, unit_price = EXCLUDED.unit_price, updated_at = NOW()
+RETURNING id, user_id, product_id, quantity, created_at, updated_at, unit_price
 `
 
 type AddToCartParams struct {
-	UserID    uuid.UUID `json:"user_id"`
-	ProductID uuid.UUID `json:"product_id"`
-	Quantity  int32     `json:"quantity"`
+	UserID    uuid.UUID    `json:"user_id"`
+	ProductID uuid.UUID    `json:"product_id"`
+	Quantity  int32        `json:"quantity"`
+	UnitPrice money.Amount `json:"unit_price"`
 }
 
 func (q *Queries) AddToCart(ctx context.Context, arg AddToCartParams) (CartItem, error) {
-	row := q.db.QueryRowContext(ctx, addToCart, arg.UserID, arg.ProductID, arg.Quantity)
+	row := q.db.QueryRowContext(ctx, addToCart,
+		arg.UserID,
+		arg.ProductID,
+		arg.Quantity,
+		arg.UnitPrice,
+	)
 	var i CartItem
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +44,7 @@ func (q *Queries) AddToCart(ctx context.Context, arg AddToCartParams) (CartItem,
 		&i.Quantity,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.UnitPrice,
 	)
 	return i, err
 }
@@ -52,7 +60,7 @@ func (q *Queries) ClearCart(ctx context.Context, userID uuid.UUID) error {
 }
 
 const getCartItems = `-- name: GetCartItems :many
-SELECT c.id, c.user_id, c.product_id, c.quantity, c.created_at, c.updated_at, p.name as product_name, p.price, p.image_url
+SELECT c.id, c.user_id, c.product_id, c.quantity, c.created_at, c.updated_at, c.unit_price, p.name as product_name, p.price, p.image_url
 FROM cart_items c
 JOIN products p ON c.product_id = p.id
 WHERE c.user_id = $1
@@ -65,8 +73,9 @@ type GetCartItemsRow struct {
 	Quantity    int32          `json:"quantity"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
+	UnitPrice   money.Amount   `json:"unit_price"`
 	ProductName string         `json:"product_name"`
-	Price       string         `json:"price"`
+	Price       money.Amount   `json:"price"`
 	ImageUrl    sql.NullString `json:"image_url"`
 }
 
@@ -86,6 +95,7 @@ func (q *Queries) GetCartItems(ctx context.Context, userID uuid.UUID) ([]GetCart
 			&i.Quantity,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.UnitPrice,
 			&i.ProductName,
 			&i.Price,
 			&i.ImageUrl,
@@ -122,7 +132,7 @@ const updateCartQuantity = `-- name: UpdateCartQuantity :one
 UPDATE cart_items
 SET quantity = $3, updated_at = NOW()
 WHERE user_id = $1 AND product_id = $2
-RETURNING id, user_id, product_id, quantity, created_at, updated_at
+RETURNING id, user_id, product_id, quantity, created_at, updated_at, unit_price
 `
 
 type UpdateCartQuantityParams struct {
@@ -141,6 +151,7 @@ func (q *Queries) UpdateCartQuantity(ctx context.Context, arg UpdateCartQuantity
 		&i.Quantity,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.UnitPrice,
 	)
 	return i, err
 }
@@ -7,10 +7,12 @@ package db
 import (
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/qhh/ecm/money"
 )
 
 type OrderStatus string
@@ -58,6 +60,230 @@ func (ns NullOrderStatus) Value() (driver.Value, error) {
 	return string(ns.OrderStatus), nil
 }
 
+type PaymentStatus string
+
+const (
+	PaymentStatusPending        PaymentStatus = "pending"
+	PaymentStatusRequiresAction PaymentStatus = "requires_action"
+	PaymentStatusAuthorized     PaymentStatus = "authorized"
+	PaymentStatusCaptured       PaymentStatus = "captured"
+	PaymentStatusDeclined       PaymentStatus = "declined"
+	PaymentStatusVoided         PaymentStatus = "voided"
+	PaymentStatusRefunded       PaymentStatus = "refunded"
+)
+
+func (e *PaymentStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PaymentStatus(s)
+	case string:
+		*e = PaymentStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PaymentStatus: %T", src)
+	}
+	return nil
+}
+
+type NullPaymentStatus struct {
+	PaymentStatus PaymentStatus `json:"payment_status"`
+	Valid         bool          `json:"valid"` // Valid is true if PaymentStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPaymentStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.PaymentStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PaymentStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPaymentStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PaymentStatus), nil
+}
+
+type RefundStatus string
+
+const (
+	RefundStatusPending    RefundStatus = "pending"
+	RefundStatusProcessing RefundStatus = "processing"
+	RefundStatusSucceeded  RefundStatus = "succeeded"
+	RefundStatusFailed     RefundStatus = "failed"
+)
+
+func (e *RefundStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RefundStatus(s)
+	case string:
+		*e = RefundStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RefundStatus: %T", src)
+	}
+	return nil
+}
+
+type NullRefundStatus struct {
+	RefundStatus RefundStatus `json:"refund_status"`
+	Valid        bool         `json:"valid"` // Valid is true if RefundStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRefundStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.RefundStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RefundStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRefundStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RefundStatus), nil
+}
+
+type ReturnStatus string
+
+const (
+	ReturnStatusRequested ReturnStatus = "requested"
+	ReturnStatusApproved  ReturnStatus = "approved"
+	ReturnStatusRejected  ReturnStatus = "rejected"
+	ReturnStatusReceived  ReturnStatus = "received"
+	ReturnStatusInspected ReturnStatus = "inspected"
+)
+
+func (e *ReturnStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ReturnStatus(s)
+	case string:
+		*e = ReturnStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ReturnStatus: %T", src)
+	}
+	return nil
+}
+
+type NullReturnStatus struct {
+	ReturnStatus ReturnStatus `json:"return_status"`
+	Valid        bool         `json:"valid"` // Valid is true if ReturnStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullReturnStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ReturnStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ReturnStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullReturnStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ReturnStatus), nil
+}
+
+type SellerApplicationStatus string
+
+const (
+	SellerApplicationStatusPending  SellerApplicationStatus = "pending"
+	SellerApplicationStatusApproved SellerApplicationStatus = "approved"
+	SellerApplicationStatusRejected SellerApplicationStatus = "rejected"
+)
+
+func (e *SellerApplicationStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = SellerApplicationStatus(s)
+	case string:
+		*e = SellerApplicationStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for SellerApplicationStatus: %T", src)
+	}
+	return nil
+}
+
+type NullSellerApplicationStatus struct {
+	SellerApplicationStatus SellerApplicationStatus `json:"seller_application_status"`
+	Valid                   bool                    `json:"valid"` // Valid is true if SellerApplicationStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullSellerApplicationStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.SellerApplicationStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.SellerApplicationStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullSellerApplicationStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.SellerApplicationStatus), nil
+}
+
+type ShipmentStatus string
+
+const (
+	ShipmentStatusLabelCreated   ShipmentStatus = "label_created"
+	ShipmentStatusInTransit      ShipmentStatus = "in_transit"
+	ShipmentStatusOutForDelivery ShipmentStatus = "out_for_delivery"
+	ShipmentStatusDelivered      ShipmentStatus = "delivered"
+	ShipmentStatusException      ShipmentStatus = "exception"
+)
+
+func (e *ShipmentStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ShipmentStatus(s)
+	case string:
+		*e = ShipmentStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ShipmentStatus: %T", src)
+	}
+	return nil
+}
+
+type NullShipmentStatus struct {
+	ShipmentStatus ShipmentStatus `json:"shipment_status"`
+	Valid          bool           `json:"valid"` // Valid is true if ShipmentStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullShipmentStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ShipmentStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ShipmentStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullShipmentStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ShipmentStatus), nil
+}
+
 type UserRole string
 
 const (
@@ -101,13 +327,28 @@ func (ns NullUserRole) Value() (driver.Value, error) {
 	return string(ns.UserRole), nil
 }
 
+type AuditEvent struct {
+	ID            uuid.UUID       `json:"id"`
+	ActorID       uuid.UUID       `json:"actor_id"`
+	ActorUsername string          `json:"actor_username"`
+	Action        string          `json:"action"`
+	TargetType    string          `json:"target_type"`
+	TargetID      uuid.UUID       `json:"target_id"`
+	Before        json.RawMessage `json:"before"`
+	After         json.RawMessage `json:"after"`
+	ClientIp      string          `json:"client_ip"`
+	RequestID     string          `json:"request_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
 type CartItem struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	ProductID uuid.UUID `json:"product_id"`
-	Quantity  int32     `json:"quantity"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	ProductID uuid.UUID    `json:"product_id"`
+	Quantity  int32        `json:"quantity"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	UnitPrice money.Amount `json:"unit_price"`
 }
 
 type Category struct {
@@ -118,31 +359,112 @@ type Category struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 }
 
+type EmailVerificationToken struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	Email     string       `json:"email"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	ThrottleKey    string       `json:"throttle_key"`
+	FailedAttempts int32        `json:"failed_attempts"`
+	LockedUntil    sql.NullTime `json:"locked_until"`
+	LastFailedAt   time.Time    `json:"last_failed_at"`
+}
+
+type MfaRecoveryCode struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
 type Order struct {
-	ID              uuid.UUID   `json:"id"`
-	UserID          uuid.UUID   `json:"user_id"`
-	Status          OrderStatus `json:"status"`
-	TotalAmount     string      `json:"total_amount"`
-	ShippingAddress string      `json:"shipping_address"`
-	PaymentMethod   string      `json:"payment_method"`
-	CreatedAt       time.Time   `json:"created_at"`
-	UpdatedAt       time.Time   `json:"updated_at"`
+	ID              uuid.UUID    `json:"id"`
+	UserID          uuid.UUID    `json:"user_id"`
+	Status          OrderStatus  `json:"status"`
+	TotalAmount     money.Amount `json:"total_amount"`
+	ShippingAddress string       `json:"shipping_address"`
+	PaymentMethod   string       `json:"payment_method"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
 }
 
 type OrderItem struct {
-	ID        uuid.UUID `json:"id"`
-	OrderID   uuid.UUID `json:"order_id"`
-	ProductID uuid.UUID `json:"product_id"`
-	Quantity  int32     `json:"quantity"`
-	Price     string    `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          uuid.UUID    `json:"id"`
+	OrderID     uuid.UUID    `json:"order_id"`
+	ProductID   uuid.UUID    `json:"product_id"`
+	Quantity    int32        `json:"quantity"`
+	Price       money.Amount `json:"price"`
+	CreatedAt   time.Time    `json:"created_at"`
+	ShopOrderID uuid.UUID    `json:"shop_order_id"`
+}
+
+type OrderStatusHistory struct {
+	ID          uuid.UUID       `json:"id"`
+	OrderID     uuid.UUID       `json:"order_id"`
+	FromStatus  NullOrderStatus `json:"from_status"`
+	ToStatus    OrderStatus     `json:"to_status"`
+	ChangedBy   uuid.NullUUID   `json:"changed_by"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Note        sql.NullString  `json:"note"`
+	ShopOrderID uuid.NullUUID   `json:"shop_order_id"`
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type Payment struct {
+	ID                uuid.UUID      `json:"id"`
+	OrderID           uuid.UUID      `json:"order_id"`
+	Provider          string         `json:"provider"`
+	ProviderPaymentID sql.NullString `json:"provider_payment_id"`
+	Amount            money.Amount   `json:"amount"`
+	RefundedAmount    money.Amount   `json:"refunded_amount"`
+	Status            PaymentStatus  `json:"status"`
+	CardLast4         sql.NullString `json:"card_last4"`
+	FailureMessage    sql.NullString `json:"failure_message"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+type PaymentStatusHistory struct {
+	ID         uuid.UUID         `json:"id"`
+	PaymentID  uuid.UUID         `json:"payment_id"`
+	FromStatus NullPaymentStatus `json:"from_status"`
+	ToStatus   PaymentStatus     `json:"to_status"`
+	Note       sql.NullString    `json:"note"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+type PaymentWebhookEvent struct {
+	ID          uuid.UUID      `json:"id"`
+	Provider    string         `json:"provider"`
+	EventID     string         `json:"event_id"`
+	EventType   string         `json:"event_type"`
+	Payload     string         `json:"payload"`
+	Signature   string         `json:"signature"`
+	Error       sql.NullString `json:"error"`
+	ProcessedAt sql.NullTime   `json:"processed_at"`
+	ReceivedAt  time.Time      `json:"received_at"`
 }
 
 type Product struct {
 	ID            uuid.UUID      `json:"id"`
 	Name          string         `json:"name"`
 	Description   sql.NullString `json:"description"`
-	Price         string         `json:"price"`
+	Price         money.Amount   `json:"price"`
 	StockQuantity int32          `json:"stock_quantity"`
 	ShopID        uuid.UUID      `json:"shop_id"`
 	CategoryID    uuid.UUID      `json:"category_id"`
@@ -151,6 +473,89 @@ type Product struct {
 	UpdatedAt     time.Time      `json:"updated_at"`
 }
 
+type Refund struct {
+	ID               uuid.UUID      `json:"id"`
+	OrderID          uuid.UUID      `json:"order_id"`
+	ReturnRequestID  uuid.NullUUID  `json:"return_request_id"`
+	Amount           money.Amount   `json:"amount"`
+	Status           RefundStatus   `json:"status"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	PaymentID        uuid.NullUUID  `json:"payment_id"`
+	ProviderRefundID sql.NullString `json:"provider_refund_id"`
+	FailureMessage   sql.NullString `json:"failure_message"`
+	Attempt          int32          `json:"attempt"`
+}
+
+type ReturnRequest struct {
+	ID          uuid.UUID      `json:"id"`
+	OrderID     uuid.UUID      `json:"order_id"`
+	OrderItemID uuid.UUID      `json:"order_item_id"`
+	ShopID      uuid.UUID      `json:"shop_id"`
+	UserID      uuid.UUID      `json:"user_id"`
+	Quantity    int32          `json:"quantity"`
+	Reason      string         `json:"reason"`
+	Status      ReturnStatus   `json:"status"`
+	Note        sql.NullString `json:"note"`
+	HandledBy   uuid.NullUUID  `json:"handled_by"`
+	Restocked   bool           `json:"restocked"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+type RolePermission struct {
+	Role       UserRole  `json:"role"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type SellerApplication struct {
+	ID              uuid.UUID               `json:"id"`
+	UserID          uuid.UUID               `json:"user_id"`
+	BusinessName    string                  `json:"business_name"`
+	BusinessEmail   string                  `json:"business_email"`
+	BusinessPhone   string                  `json:"business_phone"`
+	BusinessAddress string                  `json:"business_address"`
+	TaxID           string                  `json:"tax_id"`
+	Description     sql.NullString          `json:"description"`
+	Status          SellerApplicationStatus `json:"status"`
+	ReviewedBy      uuid.NullUUID           `json:"reviewed_by"`
+	ReviewNote      sql.NullString          `json:"review_note"`
+	ReviewedAt      sql.NullTime            `json:"reviewed_at"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+}
+
+type Session struct {
+	ID               uuid.UUID `json:"id"`
+	UserID           uuid.UUID `json:"user_id"`
+	RefreshTokenHash string    `json:"refresh_token_hash"`
+	UserAgent        string    `json:"user_agent"`
+	ClientIp         string    `json:"client_ip"`
+	IsBlocked        bool      `json:"is_blocked"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	LastUsedAt       time.Time `json:"last_used_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type Shipment struct {
+	ID                uuid.UUID      `json:"id"`
+	OrderID           uuid.UUID      `json:"order_id"`
+	ShopOrderID       uuid.UUID      `json:"shop_order_id"`
+	Carrier           string         `json:"carrier"`
+	TrackingNumber    string         `json:"tracking_number"`
+	Status            ShipmentStatus `json:"status"`
+	EstimatedDelivery sql.NullTime   `json:"estimated_delivery"`
+	DeliveredAt       sql.NullTime   `json:"delivered_at"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+type ShipmentItem struct {
+	ShipmentID  uuid.UUID `json:"shipment_id"`
+	OrderItemID uuid.UUID `json:"order_item_id"`
+}
+
 type Shop struct {
 	ID          uuid.UUID      `json:"id"`
 	Name        string         `json:"name"`
@@ -160,12 +565,42 @@ type Shop struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 }
 
+type ShopOrder struct {
+	ID        uuid.UUID    `json:"id"`
+	OrderID   uuid.UUID    `json:"order_id"`
+	ShopID    uuid.UUID    `json:"shop_id"`
+	Status    OrderStatus  `json:"status"`
+	Subtotal  money.Amount `json:"subtotal"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+type TotpCredential struct {
+	UserID       uuid.UUID    `json:"user_id"`
+	Secret       string       `json:"secret"`
+	ConfirmedAt  sql.NullTime `json:"confirmed_at"`
+	LastUsedStep int64        `json:"last_used_step"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type TrackingEvent struct {
+	ID          uuid.UUID      `json:"id"`
+	ShipmentID  uuid.UUID      `json:"shipment_id"`
+	Status      ShipmentStatus `json:"status"`
+	Location    string         `json:"location"`
+	Description string         `json:"description"`
+	OccurredAt  time.Time      `json:"occurred_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"password_hash"`
-	Role         UserRole  `json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              uuid.UUID    `json:"id"`
+	Username        string       `json:"username"`
+	Email           string       `json:"email"`
+	PasswordHash    string       `json:"password_hash"`
+	Role            UserRole     `json:"role"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	EmailVerifiedAt sql.NullTime `json:"email_verified_at"`
+	SuspendedAt     sql.NullTime `json:"suspended_at"`
 }
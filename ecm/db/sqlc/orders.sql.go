@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/qhh/ecm/money"
 )
 
 const createOrder = `-- name: CreateOrder :one
@@ -20,10 +21,10 @@ RETURNING id, user_id, status, total_amount, shipping_address, payment_method, c
 `
 
 type CreateOrderParams struct {
-	UserID          uuid.UUID `json:"user_id"`
-	TotalAmount     string    `json:"total_amount"`
-	ShippingAddress string    `json:"shipping_address"`
-	PaymentMethod   string    `json:"payment_method"`
+	UserID          uuid.UUID    `json:"user_id"`
+	TotalAmount     money.Amount `json:"total_amount"`
+	ShippingAddress string       `json:"shipping_address"`
+	PaymentMethod   string       `json:"payment_method"`
 }
 
 func (q *Queries) CreateOrder(ctx context.Context, arg CreateOrderParams) (Order, error) {
@@ -48,21 +49,23 @@ func (q *Queries) CreateOrder(ctx context.Context, arg CreateOrderParams) (Order
 }
 
 const createOrderItem = `-- name: CreateOrderItem :one
-INSERT INTO order_items (order_id, product_id, quantity, price)
-VALUES ($1, $2, $3, $4)
-RETURNING id, order_id, product_id, quantity, price, created_at
+INSERT INTO order_items (order_id, shop_order_id, product_id, quantity, price)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, order_id, product_id, quantity, price, created_at, shop_order_id
 `
 
 type CreateOrderItemParams struct {
-	OrderID   uuid.UUID `json:"order_id"`
-	ProductID uuid.UUID `json:"product_id"`
-	Quantity  int32     `json:"quantity"`
-	Price     string    `json:"price"`
+	OrderID     uuid.UUID    `json:"order_id"`
+	ShopOrderID uuid.UUID    `json:"shop_order_id"`
+	ProductID   uuid.UUID    `json:"product_id"`
+	Quantity    int32        `json:"quantity"`
+	Price       money.Amount `json:"price"`
 }
 
 func (q *Queries) CreateOrderItem(ctx context.Context, arg CreateOrderItemParams) (OrderItem, error) {
 	row := q.db.QueryRowContext(ctx, createOrderItem,
 		arg.OrderID,
+		arg.ShopOrderID,
 		arg.ProductID,
 		arg.Quantity,
 		arg.Price,
@@ -75,6 +78,7 @@ func (q *Queries) CreateOrderItem(ctx context.Context, arg CreateOrderItemParams
 		&i.Quantity,
 		&i.Price,
 		&i.CreatedAt,
+		&i.ShopOrderID,
 	)
 	return i, err
 }
@@ -100,8 +104,30 @@ func (q *Queries) GetOrder(ctx context.Context, id uuid.UUID) (Order, error) {
 	return i, err
 }
 
+const getOrderForUpdate = `-- name: GetOrderForUpdate :one
+SELECT id, user_id, status, total_amount, shipping_address, payment_method, created_at, updated_at FROM orders
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetOrderForUpdate(ctx context.Context, id uuid.UUID) (Order, error) {
+	row := q.db.QueryRowContext(ctx, getOrderForUpdate, id)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.TotalAmount,
+		&i.ShippingAddress,
+		&i.PaymentMethod,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getOrderItems = `-- name: GetOrderItems :many
-SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price, oi.created_at, p.name as product_name, p.image_url
+SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price, oi.created_at, oi.shop_order_id, p.name as product_name, p.image_url
 FROM order_items oi
 JOIN products p ON oi.product_id = p.id
 WHERE oi.order_id = $1
@@ -112,8 +138,9 @@ type GetOrderItemsRow struct {
 	OrderID     uuid.UUID      `json:"order_id"`
 	ProductID   uuid.UUID      `json:"product_id"`
 	Quantity    int32          `json:"quantity"`
-	Price       string         `json:"price"`
+	Price       money.Amount   `json:"price"`
 	CreatedAt   time.Time      `json:"created_at"`
+	ShopOrderID uuid.UUID      `json:"shop_order_id"`
 	ProductName string         `json:"product_name"`
 	ImageUrl    sql.NullString `json:"image_url"`
 }
@@ -134,6 +161,7 @@ func (q *Queries) GetOrderItems(ctx context.Context, orderID uuid.UUID) ([]GetOr
 			&i.Quantity,
 			&i.Price,
 			&i.CreatedAt,
+			&i.ShopOrderID,
 			&i.ProductName,
 			&i.ImageUrl,
 		); err != nil {
@@ -188,6 +216,101 @@ func (q *Queries) GetOrdersByUser(ctx context.Context, userID uuid.UUID) ([]Orde
 	return items, nil
 }
 
+const listAllOrders = `-- name: ListAllOrders :many
+SELECT o.id, o.user_id, o.status, o.total_amount, o.shipping_address, o.payment_method, o.created_at, o.updated_at, u.username,
+  (SELECT COALESCE(SUM(oi.quantity), 0) FROM order_items oi WHERE oi.order_id = o.id)::int AS item_count
+FROM orders o
+JOIN users u ON u.id = o.user_id
+WHERE ($1::order_status IS NULL OR o.status = $1)
+  AND ($2::uuid IS NULL OR o.user_id = $2)
+  AND ($3::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM order_items oi
+    JOIN products p ON p.id = oi.product_id
+    WHERE oi.order_id = o.id AND p.shop_id = $3
+  ))
+  AND ($4::timestamp IS NULL OR o.created_at >= $4)
+  AND ($5::timestamp IS NULL OR o.created_at < $5)
+  AND ($6::numeric IS NULL OR o.total_amount >= $6)
+  AND ($7::numeric IS NULL OR o.total_amount <= $7)
+ORDER BY
+  CASE WHEN $8::text = 'created_at_asc' THEN o.created_at END ASC,
+  CASE WHEN $8::text = 'total_amount_asc' THEN o.total_amount END ASC,
+  CASE WHEN $8::text = 'total_amount_desc' THEN o.total_amount END DESC,
+  o.created_at DESC
+LIMIT $9 OFFSET $10
+`
+
+type ListAllOrdersRow struct {
+	ID              uuid.UUID    `json:"id"`
+	UserID          uuid.UUID    `json:"user_id"`
+	Status          OrderStatus  `json:"status"`
+	TotalAmount     money.Amount `json:"total_amount"`
+	ShippingAddress string       `json:"shipping_address"`
+	PaymentMethod   string       `json:"payment_method"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	Username        string       `json:"username"`
+	ItemCount       int32        `json:"item_count"`
+}
+
+type ListAllOrdersParams struct {
+	Status      NullOrderStatus  `json:"status"`
+	UserID      uuid.NullUUID    `json:"user_id"`
+	ShopID      uuid.NullUUID    `json:"shop_id"`
+	CreatedFrom sql.NullTime     `json:"created_from"`
+	CreatedTo   sql.NullTime     `json:"created_to"`
+	MinTotal    money.NullAmount `json:"min_total"`
+	MaxTotal    money.NullAmount `json:"max_total"`
+	SortBy      string           `json:"sort_by"`
+	Limit       int32            `json:"limit"`
+	Offset      int32            `json:"offset"`
+}
+
+func (q *Queries) ListAllOrders(ctx context.Context, arg ListAllOrdersParams) ([]ListAllOrdersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAllOrders,
+		arg.Status,
+		arg.UserID,
+		arg.ShopID,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.MinTotal,
+		arg.MaxTotal,
+		arg.SortBy,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAllOrdersRow{}
+	for rows.Next() {
+		var i ListAllOrdersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Status,
+			&i.TotalAmount,
+			&i.ShippingAddress,
+			&i.PaymentMethod,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Username,
+			&i.ItemCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateOrderStatus = `-- name: UpdateOrderStatus :one
 UPDATE orders
 SET status = $2, updated_at = NOW()
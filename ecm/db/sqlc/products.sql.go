@@ -10,6 +10,7 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/qhh/ecm/money"
 )
 
 const createProduct = `-- name: CreateProduct :one
@@ -21,7 +22,7 @@ RETURNING id, name, description, price, stock_quantity, shop_id, category_id, im
 type CreateProductParams struct {
 	Name          string         `json:"name"`
 	Description   sql.NullString `json:"description"`
-	Price         string         `json:"price"`
+	Price         money.Amount   `json:"price"`
 	StockQuantity int32          `json:"stock_quantity"`
 	ShopID        uuid.UUID      `json:"shop_id"`
 	CategoryID    uuid.UUID      `json:"category_id"`
@@ -54,6 +55,36 @@ func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (P
 	return i, err
 }
 
+const decreaseProductStock = `-- name: DecreaseProductStock :one
+UPDATE products
+SET stock_quantity = stock_quantity - $1, updated_at = NOW()
+WHERE id = $2 AND stock_quantity >= $1
+RETURNING id, name, description, price, stock_quantity, shop_id, category_id, image_url, created_at, updated_at
+`
+
+type DecreaseProductStockParams struct {
+	Quantity int32     `json:"quantity"`
+	ID       uuid.UUID `json:"id"`
+}
+
+func (q *Queries) DecreaseProductStock(ctx context.Context, arg DecreaseProductStockParams) (Product, error) {
+	row := q.db.QueryRowContext(ctx, decreaseProductStock, arg.Quantity, arg.ID)
+	var i Product
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.StockQuantity,
+		&i.ShopID,
+		&i.CategoryID,
+		&i.ImageUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const deleteProduct = `-- name: DeleteProduct :exec
 DELETE FROM products
 WHERE id = $1
@@ -284,7 +315,7 @@ type UpdateProductParams struct {
 	ID            uuid.UUID      `json:"id"`
 	Name          string         `json:"name"`
 	Description   sql.NullString `json:"description"`
-	Price         string         `json:"price"`
+	Price         money.Amount   `json:"price"`
 	StockQuantity int32          `json:"stock_quantity"`
 	CategoryID    uuid.UUID      `json:"category_id"`
 	ImageUrl      sql.NullString `json:"image_url"`
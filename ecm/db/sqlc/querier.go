@@ -6,48 +6,155 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/qhh/ecm/money"
 )
 
 type Querier interface {
+	AddPaymentRefund(ctx context.Context, arg AddPaymentRefundParams) (Payment, error)
+	AddShopOrderItemsToShipment(ctx context.Context, arg AddShopOrderItemsToShipmentParams) error
 	AddToCart(ctx context.Context, arg AddToCartParams) (CartItem, error)
+	BlockSession(ctx context.Context, arg BlockSessionParams) (Session, error)
+	BlockUserSessions(ctx context.Context, userID uuid.UUID) error
 	ClearCart(ctx context.Context, userID uuid.UUID) error
+	ClearLoginThrottle(ctx context.Context, throttleKey string) error
+	ConfirmTOTPCredential(ctx context.Context, userID uuid.UUID) (TotpCredential, error)
+	ConsumeEmailVerificationToken(ctx context.Context, tokenHash string) (EmailVerificationToken, error)
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	CountOrdersByStatus(ctx context.Context) ([]CountOrdersByStatusRow, error)
+	CountProducts(ctx context.Context) (int64, error)
+	CountShops(ctx context.Context) (int64, error)
+	CountUsersByRole(ctx context.Context) ([]CountUsersByRoleRow, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) error
 	CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error)
+	CreateEmailVerificationToken(ctx context.Context, arg CreateEmailVerificationTokenParams) (EmailVerificationToken, error)
 	CreateOrder(ctx context.Context, arg CreateOrderParams) (Order, error)
 	CreateOrderItem(ctx context.Context, arg CreateOrderItemParams) (OrderItem, error)
+	CreateOrderStatusChange(ctx context.Context, arg CreateOrderStatusChangeParams) error
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error)
+	CreatePaymentStatusChange(ctx context.Context, arg CreatePaymentStatusChangeParams) error
+	CreatePaymentWebhookEvent(ctx context.Context, arg CreatePaymentWebhookEventParams) error
 	CreateProduct(ctx context.Context, arg CreateProductParams) (Product, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateRefund(ctx context.Context, arg CreateRefundParams) (Refund, error)
+	CreateReturnRequest(ctx context.Context, arg CreateReturnRequestParams) (ReturnRequest, error)
+	CreateSellerApplication(ctx context.Context, arg CreateSellerApplicationParams) (SellerApplication, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateShipment(ctx context.Context, arg CreateShipmentParams) (Shipment, error)
 	CreateShop(ctx context.Context, arg CreateShopParams) (Shop, error)
+	CreateShopOrder(ctx context.Context, arg CreateShopOrderParams) (ShopOrder, error)
+	CreateTrackingEvent(ctx context.Context, arg CreateTrackingEventParams) (int64, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DecreaseProductStock(ctx context.Context, arg DecreaseProductStockParams) (Product, error)
 	DeleteCategory(ctx context.Context, id uuid.UUID) error
+	DeleteExpiredRevokedTokens(ctx context.Context) error
 	DeleteProduct(ctx context.Context, id uuid.UUID) error
+	DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error
 	DeleteShop(ctx context.Context, id uuid.UUID) error
+	DeleteStaleLoginThrottles(ctx context.Context, lastFailedAt time.Time) error
+	DeleteTOTPCredential(ctx context.Context, userID uuid.UUID) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	ForgiveLoginAttempt(ctx context.Context, arg ForgiveLoginAttemptParams) error
 	GetCartItems(ctx context.Context, userID uuid.UUID) ([]GetCartItemsRow, error)
 	GetCategory(ctx context.Context, id uuid.UUID) (Category, error)
+	GetLatestSellerApplicationByUser(ctx context.Context, userID uuid.UUID) (SellerApplication, error)
+	GetLoginThrottle(ctx context.Context, throttleKey string) (LoginThrottle, error)
 	GetOrder(ctx context.Context, id uuid.UUID) (Order, error)
+	GetOrderForUpdate(ctx context.Context, id uuid.UUID) (Order, error)
+	GetOrderItemForReturn(ctx context.Context, id uuid.UUID) (GetOrderItemForReturnRow, error)
 	GetOrderItems(ctx context.Context, orderID uuid.UUID) ([]GetOrderItemsRow, error)
 	GetOrdersByUser(ctx context.Context, userID uuid.UUID) ([]Order, error)
+	GetPaymentByOrder(ctx context.Context, orderID uuid.UUID) (Payment, error)
+	GetPaymentByOrderForUpdate(ctx context.Context, orderID uuid.UUID) (Payment, error)
+	GetPaymentByProviderPaymentID(ctx context.Context, arg GetPaymentByProviderPaymentIDParams) (Payment, error)
+	GetPaymentWebhookEventForUpdate(ctx context.Context, arg GetPaymentWebhookEventForUpdateParams) (PaymentWebhookEvent, error)
 	GetProduct(ctx context.Context, id uuid.UUID) (Product, error)
+	GetRefundByReturnRequest(ctx context.Context, returnRequestID uuid.NullUUID) (Refund, error)
+	GetRefundForUpdate(ctx context.Context, id uuid.UUID) (Refund, error)
+	GetReturnRequest(ctx context.Context, id uuid.UUID) (ReturnRequest, error)
+	GetReturnRequestForUpdate(ctx context.Context, id uuid.UUID) (ReturnRequest, error)
+	GetReturnedQuantity(ctx context.Context, orderItemID uuid.UUID) (int32, error)
+	GetRevenueByPeriod(ctx context.Context, arg GetRevenueByPeriodParams) ([]GetRevenueByPeriodRow, error)
+	GetSellerApplication(ctx context.Context, id uuid.UUID) (SellerApplication, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetShop(ctx context.Context, id uuid.UUID) (Shop, error)
+	GetShopOrder(ctx context.Context, id uuid.UUID) (ShopOrder, error)
+	GetShopOrderForUpdate(ctx context.Context, id uuid.UUID) (ShopOrder, error)
+	GetShopOrderItems(ctx context.Context, shopOrderID uuid.UUID) ([]GetShopOrderItemsRow, error)
+	GetTOTPCredential(ctx context.Context, userID uuid.UUID) (TotpCredential, error)
+	GetUnsettledRefundAmount(ctx context.Context, paymentID uuid.NullUUID) (money.Amount, error)
 	GetUser(ctx context.Context, id uuid.UUID) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
+	GetUserTokenStatus(ctx context.Context, id uuid.UUID) (GetUserTokenStatusRow, error)
+	InvalidateEmailVerificationTokens(ctx context.Context, userID uuid.UUID) error
+	InvalidatePasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+	IsTokenRevoked(ctx context.Context, id uuid.UUID) (bool, error)
+	ListActiveAdminIDsForUpdate(ctx context.Context) ([]uuid.UUID, error)
+	ListActiveSessionsByUser(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	ListAllOrders(ctx context.Context, arg ListAllOrdersParams) ([]ListAllOrdersRow, error)
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error)
 	ListCategories(ctx context.Context) ([]Category, error)
+	ListExpiredUnpaidOrderIDs(ctx context.Context, arg ListExpiredUnpaidOrderIDsParams) ([]uuid.UUID, error)
+	ListOrderStatusHistory(ctx context.Context, orderID uuid.UUID) ([]ListOrderStatusHistoryRow, error)
+	ListPaymentStatusHistory(ctx context.Context, paymentID uuid.UUID) ([]PaymentStatusHistory, error)
+	ListPaymentWebhookEvents(ctx context.Context, arg ListPaymentWebhookEventsParams) ([]PaymentWebhookEvent, error)
+	ListPendingRefundIDs(ctx context.Context, paymentID uuid.NullUUID) ([]uuid.UUID, error)
 	ListProducts(ctx context.Context, arg ListProductsParams) ([]Product, error)
 	ListProductsByCategory(ctx context.Context, arg ListProductsByCategoryParams) ([]Product, error)
 	ListProductsByShop(ctx context.Context, shopID uuid.UUID) ([]Product, error)
+	ListReturnRequestsByOrder(ctx context.Context, orderID uuid.UUID) ([]ListReturnRequestsByOrderRow, error)
+	ListReturnRequestsByShop(ctx context.Context, arg ListReturnRequestsByShopParams) ([]ListReturnRequestsByShopRow, error)
+	ListRolePermissions(ctx context.Context, role UserRole) ([]string, error)
+	ListSellerApplications(ctx context.Context, arg ListSellerApplicationsParams) ([]SellerApplication, error)
+	ListShipmentItemsByOrder(ctx context.Context, orderID uuid.UUID) ([]ListShipmentItemsByOrderRow, error)
+	ListShipmentsByOrder(ctx context.Context, orderID uuid.UUID) ([]Shipment, error)
+	ListShipmentsByTrackingNumberForUpdate(ctx context.Context, arg ListShipmentsByTrackingNumberForUpdateParams) ([]Shipment, error)
+	ListShopOrderItemsByShopOrders(ctx context.Context, shopOrderIds []uuid.UUID) ([]ListShopOrderItemsByShopOrdersRow, error)
+	ListShopOrders(ctx context.Context, arg ListShopOrdersParams) ([]ListShopOrdersRow, error)
+	ListShopOrdersByOrder(ctx context.Context, orderID uuid.UUID) ([]ListShopOrdersByOrderRow, error)
+	ListShopOrdersByOrderForUpdate(ctx context.Context, orderID uuid.UUID) ([]ShopOrder, error)
 	ListShops(ctx context.Context, arg ListShopsParams) ([]Shop, error)
 	ListShopsByOwner(ctx context.Context, ownerID uuid.UUID) ([]Shop, error)
+	ListTopProducts(ctx context.Context, arg ListTopProductsParams) ([]ListTopProductsRow, error)
+	ListTopShops(ctx context.Context, arg ListTopShopsParams) ([]ListTopShopsRow, error)
+	ListTrackingEventsByOrder(ctx context.Context, orderID uuid.UUID) ([]TrackingEvent, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	MarkPaymentWebhookEventProcessed(ctx context.Context, arg MarkPaymentWebhookEventProcessedParams) error
+	MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error)
+	PromoteBuyerToSeller(ctx context.Context, id uuid.UUID) (User, error)
+	RecordLoginAttempt(ctx context.Context, arg RecordLoginAttemptParams) (LoginThrottle, error)
+	RecordPaymentWebhookEventError(ctx context.Context, arg RecordPaymentWebhookEventErrorParams) error
+	ReducePaymentAmount(ctx context.Context, arg ReducePaymentAmountParams) (Payment, error)
+	RefreshShipmentStatus(ctx context.Context, id uuid.UUID) (Shipment, error)
 	RemoveFromCart(ctx context.Context, arg RemoveFromCartParams) error
+	RetryRefund(ctx context.Context, id uuid.UUID) (Refund, error)
+	ReviewSellerApplication(ctx context.Context, arg ReviewSellerApplicationParams) (SellerApplication, error)
+	RevokeToken(ctx context.Context, arg RevokeTokenParams) error
+	RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) error
 	SearchProducts(ctx context.Context, arg SearchProductsParams) ([]Product, error)
+	SuspendUser(ctx context.Context, id uuid.UUID) (User, error)
+	TouchSession(ctx context.Context, id uuid.UUID) error
+	UnsuspendUser(ctx context.Context, id uuid.UUID) (User, error)
 	UpdateCartQuantity(ctx context.Context, arg UpdateCartQuantityParams) (CartItem, error)
 	UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Category, error)
 	UpdateOrderStatus(ctx context.Context, arg UpdateOrderStatusParams) (Order, error)
+	UpdatePayment(ctx context.Context, arg UpdatePaymentParams) (Payment, error)
 	UpdateProduct(ctx context.Context, arg UpdateProductParams) (Product, error)
 	UpdateProductStock(ctx context.Context, arg UpdateProductStockParams) (Product, error)
+	UpdateRefund(ctx context.Context, arg UpdateRefundParams) (Refund, error)
+	UpdateReturnRequestStatus(ctx context.Context, arg UpdateReturnRequestStatusParams) (ReturnRequest, error)
 	UpdateShop(ctx context.Context, arg UpdateShopParams) (Shop, error)
+	UpdateShopOrderStatus(ctx context.Context, arg UpdateShopOrderStatusParams) (ShopOrder, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
 	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateUserUsername(ctx context.Context, arg UpdateUserUsernameParams) (User, error)
+	UpsertPendingTOTPCredential(ctx context.Context, arg UpsertPendingTOTPCredentialParams) (TotpCredential, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/qhh/ecm/money"
 )
 
 // Store provides all functions to execute db queries and transactions
@@ -14,7 +15,56 @@ type Store interface {
 	CreateOrderWithTx(ctx context.Context, tx *sql.Tx, arg CreateOrderParams) (Order, error)
 	CreateOrderItemWithTx(ctx context.Context, tx *sql.Tx, arg CreateOrderItemParams) (OrderItem, error)
 	UpdateProductStockWithTx(ctx context.Context, tx *sql.Tx, arg UpdateProductStockParams) (Product, error)
+	DecreaseProductStockWithTx(ctx context.Context, tx *sql.Tx, arg DecreaseProductStockParams) (Product, error)
 	ClearCartWithTx(ctx context.Context, tx *sql.Tx, userID interface{}) error
+	ConsumePasswordResetTokenWithTx(ctx context.Context, tx *sql.Tx, tokenHash string) (PasswordResetToken, error)
+	InvalidatePasswordResetTokensWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error
+	UpdateUserPasswordWithTx(ctx context.Context, tx *sql.Tx, arg UpdateUserPasswordParams) (User, error)
+	ConsumeEmailVerificationTokenWithTx(ctx context.Context, tx *sql.Tx, tokenHash string) (EmailVerificationToken, error)
+	InvalidateEmailVerificationTokensWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error
+	MarkUserEmailVerifiedWithTx(ctx context.Context, tx *sql.Tx, arg MarkUserEmailVerifiedParams) (User, error)
+	ConfirmTOTPCredentialWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (TotpCredential, error)
+	DeleteTOTPCredentialWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error
+	CreateRecoveryCodeWithTx(ctx context.Context, tx *sql.Tx, arg CreateRecoveryCodeParams) error
+	DeleteRecoveryCodesWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error
+	ReviewSellerApplicationWithTx(ctx context.Context, tx *sql.Tx, arg ReviewSellerApplicationParams) (SellerApplication, error)
+	UpdateUserRoleWithTx(ctx context.Context, tx *sql.Tx, arg UpdateUserRoleParams) (User, error)
+	ListActiveAdminIDsForUpdateWithTx(ctx context.Context, tx *sql.Tx) ([]uuid.UUID, error)
+	SuspendUserWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (User, error)
+	DeleteUserWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) error
+	GetOrderForUpdateWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (Order, error)
+	UpdateOrderStatusWithTx(ctx context.Context, tx *sql.Tx, arg UpdateOrderStatusParams) (Order, error)
+	CreateOrderStatusChangeWithTx(ctx context.Context, tx *sql.Tx, arg CreateOrderStatusChangeParams) error
+	CreateShopOrderWithTx(ctx context.Context, tx *sql.Tx, arg CreateShopOrderParams) (ShopOrder, error)
+	GetShopOrderForUpdateWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (ShopOrder, error)
+	ListShopOrdersByOrderForUpdateWithTx(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) ([]ShopOrder, error)
+	UpdateShopOrderStatusWithTx(ctx context.Context, tx *sql.Tx, arg UpdateShopOrderStatusParams) (ShopOrder, error)
+	GetShopOrderItemsWithTx(ctx context.Context, tx *sql.Tx, shopOrderID uuid.UUID) ([]GetShopOrderItemsRow, error)
+	CreateShipmentWithTx(ctx context.Context, tx *sql.Tx, arg CreateShipmentParams) (Shipment, error)
+	AddShopOrderItemsToShipmentWithTx(ctx context.Context, tx *sql.Tx, arg AddShopOrderItemsToShipmentParams) error
+	ListShipmentsByTrackingNumberForUpdateWithTx(ctx context.Context, tx *sql.Tx, arg ListShipmentsByTrackingNumberForUpdateParams) ([]Shipment, error)
+	CreateTrackingEventWithTx(ctx context.Context, tx *sql.Tx, arg CreateTrackingEventParams) (int64, error)
+	RefreshShipmentStatusWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (Shipment, error)
+	GetReturnedQuantityWithTx(ctx context.Context, tx *sql.Tx, orderItemID uuid.UUID) (int32, error)
+	CreateReturnRequestWithTx(ctx context.Context, tx *sql.Tx, arg CreateReturnRequestParams) (ReturnRequest, error)
+	GetReturnRequestForUpdateWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (ReturnRequest, error)
+	UpdateReturnRequestStatusWithTx(ctx context.Context, tx *sql.Tx, arg UpdateReturnRequestStatusParams) (ReturnRequest, error)
+	CreateRefundWithTx(ctx context.Context, tx *sql.Tx, arg CreateRefundParams) (Refund, error)
+	CreatePaymentWithTx(ctx context.Context, tx *sql.Tx, arg CreatePaymentParams) (Payment, error)
+	GetPaymentByOrderForUpdateWithTx(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) (Payment, error)
+	UpdatePaymentWithTx(ctx context.Context, tx *sql.Tx, arg UpdatePaymentParams) (Payment, error)
+	ReducePaymentAmountWithTx(ctx context.Context, tx *sql.Tx, arg ReducePaymentAmountParams) (Payment, error)
+	AddPaymentRefundWithTx(ctx context.Context, tx *sql.Tx, arg AddPaymentRefundParams) (Payment, error)
+	CreatePaymentStatusChangeWithTx(ctx context.Context, tx *sql.Tx, arg CreatePaymentStatusChangeParams) error
+	GetPaymentWebhookEventForUpdateWithTx(ctx context.Context, tx *sql.Tx, arg GetPaymentWebhookEventForUpdateParams) (PaymentWebhookEvent, error)
+	MarkPaymentWebhookEventProcessedWithTx(ctx context.Context, tx *sql.Tx, arg MarkPaymentWebhookEventProcessedParams) error
+	RevokeUserTokensWithTx(ctx context.Context, tx *sql.Tx, arg RevokeUserTokensParams) error
+	BlockUserSessionsWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error
+	GetRefundForUpdateWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (Refund, error)
+	GetUnsettledRefundAmountWithTx(ctx context.Context, tx *sql.Tx, paymentID uuid.NullUUID) (money.Amount, error)
+	UpdateRefundWithTx(ctx context.Context, tx *sql.Tx, arg UpdateRefundParams) (Refund, error)
+	RetryRefundWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (Refund, error)
+	PromoteBuyerToSellerWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (User, error)
 }
 
 // SQLStore provides all functions to execute SQL queries and transactions
@@ -54,8 +104,302 @@ func (store *SQLStore) UpdateProductStockWithTx(ctx context.Context, tx *sql.Tx,
 	return q.UpdateProductStock(ctx, arg)
 }
 
+// DecreaseProductStockWithTx takes stock from a product with transaction, as long as enough is left
+func (store *SQLStore) DecreaseProductStockWithTx(ctx context.Context, tx *sql.Tx, arg DecreaseProductStockParams) (Product, error) {
+	q := New(tx)
+	return q.DecreaseProductStock(ctx, arg)
+}
+
 // ClearCartWithTx clears cart with transaction
 func (store *SQLStore) ClearCartWithTx(ctx context.Context, tx *sql.Tx, userID interface{}) error {
 	q := New(tx)
 	return q.ClearCart(ctx, userID.(uuid.UUID))
 }
+
+// ConsumePasswordResetTokenWithTx marks a reset token as used with transaction
+func (store *SQLStore) ConsumePasswordResetTokenWithTx(ctx context.Context, tx *sql.Tx, tokenHash string) (PasswordResetToken, error) {
+	q := New(tx)
+	return q.ConsumePasswordResetToken(ctx, tokenHash)
+}
+
+// InvalidatePasswordResetTokensWithTx invalidates a user's outstanding reset tokens with transaction
+func (store *SQLStore) InvalidatePasswordResetTokensWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	q := New(tx)
+	return q.InvalidatePasswordResetTokens(ctx, userID)
+}
+
+// UpdateUserPasswordWithTx updates a user's password with transaction
+func (store *SQLStore) UpdateUserPasswordWithTx(ctx context.Context, tx *sql.Tx, arg UpdateUserPasswordParams) (User, error) {
+	q := New(tx)
+	return q.UpdateUserPassword(ctx, arg)
+}
+
+// ConsumeEmailVerificationTokenWithTx marks a verification token as used with transaction
+func (store *SQLStore) ConsumeEmailVerificationTokenWithTx(ctx context.Context, tx *sql.Tx, tokenHash string) (EmailVerificationToken, error) {
+	q := New(tx)
+	return q.ConsumeEmailVerificationToken(ctx, tokenHash)
+}
+
+// InvalidateEmailVerificationTokensWithTx invalidates a user's outstanding verification tokens with transaction
+func (store *SQLStore) InvalidateEmailVerificationTokensWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	q := New(tx)
+	return q.InvalidateEmailVerificationTokens(ctx, userID)
+}
+
+// MarkUserEmailVerifiedWithTx marks a user's email as verified with transaction
+func (store *SQLStore) MarkUserEmailVerifiedWithTx(ctx context.Context, tx *sql.Tx, arg MarkUserEmailVerifiedParams) (User, error) {
+	q := New(tx)
+	return q.MarkUserEmailVerified(ctx, arg)
+}
+
+// ConfirmTOTPCredentialWithTx confirms a pending TOTP enrollment with transaction
+func (store *SQLStore) ConfirmTOTPCredentialWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (TotpCredential, error) {
+	q := New(tx)
+	return q.ConfirmTOTPCredential(ctx, userID)
+}
+
+// DeleteTOTPCredentialWithTx removes a user's TOTP credential with transaction
+func (store *SQLStore) DeleteTOTPCredentialWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	q := New(tx)
+	return q.DeleteTOTPCredential(ctx, userID)
+}
+
+// CreateRecoveryCodeWithTx stores a hashed recovery code with transaction
+func (store *SQLStore) CreateRecoveryCodeWithTx(ctx context.Context, tx *sql.Tx, arg CreateRecoveryCodeParams) error {
+	q := New(tx)
+	return q.CreateRecoveryCode(ctx, arg)
+}
+
+// DeleteRecoveryCodesWithTx removes all of a user's recovery codes with transaction
+func (store *SQLStore) DeleteRecoveryCodesWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	q := New(tx)
+	return q.DeleteRecoveryCodes(ctx, userID)
+}
+
+// ReviewSellerApplicationWithTx records the decision on a pending seller application with transaction
+func (store *SQLStore) ReviewSellerApplicationWithTx(ctx context.Context, tx *sql.Tx, arg ReviewSellerApplicationParams) (SellerApplication, error) {
+	q := New(tx)
+	return q.ReviewSellerApplication(ctx, arg)
+}
+
+// UpdateUserRoleWithTx changes a user's role with transaction
+func (store *SQLStore) UpdateUserRoleWithTx(ctx context.Context, tx *sql.Tx, arg UpdateUserRoleParams) (User, error) {
+	q := New(tx)
+	return q.UpdateUserRole(ctx, arg)
+}
+
+// ListActiveAdminIDsForUpdateWithTx locks the active admins until the transaction ends
+func (store *SQLStore) ListActiveAdminIDsForUpdateWithTx(ctx context.Context, tx *sql.Tx) ([]uuid.UUID, error) {
+	q := New(tx)
+	return q.ListActiveAdminIDsForUpdate(ctx)
+}
+
+// SuspendUserWithTx suspends a user's account with transaction
+func (store *SQLStore) SuspendUserWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (User, error) {
+	q := New(tx)
+	return q.SuspendUser(ctx, id)
+}
+
+// DeleteUserWithTx deletes a user with transaction
+func (store *SQLStore) DeleteUserWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) error {
+	q := New(tx)
+	return q.DeleteUser(ctx, id)
+}
+
+// GetOrderForUpdateWithTx gets an order and locks it until the transaction ends
+func (store *SQLStore) GetOrderForUpdateWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (Order, error) {
+	q := New(tx)
+	return q.GetOrderForUpdate(ctx, id)
+}
+
+// UpdateOrderStatusWithTx updates an order's status with transaction
+func (store *SQLStore) UpdateOrderStatusWithTx(ctx context.Context, tx *sql.Tx, arg UpdateOrderStatusParams) (Order, error) {
+	q := New(tx)
+	return q.UpdateOrderStatus(ctx, arg)
+}
+
+// CreateOrderStatusChangeWithTx adds a status change to an order's history with transaction
+func (store *SQLStore) CreateOrderStatusChangeWithTx(ctx context.Context, tx *sql.Tx, arg CreateOrderStatusChangeParams) error {
+	q := New(tx)
+	return q.CreateOrderStatusChange(ctx, arg)
+}
+
+// CreateShopOrderWithTx creates a shop order with transaction
+func (store *SQLStore) CreateShopOrderWithTx(ctx context.Context, tx *sql.Tx, arg CreateShopOrderParams) (ShopOrder, error) {
+	q := New(tx)
+	return q.CreateShopOrder(ctx, arg)
+}
+
+// GetShopOrderForUpdateWithTx gets a shop order and locks it until the transaction ends
+func (store *SQLStore) GetShopOrderForUpdateWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (ShopOrder, error) {
+	q := New(tx)
+	return q.GetShopOrderForUpdate(ctx, id)
+}
+
+// ListShopOrdersByOrderForUpdateWithTx lists the shop orders of an order and locks them until the transaction ends
+func (store *SQLStore) ListShopOrdersByOrderForUpdateWithTx(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) ([]ShopOrder, error) {
+	q := New(tx)
+	return q.ListShopOrdersByOrderForUpdate(ctx, orderID)
+}
+
+// UpdateShopOrderStatusWithTx updates a shop order's status with transaction
+func (store *SQLStore) UpdateShopOrderStatusWithTx(ctx context.Context, tx *sql.Tx, arg UpdateShopOrderStatusParams) (ShopOrder, error) {
+	q := New(tx)
+	return q.UpdateShopOrderStatus(ctx, arg)
+}
+
+// GetShopOrderItemsWithTx gets the items of a shop order with transaction
+func (store *SQLStore) GetShopOrderItemsWithTx(ctx context.Context, tx *sql.Tx, shopOrderID uuid.UUID) ([]GetShopOrderItemsRow, error) {
+	q := New(tx)
+	return q.GetShopOrderItems(ctx, shopOrderID)
+}
+
+// CreateShipmentWithTx creates a shipment with transaction
+func (store *SQLStore) CreateShipmentWithTx(ctx context.Context, tx *sql.Tx, arg CreateShipmentParams) (Shipment, error) {
+	q := New(tx)
+	return q.CreateShipment(ctx, arg)
+}
+
+// AddShopOrderItemsToShipmentWithTx adds all items of a shop order to a shipment with transaction
+func (store *SQLStore) AddShopOrderItemsToShipmentWithTx(ctx context.Context, tx *sql.Tx, arg AddShopOrderItemsToShipmentParams) error {
+	q := New(tx)
+	return q.AddShopOrderItemsToShipment(ctx, arg)
+}
+
+// ListShipmentsByTrackingNumberForUpdateWithTx lists the shipments with a tracking number and locks them until the transaction ends
+func (store *SQLStore) ListShipmentsByTrackingNumberForUpdateWithTx(ctx context.Context, tx *sql.Tx, arg ListShipmentsByTrackingNumberForUpdateParams) ([]Shipment, error) {
+	q := New(tx)
+	return q.ListShipmentsByTrackingNumberForUpdate(ctx, arg)
+}
+
+// CreateTrackingEventWithTx records a tracking event with transaction, returning 0 if it was already recorded
+func (store *SQLStore) CreateTrackingEventWithTx(ctx context.Context, tx *sql.Tx, arg CreateTrackingEventParams) (int64, error) {
+	q := New(tx)
+	return q.CreateTrackingEvent(ctx, arg)
+}
+
+// RefreshShipmentStatusWithTx updates a shipment's status from its tracking events with transaction
+func (store *SQLStore) RefreshShipmentStatusWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (Shipment, error) {
+	q := New(tx)
+	return q.RefreshShipmentStatus(ctx, id)
+}
+
+// GetReturnedQuantityWithTx gets how much of an order item is being or has been returned, with transaction
+func (store *SQLStore) GetReturnedQuantityWithTx(ctx context.Context, tx *sql.Tx, orderItemID uuid.UUID) (int32, error) {
+	q := New(tx)
+	return q.GetReturnedQuantity(ctx, orderItemID)
+}
+
+// CreateReturnRequestWithTx creates a return request with transaction
+func (store *SQLStore) CreateReturnRequestWithTx(ctx context.Context, tx *sql.Tx, arg CreateReturnRequestParams) (ReturnRequest, error) {
+	q := New(tx)
+	return q.CreateReturnRequest(ctx, arg)
+}
+
+// GetReturnRequestForUpdateWithTx gets a return request and locks it until the transaction ends
+func (store *SQLStore) GetReturnRequestForUpdateWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (ReturnRequest, error) {
+	q := New(tx)
+	return q.GetReturnRequestForUpdate(ctx, id)
+}
+
+// UpdateReturnRequestStatusWithTx updates a return request's status with transaction
+func (store *SQLStore) UpdateReturnRequestStatusWithTx(ctx context.Context, tx *sql.Tx, arg UpdateReturnRequestStatusParams) (ReturnRequest, error) {
+	q := New(tx)
+	return q.UpdateReturnRequestStatus(ctx, arg)
+}
+
+// CreateRefundWithTx creates a refund with transaction
+func (store *SQLStore) CreateRefundWithTx(ctx context.Context, tx *sql.Tx, arg CreateRefundParams) (Refund, error) {
+	q := New(tx)
+	return q.CreateRefund(ctx, arg)
+}
+
+// CreatePaymentWithTx creates an order's payment with transaction
+func (store *SQLStore) CreatePaymentWithTx(ctx context.Context, tx *sql.Tx, arg CreatePaymentParams) (Payment, error) {
+	q := New(tx)
+	return q.CreatePayment(ctx, arg)
+}
+
+// GetPaymentByOrderForUpdateWithTx gets an order's payment and locks it until the transaction ends
+func (store *SQLStore) GetPaymentByOrderForUpdateWithTx(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) (Payment, error) {
+	q := New(tx)
+	return q.GetPaymentByOrderForUpdate(ctx, orderID)
+}
+
+// UpdatePaymentWithTx records the outcome of a payment attempt with transaction
+func (store *SQLStore) UpdatePaymentWithTx(ctx context.Context, tx *sql.Tx, arg UpdatePaymentParams) (Payment, error) {
+	q := New(tx)
+	return q.UpdatePayment(ctx, arg)
+}
+
+// ReducePaymentAmountWithTx lowers the amount still to be charged for a payment with transaction
+func (store *SQLStore) ReducePaymentAmountWithTx(ctx context.Context, tx *sql.Tx, arg ReducePaymentAmountParams) (Payment, error) {
+	q := New(tx)
+	return q.ReducePaymentAmount(ctx, arg)
+}
+
+// AddPaymentRefundWithTx adds a refunded amount to a payment with transaction
+func (store *SQLStore) AddPaymentRefundWithTx(ctx context.Context, tx *sql.Tx, arg AddPaymentRefundParams) (Payment, error) {
+	q := New(tx)
+	return q.AddPaymentRefund(ctx, arg)
+}
+
+// CreatePaymentStatusChangeWithTx adds a change to a payment's status history with transaction
+func (store *SQLStore) CreatePaymentStatusChangeWithTx(ctx context.Context, tx *sql.Tx, arg CreatePaymentStatusChangeParams) error {
+	q := New(tx)
+	return q.CreatePaymentStatusChange(ctx, arg)
+}
+
+// GetPaymentWebhookEventForUpdateWithTx gets a received webhook event and locks it until the transaction ends
+func (store *SQLStore) GetPaymentWebhookEventForUpdateWithTx(ctx context.Context, tx *sql.Tx, arg GetPaymentWebhookEventForUpdateParams) (PaymentWebhookEvent, error) {
+	q := New(tx)
+	return q.GetPaymentWebhookEventForUpdate(ctx, arg)
+}
+
+// MarkPaymentWebhookEventProcessedWithTx marks a webhook event as processed with transaction
+func (store *SQLStore) MarkPaymentWebhookEventProcessedWithTx(ctx context.Context, tx *sql.Tx, arg MarkPaymentWebhookEventProcessedParams) error {
+	q := New(tx)
+	return q.MarkPaymentWebhookEventProcessed(ctx, arg)
+}
+
+// RevokeUserTokensWithTx revokes every token issued to a user before a time with transaction
+func (store *SQLStore) RevokeUserTokensWithTx(ctx context.Context, tx *sql.Tx, arg RevokeUserTokensParams) error {
+	q := New(tx)
+	return q.RevokeUserTokens(ctx, arg)
+}
+
+// BlockUserSessionsWithTx blocks all of a user's sessions with transaction
+func (store *SQLStore) BlockUserSessionsWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	q := New(tx)
+	return q.BlockUserSessions(ctx, userID)
+}
+
+// GetRefundForUpdateWithTx locks a refund within a transaction, for it to be settled
+func (store *SQLStore) GetRefundForUpdateWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (Refund, error) {
+	q := New(tx)
+	return q.GetRefundForUpdate(ctx, id)
+}
+
+// GetUnsettledRefundAmountWithTx sums the refunds of a payment that have not been paid back, within a transaction
+func (store *SQLStore) GetUnsettledRefundAmountWithTx(ctx context.Context, tx *sql.Tx, paymentID uuid.NullUUID) (money.Amount, error) {
+	q := New(tx)
+	return q.GetUnsettledRefundAmount(ctx, paymentID)
+}
+
+// UpdateRefundWithTx records the outcome of a refund within a transaction
+func (store *SQLStore) UpdateRefundWithTx(ctx context.Context, tx *sql.Tx, arg UpdateRefundParams) (Refund, error) {
+	q := New(tx)
+	return q.UpdateRefund(ctx, arg)
+}
+
+// RetryRefundWithTx puts a failed refund back to pending under a new attempt, within a transaction
+func (store *SQLStore) RetryRefundWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (Refund, error) {
+	q := New(tx)
+	return q.RetryRefund(ctx, id)
+}
+
+// PromoteBuyerToSellerWithTx makes a buyer a seller within a transaction, and returns sql.ErrNoRows if the user is no longer a buyer
+func (store *SQLStore) PromoteBuyerToSellerWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (User, error) {
+	q := New(tx)
+	return q.PromoteBuyerToSeller(ctx, id)
+}
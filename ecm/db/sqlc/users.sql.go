@@ -7,6 +7,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
@@ -14,7 +15,7 @@ import (
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, email, password_hash, role) 
 VALUES ($1, $2, $3, $4)
-RETURNING id, username, email, password_hash, role, created_at, updated_at
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at
 `
 
 type CreateUserParams struct {
@@ -40,12 +41,24 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.SuspendedAt,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
 const getUser = `-- name: GetUser :one
-SELECT id, username, email, password_hash, role, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at FROM users
 WHERE id = $1
 `
 
@@ -60,12 +73,14 @@ func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.SuspendedAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, password_hash, role, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at FROM users
 WHERE email = $1
 `
 
@@ -80,12 +95,14 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.SuspendedAt,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, email, password_hash, role, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at FROM users
 WHERE username = $1
 `
 
@@ -100,23 +117,68 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.SuspendedAt,
 	)
 	return i, err
 }
 
+const listActiveAdminIDsForUpdate = `-- name: ListActiveAdminIDsForUpdate :many
+SELECT id FROM users
+WHERE role = 'admin' AND suspended_at IS NULL
+ORDER BY id
+FOR UPDATE
+`
+
+func (q *Queries) ListActiveAdminIDsForUpdate(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveAdminIDsForUpdate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUsers = `-- name: ListUsers :many
-SELECT id, username, email, password_hash, role, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at FROM users
+WHERE (
+  $1::text IS NULL
+  OR username ILIKE '%' || $1 || '%'
+  OR email ILIKE '%' || $1 || '%'
+)
+AND ($2::user_role IS NULL OR role = $2)
 ORDER BY created_at
-LIMIT $1 OFFSET $2
+LIMIT $3 OFFSET $4
 `
 
 type ListUsersParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	Search sql.NullString `json:"search"`
+	Role   NullUserRole   `json:"role"`
+	Limit  int32          `json:"limit"`
+	Offset int32          `json:"offset"`
 }
 
 func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, listUsers, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, listUsers,
+		arg.Search,
+		arg.Role,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -132,6 +194,8 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 			&i.Role,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.EmailVerifiedAt,
+			&i.SuspendedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -146,11 +210,141 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 	return items, nil
 }
 
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :one
+UPDATE users
+SET email = $2, email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at
+`
+
+type MarkUserEmailVerifiedParams struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, markUserEmailVerified, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.SuspendedAt,
+	)
+	return i, err
+}
+
+const promoteBuyerToSeller = `-- name: PromoteBuyerToSeller :one
+UPDATE users
+SET role = 'seller', updated_at = NOW()
+WHERE id = $1 AND role = 'buyer'
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at
+`
+
+func (q *Queries) PromoteBuyerToSeller(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, promoteBuyerToSeller, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.SuspendedAt,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at
+`
+
+func (q *Queries) SuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.SuspendedAt,
+	)
+	return i, err
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, unsuspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.SuspendedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at
+`
+
+type UpdateUserPasswordParams struct {
+	ID           uuid.UUID `json:"id"`
+	PasswordHash string    `json:"password_hash"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.SuspendedAt,
+	)
+	return i, err
+}
+
 const updateUserRole = `-- name: UpdateUserRole :one
 UPDATE users
 SET role = $2, updated_at = NOW()
 WHERE id = $1
-RETURNING id, username, email, password_hash, role, created_at, updated_at
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at
 `
 
 type UpdateUserRoleParams struct {
@@ -169,6 +363,37 @@ func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams)
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.SuspendedAt,
+	)
+	return i, err
+}
+
+const updateUserUsername = `-- name: UpdateUserUsername :one
+UPDATE users
+SET username = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, suspended_at
+`
+
+type UpdateUserUsernameParams struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+}
+
+func (q *Queries) UpdateUserUsername(ctx context.Context, arg UpdateUserUsernameParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserUsername, arg.ID, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.SuspendedAt,
 	)
 	return i, err
 }
@@ -16,6 +16,7 @@ import UserOrders from './pages/UserOrders';
 import OrderDetails from './pages/OrderDetails';
 import ShopManagement from './pages/seller/ShopManagement';
 import ProductManagement from './pages/seller/ProductManagement';
+import ShopOrders from './pages/seller/ShopOrders';
 import AdminDashboard from './pages/admin/AdminDashboard';
 import CategoryManagement from './pages/admin/CategoryManagement';
 import ShopManagementAdmin from './pages/admin/ShopManagement';
@@ -82,6 +83,15 @@ const App: React.FC = () => {
                                 />
                             }
                         />
+                        <Route
+                            path="/seller/orders"
+                            element={
+                                <PrivateRoute
+                                    element={<ShopOrders />}
+                                    requiredRole="seller"
+                                />
+                            }
+                        />
 
                         {/* Admin routes */}
                         <Route
@@ -160,6 +160,11 @@ const Header: React.FC = () => {
                                     My Shops
                                 </MenuItem>
                             )}
+                            {(user?.role === 'seller' || user?.role === 'admin') && (
+                                <MenuItem onClick={() => { handleMenuClose(); navigate('/seller/orders'); }}>
+                                    Shop Orders
+                                </MenuItem>
+                            )}
 
                             {user?.role === 'admin' && (
                                 <MenuItem onClick={() => { handleMenuClose(); navigate('/admin'); }}>
@@ -1,4 +1,4 @@
-import React, { createContext, useContext, useState, useEffect } from 'react';
+import React, { createContext, useContext, useState, useEffect, useCallback } from 'react';
 import axios from 'axios';
 import { API_URL } from '../config/constants';
 
@@ -9,15 +9,22 @@ interface User {
     role: string;
 }
 
+export interface MFAChallenge {
+    mfaToken: string;
+    enrollmentRequired: boolean;
+}
+
 interface AuthContextType {
     user: User | null;
     isAuthenticated: boolean;
     isLoading: boolean;
     token: string | null;
-    login: (username: string, password: string) => Promise<void>;
+    login: (username: string, password: string) => Promise<MFAChallenge | null>;
+    completeMFALogin: (mfaToken: string, code: string) => Promise<string[]>;
     register: (username: string, email: string, password: string) => Promise<void>;
     logout: () => void;
-    updateRole: (role: string) => Promise<void>;
+    updateProfile: (changes: { username?: string; email?: string }) => Promise<string | null>;
+    changePassword: (currentPassword: string, newPassword: string) => Promise<void>;
 }
 
 const AuthContext = createContext<AuthContextType | undefined>(undefined);
@@ -27,6 +34,49 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
     const [token, setToken] = useState<string | null>(localStorage.getItem('token'));
     const [isLoading, setIsLoading] = useState<boolean>(true);
 
+    const clearSession = useCallback(() => {
+        localStorage.removeItem('token');
+        localStorage.removeItem('refreshToken');
+        setToken(null);
+        setUser(null);
+    }, []);
+
+    // Renew the access token with the stored refresh token when a request is rejected as unauthorized
+    useEffect(() => {
+        const interceptor = axios.interceptors.response.use(
+            (response) => response,
+            async (error) => {
+                const original = error.config;
+                const refreshToken = localStorage.getItem('refreshToken');
+                if (
+                    error.response?.status !== 401 ||
+                    !refreshToken ||
+                    original._retry ||
+                    original.url?.endsWith('/tokens/renew_access')
+                ) {
+                    return Promise.reject(error);
+                }
+
+                original._retry = true;
+                try {
+                    const response = await axios.post(`${API_URL}/tokens/renew_access`, {
+                        refresh_token: refreshToken,
+                    });
+                    const { access_token } = response.data;
+                    localStorage.setItem('token', access_token);
+                    setToken(access_token);
+                    original.headers = { ...original.headers, Authorization: `Bearer ${access_token}` };
+                    return axios(original);
+                } catch (renewError) {
+                    clearSession();
+                    return Promise.reject(error);
+                }
+            }
+        );
+
+        return () => axios.interceptors.response.eject(interceptor);
+    }, [clearSession]);
+
     useEffect(() => {
         const fetchCurrentUser = async () => {
             if (!token) {
@@ -43,15 +93,14 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
                 setUser(response.data);
             } catch (error) {
                 console.error('Error fetching user:', error);
-                localStorage.removeItem('token');
-                setToken(null);
+                clearSession();
             } finally {
                 setIsLoading(false);
             }
         };
 
         fetchCurrentUser();
-    }, [token]);
+    }, [token, clearSession]);
 
     const login = async (username: string, password: string) => {
         const response = await axios.post(`${API_URL}/users/login`, {
@@ -59,10 +108,34 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
             password,
         });
 
-        const { access_token, user } = response.data;
-        localStorage.setItem('token', access_token);
-        setToken(access_token);
-        setUser(user);
+        // Accounts with two-factor authentication get a challenge instead of tokens
+        if (response.data.mfa_required) {
+            return {
+                mfaToken: response.data.mfa_token,
+                enrollmentRequired: response.data.mfa_enrollment_required,
+            };
+        }
+
+        startSession(response.data);
+        return null;
+    };
+
+    // completeMFALogin answers the challenge and returns the recovery codes issued if it completed an enrollment
+    const completeMFALogin = async (mfaToken: string, code: string) => {
+        const response = await axios.post(`${API_URL}/users/login/mfa`, {
+            mfa_token: mfaToken,
+            code,
+        });
+
+        startSession(response.data);
+        return response.data.recovery_codes ?? [];
+    };
+
+    const startSession = (data: { access_token: string; refresh_token: string; user: User }) => {
+        localStorage.setItem('token', data.access_token);
+        localStorage.setItem('refreshToken', data.refresh_token);
+        setToken(data.access_token);
+        setUser(data.user);
     };
 
     const register = async (username: string, email: string, password: string) => {
@@ -74,27 +147,32 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
     };
 
     const logout = () => {
-        localStorage.removeItem('token');
-        setToken(null);
-        setUser(null);
+        if (token) {
+            // Revoke the token server-side; the local session is cleared regardless of the outcome
+            axios
+                .post(`${API_URL}/users/logout`, {}, { headers: { Authorization: `Bearer ${token}` } })
+                .catch((error) => console.error('Error logging out:', error));
+        }
+        clearSession();
     };
 
-    const updateRole = async (role: string) => {
-        try {
-            const response = await axios.patch(
-                `${API_URL}/users/role`,
-                { role },
-                {
-                    headers: {
-                        Authorization: `Bearer ${token}`,
-                    },
-                }
-            );
-            setUser(response.data);
-        } catch (error) {
-            console.error('Error updating user role:', error);
-            throw error;
-        }
+    // updateProfile returns the new email if it is waiting for verification
+    const updateProfile = async (changes: { username?: string; email?: string }) => {
+        const response = await axios.patch(`${API_URL}/users/me`, changes, {
+            headers: { Authorization: `Bearer ${token}` },
+        });
+        setUser(response.data.user);
+        return response.data.pending_email ?? null;
+    };
+
+    // Changing the password signs out all sessions, so the response carries a new one for this client
+    const changePassword = async (currentPassword: string, newPassword: string) => {
+        const response = await axios.put(
+            `${API_URL}/users/me/password`,
+            { current_password: currentPassword, new_password: newPassword },
+            { headers: { Authorization: `Bearer ${token}` } }
+        );
+        startSession(response.data);
     };
 
     return (
@@ -105,9 +183,11 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
                 isLoading,
                 token,
                 login,
+                completeMFALogin,
                 register,
                 logout,
-                updateRole,
+                updateProfile,
+                changePassword,
             }}
         >
             {children}
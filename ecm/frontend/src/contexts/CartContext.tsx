@@ -9,7 +9,7 @@ interface CartItem {
     product_id: string;
     product_name: string;
     quantity: number;
-    price: number;
+    price: string;
     image_url: string;
     created_at: string;
     updated_at: string;
@@ -144,7 +144,7 @@ export const CartProvider: React.FC<{ children: React.ReactNode }> = ({ children
     };
 
     const getCartTotal = () => {
-        return cartItems.reduce((total, item) => total + item.price * item.quantity, 0);
+        return cartItems.reduce((total, item) => total + Number(item.price) * item.quantity, 0);
     };
 
     return (
@@ -77,7 +77,7 @@ const Cart: React.FC = () => {
                                             {item.product_name}
                                         </Typography>
                                         <Typography variant="body2" color="text.secondary">
-                                            Price: ${item.price.toFixed(2)}
+                                            Price: ${item.price}
                                         </Typography>
                                     </Grid>
                                     <Grid item xs={3}>
@@ -92,7 +92,7 @@ const Cart: React.FC = () => {
                                     </Grid>
                                     <Grid item xs={2}>
                                         <Typography variant="subtitle1">
-                                            ${(item.price * item.quantity).toFixed(2)}
+                                            ${(Number(item.price) * item.quantity).toFixed(2)}
                                         </Typography>
                                     </Grid>
                                     <Grid item xs={1}>
@@ -28,13 +28,45 @@ import { API_URL } from '../config/constants';
 interface CheckoutFormValues {
     shippingAddress: string;
     paymentMethod: string;
+    cardNumber: string;
+    expMonth: string;
+    expYear: string;
+    cvc: string;
 }
 
+interface Payment {
+    status: string;
+    failure_message?: string;
+}
+
+const requiredForCard = (schema: Yup.StringSchema, message: string) =>
+    Yup.string().when('paymentMethod', {
+        is: 'credit_card',
+        then: schema.required(message),
+    });
+
 const validationSchema = Yup.object({
     shippingAddress: Yup.string().required('Shipping address is required'),
     paymentMethod: Yup.string().required('Payment method is required'),
+    cardNumber: requiredForCard(
+        Yup.string().matches(/^\d{12,19}$/, 'Enter the card number without spaces'),
+        'Card number is required'
+    ),
+    expMonth: requiredForCard(Yup.string().matches(/^(0?[1-9]|1[0-2])$/, 'Enter a month from 1 to 12'), 'Required'),
+    expYear: requiredForCard(Yup.string().matches(/^\d{4}$/, 'Enter a four-digit year'), 'Required'),
+    cvc: requiredForCard(Yup.string().matches(/^\d{3,4}$/, 'Enter 3 or 4 digits'), 'Required'),
 });
 
+const cardFromValues = (values: CheckoutFormValues) =>
+    values.paymentMethod === 'credit_card'
+        ? {
+              number: values.cardNumber,
+              exp_month: Number(values.expMonth),
+              exp_year: Number(values.expYear),
+              cvc: values.cvc,
+          }
+        : undefined;
+
 const Checkout: React.FC = () => {
     const { cartItems, loading, getCartTotal } = useCart();
     const { token } = useAuth();
@@ -44,9 +76,31 @@ const Checkout: React.FC = () => {
     const initialValues: CheckoutFormValues = {
         shippingAddress: '',
         paymentMethod: 'credit_card',
+        cardNumber: '',
+        expMonth: '',
+        expYear: '',
+        cvc: '',
     };
 
-    const handleSubmit = async (values: CheckoutFormValues) => {
+    // confirmPayment completes the payment of a placed order when the bank asks for 3-D Secure
+    const confirmPayment = async (orderId: string, values: CheckoutFormValues): Promise<Payment> => {
+        const confirmed = window.confirm('Your bank asks you to confirm this payment with 3-D Secure. Confirm it?');
+        const response = await axios.post(
+            `${API_URL}/orders/${orderId}/payment`,
+            {
+                card: cardFromValues(values),
+                three_d_secure: confirmed ? 'passed' : 'failed',
+            },
+            {
+                headers: {
+                    Authorization: `Bearer ${token}`,
+                },
+            }
+        );
+        return response.data;
+    };
+
+    const handleSubmit = async (values: CheckoutFormValues, confirmedTotal?: string) => {
         if (!token) {
             toast.error('You must be logged in to place an order');
             navigate('/login');
@@ -60,6 +114,8 @@ const Checkout: React.FC = () => {
                 {
                     shipping_address: values.shippingAddress,
                     payment_method: values.paymentMethod,
+                    confirmed_total: confirmedTotal,
+                    card: cardFromValues(values),
                 },
                 {
                     headers: {
@@ -68,11 +124,43 @@ const Checkout: React.FC = () => {
                 }
             );
 
-            toast.success('Order placed successfully!');
+            let payment: Payment = response.data.payment;
+            if (payment.status === 'requires_action') {
+                // The order is placed either way, and can still be paid for from the order page
+                payment = await confirmPayment(response.data.id, values).catch((error) => {
+                    console.error('Error confirming payment:', error);
+                    return payment;
+                });
+            }
+
+            if (payment.status === 'captured') {
+                toast.success('Order placed successfully!');
+            } else {
+                toast.warning(
+                    `Your order was placed, but the payment did not go through${
+                        payment.failure_message ? `: ${payment.failure_message}` : '.'
+                    } You can pay for it from the order page.`
+                );
+            }
             navigate(`/orders/${response.data.id}`);
         } catch (error) {
             console.error('Error placing order:', error);
-            toast.error('Failed to place order. Please try again.');
+            if (axios.isAxiosError(error) && error.response?.status === 409 && error.response.data.total !== undefined) {
+                const items: { product_name: string; old_price: string; new_price: string }[] = error.response.data.items;
+                const changes = items
+                    .map((item) => `${item.product_name}: $${item.old_price} → $${item.new_price}`)
+                    .join('\n');
+                const total: string = error.response.data.total;
+                if (window.confirm(`Some prices have changed:\n${changes}\n\nPlace the order for $${total}?`)) {
+                    return await handleSubmit(values, total);
+                }
+            } else if (axios.isAxiosError(error) && error.response?.status === 409) {
+                const items: { product_name: string; available: number }[] = error.response.data.items || [];
+                const details = items.map((item) => `${item.product_name} (${item.available} left)`).join(', ');
+                toast.error(details ? `Not enough stock for ${details}` : error.response.data.error);
+            } else {
+                toast.error('Failed to place order. Please try again.');
+            }
         } finally {
             setIsSubmitting(false);
         }
@@ -113,9 +201,9 @@ const Checkout: React.FC = () => {
                         <Formik
                             initialValues={initialValues}
                             validationSchema={validationSchema}
-                            onSubmit={handleSubmit}
+                            onSubmit={(values) => handleSubmit(values)}
                         >
-                            {({ errors, touched }) => (
+                            {({ values, errors, touched }) => (
                                 <Form>
                                     <Box mb={3}>
                                         <Field
@@ -150,6 +238,55 @@ const Checkout: React.FC = () => {
                                         </Field>
                                     </FormControl>
 
+                                    {values.paymentMethod === 'credit_card' && (
+                                        <Grid container spacing={2} sx={{ mt: 1 }}>
+                                            <Grid item xs={12}>
+                                                <Field
+                                                    as={TextField}
+                                                    fullWidth
+                                                    label="Card Number"
+                                                    name="cardNumber"
+                                                    inputProps={{ inputMode: 'numeric', autoComplete: 'cc-number' }}
+                                                    error={touched.cardNumber && Boolean(errors.cardNumber)}
+                                                    helperText={touched.cardNumber && errors.cardNumber}
+                                                />
+                                            </Grid>
+                                            <Grid item xs={4}>
+                                                <Field
+                                                    as={TextField}
+                                                    fullWidth
+                                                    label="Month"
+                                                    name="expMonth"
+                                                    inputProps={{ inputMode: 'numeric', autoComplete: 'cc-exp-month' }}
+                                                    error={touched.expMonth && Boolean(errors.expMonth)}
+                                                    helperText={touched.expMonth && errors.expMonth}
+                                                />
+                                            </Grid>
+                                            <Grid item xs={4}>
+                                                <Field
+                                                    as={TextField}
+                                                    fullWidth
+                                                    label="Year"
+                                                    name="expYear"
+                                                    inputProps={{ inputMode: 'numeric', autoComplete: 'cc-exp-year' }}
+                                                    error={touched.expYear && Boolean(errors.expYear)}
+                                                    helperText={touched.expYear && errors.expYear}
+                                                />
+                                            </Grid>
+                                            <Grid item xs={4}>
+                                                <Field
+                                                    as={TextField}
+                                                    fullWidth
+                                                    label="CVC"
+                                                    name="cvc"
+                                                    inputProps={{ inputMode: 'numeric', autoComplete: 'cc-csc' }}
+                                                    error={touched.cvc && Boolean(errors.cvc)}
+                                                    helperText={touched.cvc && errors.cvc}
+                                                />
+                                            </Grid>
+                                        </Grid>
+                                    )}
+
                                     <Box sx={{ mt: 4, display: 'flex', justifyContent: 'space-between' }}>
                                         <Button
                                             variant="outlined"
@@ -186,7 +323,7 @@ const Checkout: React.FC = () => {
                                     </Grid>
                                     <Grid item xs={4}>
                                         <Typography variant="body2" align="right">
-                                            ${(item.price * item.quantity).toFixed(2)}
+                                            ${(Number(item.price) * item.quantity).toFixed(2)}
                                         </Typography>
                                     </Grid>
                                 </Grid>
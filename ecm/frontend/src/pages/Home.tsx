@@ -27,7 +27,7 @@ interface Product {
     id: string;
     name: string;
     description: string;
-    price: number;
+    price: string;
     stock_quantity: number;
     image_url: string;
     category_id: string;
@@ -165,7 +165,7 @@ const Home: React.FC = () => {
                                             {product.description}
                                         </Typography>
                                         <Typography variant="h6" color="primary" sx={{ mt: 2 }}>
-                                            ${product.price.toFixed(2)}
+                                            ${product.price}
                                         </Typography>
                                     </CardContent>
                                     <CardActions>
@@ -4,7 +4,9 @@ import { Formik, Form, Field, ErrorMessage } from 'formik';
 import * as Yup from 'yup';
 import { toast } from 'react-toastify';
 import { TextField, Button, Typography, Container, Box, Link, Paper } from '@mui/material';
-import { useAuth } from '../contexts/AuthContext';
+import axios from 'axios';
+import { useAuth, MFAChallenge } from '../contexts/AuthContext';
+import { API_URL } from '../config/constants';
 
 interface LoginFormValues {
     username: string;
@@ -12,14 +14,17 @@ interface LoginFormValues {
 }
 
 const validationSchema = Yup.object({
-    username: Yup.string().required('Username is required'),
+    username: Yup.string().required('Username or email is required'),
     password: Yup.string().required('Password is required'),
 });
 
 const Login: React.FC = () => {
     const navigate = useNavigate();
-    const { login } = useAuth();
+    const { login, completeMFALogin } = useAuth();
     const [isSubmitting, setIsSubmitting] = useState(false);
+    const [challenge, setChallenge] = useState<MFAChallenge | null>(null);
+    const [enrollmentSecret, setEnrollmentSecret] = useState<string | null>(null);
+    const [code, setCode] = useState('');
 
     const initialValues: LoginFormValues = {
         username: '',
@@ -29,17 +34,92 @@ const Login: React.FC = () => {
     const handleSubmit = async (values: LoginFormValues) => {
         setIsSubmitting(true);
         try {
-            await login(values.username, values.password);
+            const mfaChallenge = await login(values.username, values.password);
+            if (mfaChallenge) {
+                if (mfaChallenge.enrollmentRequired) {
+                    const response = await axios.post(`${API_URL}/users/login/mfa/enroll`, {
+                        mfa_token: mfaChallenge.mfaToken,
+                    });
+                    setEnrollmentSecret(response.data.secret);
+                }
+                setChallenge(mfaChallenge);
+                return;
+            }
             toast.success('Login successful');
             navigate('/');
         } catch (error) {
             console.error('Login failed:', error);
-            toast.error('Login failed. Please check your credentials.');
+            if (axios.isAxiosError(error) && error.response?.status === 429) {
+                toast.error('Too many failed login attempts. Please try again later.');
+            } else {
+                toast.error('Login failed. Please check your credentials.');
+            }
         } finally {
             setIsSubmitting(false);
         }
     };
 
+    const handleCodeSubmit = async (event: React.FormEvent) => {
+        event.preventDefault();
+        if (!challenge) return;
+
+        setIsSubmitting(true);
+        try {
+            const recoveryCodes = await completeMFALogin(challenge.mfaToken, code);
+            if (recoveryCodes.length > 0) {
+                window.alert(`Save these recovery codes somewhere safe:\n\n${recoveryCodes.join('\n')}`);
+            }
+            toast.success('Login successful');
+            navigate('/');
+        } catch (error) {
+            // The challenge can only be used once, so the user has to start over
+            console.error('Two-factor authentication failed:', error);
+            toast.error('Invalid code. Please log in again.');
+            setChallenge(null);
+            setEnrollmentSecret(null);
+        } finally {
+            setCode('');
+            setIsSubmitting(false);
+        }
+    };
+
+    if (challenge) {
+        return (
+            <Container maxWidth="sm">
+                <Paper elevation={3} sx={{ mt: 8, p: 4 }}>
+                    <Typography component="h1" variant="h5" align="center">
+                        Two-Factor Authentication
+                    </Typography>
+                    {enrollmentSecret && (
+                        <Typography variant="body2" sx={{ mt: 2 }}>
+                            Your account requires two-factor authentication. Add this key to your authenticator
+                            app, then enter the code it shows: <strong>{enrollmentSecret}</strong>
+                        </Typography>
+                    )}
+                    <form onSubmit={handleCodeSubmit}>
+                        <Box mb={3} mt={2}>
+                            <TextField
+                                fullWidth
+                                label={enrollmentSecret ? 'Authentication code' : 'Authentication or recovery code'}
+                                value={code}
+                                onChange={(event) => setCode(event.target.value.trim())}
+                            />
+                        </Box>
+                        <Button
+                            type="submit"
+                            fullWidth
+                            variant="contained"
+                            color="primary"
+                            disabled={isSubmitting || code.length === 0}
+                        >
+                            {isSubmitting ? 'Verifying...' : 'Verify'}
+                        </Button>
+                    </form>
+                </Paper>
+            </Container>
+        );
+    }
+
     return (
         <Container maxWidth="sm">
             <Paper elevation={3} sx={{ mt: 8, p: 4 }}>
@@ -57,7 +137,7 @@ const Login: React.FC = () => {
                                 <Field
                                     as={TextField}
                                     fullWidth
-                                    label="Username"
+                                    label="Username or Email"
                                     name="username"
                                     error={touched.username && Boolean(errors.username)}
                                     helperText={touched.username && errors.username}
@@ -17,7 +17,9 @@ import {
     ListItemText,
     ListItemAvatar,
     Avatar,
+    TextField,
 } from '@mui/material';
+import { toast } from 'react-toastify';
 import { useAuth } from '../contexts/AuthContext';
 import { formatDate } from '../utils/formatters';
 import { API_URL } from '../config/constants';
@@ -27,28 +29,61 @@ interface OrderItem {
     product_id: string;
     product_name: string;
     quantity: number;
-    price: number;
+    price: string;
     image_url: string;
 }
 
+interface Payment {
+    provider: string;
+    status: string;
+    amount: string;
+    refunded_amount: string;
+    card_last4?: string;
+    failure_message?: string;
+}
+
 interface Order {
     id: string;
     user_id: string;
     status: string;
-    total_amount: number;
+    total_amount: string;
     shipping_address: string;
     payment_method: string;
     created_at: string;
     updated_at: string;
     items: OrderItem[];
+    payment?: Payment;
+}
+
+interface TrackingEvent {
+    status: string;
+    location?: string;
+    description?: string;
+    occurred_at: string;
+}
+
+interface Shipment {
+    id: string;
+    carrier: string;
+    tracking_number: string;
+    status: string;
+    estimated_delivery?: string;
+    delivered_at?: string;
+    items: { order_item_id: string; product_name: string; quantity: number }[];
+    events: TrackingEvent[];
 }
 
 const OrderDetails: React.FC = () => {
     const { id } = useParams<{ id: string }>();
     const [order, setOrder] = useState<Order | null>(null);
+    const [shipments, setShipments] = useState<Shipment[]>([]);
     const [loading, setLoading] = useState<boolean>(true);
     const [error, setError] = useState<string | null>(null);
-    const { token } = useAuth();
+    const [cancelReason, setCancelReason] = useState('');
+    const [cancelling, setCancelling] = useState(false);
+    const [card, setCard] = useState({ number: '', exp_month: '', exp_year: '', cvc: '' });
+    const [paying, setPaying] = useState(false);
+    const { token, user } = useAuth();
 
     useEffect(() => {
         const fetchOrderDetails = async () => {
@@ -62,6 +97,13 @@ const OrderDetails: React.FC = () => {
                     },
                 });
                 setOrder(response.data);
+
+                const trackingResponse = await axios.get(`${API_URL}/orders/${id}/tracking`, {
+                    headers: {
+                        Authorization: `Bearer ${token}`,
+                    },
+                });
+                setShipments(trackingResponse.data.shipments);
             } catch (error) {
                 console.error('Error fetching order details:', error);
                 setError('Failed to load order details. Please try again.');
@@ -73,6 +115,97 @@ const OrderDetails: React.FC = () => {
         fetchOrderDetails();
     }, [id, token]);
 
+    const handleCancelOrder = async () => {
+        if (!token || !order) return;
+
+        setCancelling(true);
+        try {
+            const response = await axios.post(
+                `${API_URL}/orders/${order.id}/cancel`,
+                { reason: cancelReason },
+                {
+                    headers: {
+                        Authorization: `Bearer ${token}`,
+                    },
+                }
+            );
+            setOrder({ ...order, status: response.data.status });
+            setCancelReason('');
+            toast.success('Your order has been cancelled');
+        } catch (error) {
+            console.error('Error cancelling order:', error);
+            if (axios.isAxiosError(error) && error.response?.status === 409) {
+                toast.error(error.response.data.error);
+            } else {
+                toast.error('Failed to cancel the order. Please try again.');
+            }
+        } finally {
+            setCancelling(false);
+        }
+    };
+
+    const handlePay = async (threeDSecure?: string) => {
+        if (!token || !order) return;
+
+        setPaying(true);
+        try {
+            const response = await axios.post(
+                `${API_URL}/orders/${order.id}/payment`,
+                {
+                    card:
+                        order.payment_method === 'credit_card'
+                            ? {
+                                  number: card.number,
+                                  exp_month: Number(card.exp_month),
+                                  exp_year: Number(card.exp_year),
+                                  cvc: card.cvc,
+                              }
+                            : undefined,
+                    three_d_secure: threeDSecure,
+                },
+                {
+                    headers: {
+                        Authorization: `Bearer ${token}`,
+                    },
+                }
+            );
+            const payment: Payment = response.data;
+            setOrder({ ...order, payment });
+
+            if (payment.status === 'requires_action' && !threeDSecure) {
+                const confirmed = window.confirm(
+                    'Your bank asks you to confirm this payment with 3-D Secure. Confirm it?'
+                );
+                return await handlePay(confirmed ? 'passed' : 'failed');
+            }
+            if (payment.status === 'captured') {
+                toast.success('Thank you, your payment went through');
+            } else {
+                toast.error(payment.failure_message || 'Your payment did not go through');
+            }
+        } catch (error) {
+            console.error('Error paying for order:', error);
+            if (axios.isAxiosError(error) && (error.response?.status === 400 || error.response?.status === 409)) {
+                toast.error(error.response.data.error);
+            } else {
+                toast.error('Failed to take the payment. Please try again.');
+            }
+        } finally {
+            setPaying(false);
+        }
+    };
+
+    const canPay =
+        order !== null &&
+        order.user_id === user?.id &&
+        order.status !== 'cancelled' &&
+        ['pending', 'requires_action', 'declined', 'authorized'].includes(order.payment?.status ?? '');
+
+    const canCancel =
+        order !== null &&
+        order.user_id === user?.id &&
+        (order.status === 'pending' || order.status === 'processing');
+
     const getStatusColor = (status: string) => {
         switch (status) {
             case 'pending':
@@ -151,10 +284,10 @@ const OrderDetails: React.FC = () => {
                                         secondary={
                                             <>
                                                 <Typography component="span" variant="body2" color="text.primary">
-                                                    ${item.price.toFixed(2)} × {item.quantity}
+                                                    ${item.price} × {item.quantity}
                                                 </Typography>
                                                 <Box component="span" sx={{ display: 'block' }}>
-                                                    Total: ${(item.price * item.quantity).toFixed(2)}
+                                                    Total: ${(Number(item.price) * item.quantity).toFixed(2)}
                                                 </Box>
                                             </>
                                         }
@@ -163,6 +296,48 @@ const OrderDetails: React.FC = () => {
                             ))}
                         </List>
                     </Paper>
+
+                    {shipments.length > 0 && (
+                        <Paper sx={{ p: 3, mb: 3 }}>
+                            <Typography variant="h6" gutterBottom>
+                                Tracking
+                            </Typography>
+                            {shipments.map((shipment) => (
+                                <Box key={shipment.id} sx={{ mb: 3 }}>
+                                    <Box sx={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center' }}>
+                                        <Typography variant="subtitle1">
+                                            {shipment.carrier.toUpperCase()} {shipment.tracking_number}
+                                        </Typography>
+                                        <Chip label={shipment.status.replace(/_/g, ' ').toUpperCase()} size="small" />
+                                    </Box>
+                                    <Typography variant="body2" color="text.secondary">
+                                        {shipment.items.map((item) => `${item.quantity} × ${item.product_name}`).join(', ')}
+                                    </Typography>
+                                    {shipment.delivered_at ? (
+                                        <Typography variant="body2">Delivered {formatDate(shipment.delivered_at)}</Typography>
+                                    ) : (
+                                        shipment.estimated_delivery && (
+                                            <Typography variant="body2">
+                                                Estimated delivery {formatDate(shipment.estimated_delivery)}
+                                            </Typography>
+                                        )
+                                    )}
+                                    <List dense>
+                                        {shipment.events.map((event) => (
+                                            <ListItem key={`${event.status}-${event.occurred_at}`}>
+                                                <ListItemText
+                                                    primary={event.description || event.status.replace(/_/g, ' ')}
+                                                    secondary={[formatDate(event.occurred_at), event.location]
+                                                        .filter(Boolean)
+                                                        .join(' · ')}
+                                                />
+                                            </ListItem>
+                                        ))}
+                                    </List>
+                                </Box>
+                            ))}
+                        </Paper>
+                    )}
                 </Grid>
 
                 <Grid item xs={12} md={4}>
@@ -198,8 +373,31 @@ const OrderDetails: React.FC = () => {
                             </Typography>
                             <Typography variant="body1" sx={{ textTransform: 'capitalize' }}>
                                 {order.payment_method.replace(/_/g, ' ')}
+                                {order.payment?.card_last4 && ` ending in ${order.payment.card_last4}`}
                             </Typography>
                         </Box>
+                        {order.payment && (
+                            <Box sx={{ mb: 2 }}>
+                                <Typography variant="body2" color="text.secondary">
+                                    Payment:
+                                </Typography>
+                                <Chip
+                                    label={order.payment.status.replace(/_/g, ' ').toUpperCase()}
+                                    color={order.payment.status === 'captured' ? 'success' : 'default'}
+                                    size="small"
+                                />
+                                {order.payment.failure_message && (
+                                    <Typography variant="body2" color="error" sx={{ mt: 1 }}>
+                                        {order.payment.failure_message}
+                                    </Typography>
+                                )}
+                                {Number(order.payment.refunded_amount) > 0 && (
+                                    <Typography variant="body2" sx={{ mt: 1 }}>
+                                        Refunded ${order.payment.refunded_amount}
+                                    </Typography>
+                                )}
+                            </Box>
+                        )}
 
                         <Divider sx={{ my: 2 }} />
 
@@ -217,10 +415,100 @@ const OrderDetails: React.FC = () => {
                                 Total
                             </Typography>
                             <Typography variant="subtitle1" fontWeight="bold">
-                                ${order.total_amount.toFixed(2)}
+                                ${order.total_amount}
                             </Typography>
                         </Box>
                     </Paper>
+
+                    {canPay && (
+                        <Paper sx={{ p: 3, mb: 3 }}>
+                            <Typography variant="h6" gutterBottom>
+                                Pay for Order
+                            </Typography>
+                            <Typography variant="body2" color="text.secondary" gutterBottom>
+                                Your order is on hold until it is paid.
+                            </Typography>
+                            {order.payment_method === 'credit_card' && (
+                                <Grid container spacing={2} sx={{ my: 1 }}>
+                                    <Grid item xs={12}>
+                                        <TextField
+                                            fullWidth
+                                            label="Card Number"
+                                            value={card.number}
+                                            onChange={(e) => setCard({ ...card, number: e.target.value })}
+                                            inputProps={{ inputMode: 'numeric', autoComplete: 'cc-number' }}
+                                        />
+                                    </Grid>
+                                    <Grid item xs={4}>
+                                        <TextField
+                                            fullWidth
+                                            label="Month"
+                                            value={card.exp_month}
+                                            onChange={(e) => setCard({ ...card, exp_month: e.target.value })}
+                                            inputProps={{ inputMode: 'numeric', autoComplete: 'cc-exp-month' }}
+                                        />
+                                    </Grid>
+                                    <Grid item xs={4}>
+                                        <TextField
+                                            fullWidth
+                                            label="Year"
+                                            value={card.exp_year}
+                                            onChange={(e) => setCard({ ...card, exp_year: e.target.value })}
+                                            inputProps={{ inputMode: 'numeric', autoComplete: 'cc-exp-year' }}
+                                        />
+                                    </Grid>
+                                    <Grid item xs={4}>
+                                        <TextField
+                                            fullWidth
+                                            label="CVC"
+                                            value={card.cvc}
+                                            onChange={(e) => setCard({ ...card, cvc: e.target.value })}
+                                            inputProps={{ inputMode: 'numeric', autoComplete: 'cc-csc' }}
+                                        />
+                                    </Grid>
+                                </Grid>
+                            )}
+                            <Button
+                                variant="contained"
+                                color="primary"
+                                fullWidth
+                                onClick={() => handlePay()}
+                                disabled={paying}
+                            >
+                                {paying ? 'Paying...' : `Pay $${order.payment?.amount}`}
+                            </Button>
+                        </Paper>
+                    )}
+
+                    {canCancel && (
+                        <Paper sx={{ p: 3, mb: 3 }}>
+                            <Typography variant="h6" gutterBottom>
+                                Cancel Order
+                            </Typography>
+                            <Typography variant="body2" color="text.secondary" gutterBottom>
+                                You can cancel this order until it ships.
+                            </Typography>
+                            <TextField
+                                fullWidth
+                                multiline
+                                minRows={2}
+                                label="Reason"
+                                value={cancelReason}
+                                onChange={(e) => setCancelReason(e.target.value)}
+                                inputProps={{ maxLength: 500 }}
+                                sx={{ my: 2 }}
+                            />
+                            <Button
+                                variant="outlined"
+                                color="error"
+                                fullWidth
+                                onClick={handleCancelOrder}
+                                disabled={cancelling || cancelReason.trim() === ''}
+                            >
+                                Cancel Order
+                            </Button>
+                        </Paper>
+                    )}
                 </Grid>
             </Grid>
         </Container>
@@ -21,7 +21,7 @@ interface Product {
     id: string;
     name: string;
     description: string;
-    price: number;
+    price: string;
     stock_quantity: number;
     shop_id: string;
     category_id: string;
@@ -141,7 +141,7 @@ const ProductDetails: React.FC = () => {
                         {product.name}
                     </Typography>
                     <Typography variant="h5" color="primary" gutterBottom>
-                        ${product.price.toFixed(2)}
+                        ${product.price}
                     </Typography>
                     <Divider sx={{ my: 2 }} />
                     <Typography variant="body1" paragraph>
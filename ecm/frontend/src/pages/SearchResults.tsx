@@ -22,7 +22,7 @@ interface Product {
     id: string;
     name: string;
     description: string;
-    price: number;
+    price: string;
     stock_quantity: number;
     image_url: string;
 }
@@ -134,7 +134,7 @@ const SearchResults: React.FC = () => {
                                             {product.description}
                                         </Typography>
                                         <Typography variant="h6" color="primary" sx={{ mt: 2 }}>
-                                            ${product.price.toFixed(2)}
+                                            ${product.price}
                                         </Typography>
                                     </CardContent>
                                     <CardActions>
@@ -24,7 +24,7 @@ import { API_URL } from '../config/constants';
 interface Order {
     id: string;
     status: string;
-    total_amount: number;
+    total_amount: string;
     shipping_address: string;
     payment_method: string;
     created_at: string;
@@ -135,7 +135,7 @@ const UserOrders: React.FC = () => {
                                         size="small"
                                     />
                                 </TableCell>
-                                <TableCell>${order.total_amount.toFixed(2)}</TableCell>
+                                <TableCell>${order.total_amount}</TableCell>
                                 <TableCell>
                                     <Button
                                         variant="contained"
@@ -1,5 +1,6 @@
-import React, { useState } from 'react';
+import React, { useState, useEffect } from 'react';
 import { useNavigate } from 'react-router-dom';
+import axios from 'axios';
 import {
     Container,
     Typography,
@@ -7,40 +8,107 @@ import {
     Grid,
     Box,
     Button,
-    FormControl,
-    FormLabel,
-    FormControlLabel,
-    Radio,
-    RadioGroup,
     Divider,
     Alert,
+    TextField,
 } from '@mui/material';
 import { toast } from 'react-toastify';
 import { useAuth } from '../contexts/AuthContext';
+import { API_URL } from '../config/constants';
+
+interface SellerApplication {
+    id: string;
+    business_name: string;
+    status: string;
+    review_note?: string;
+}
+
+const emptyApplication = {
+    business_name: '',
+    business_email: '',
+    business_phone: '',
+    business_address: '',
+    tax_id: '',
+    description: '',
+};
 
 const UserProfile: React.FC = () => {
-    const { user, logout, updateRole } = useAuth();
-    const [selectedRole, setSelectedRole] = useState<string>(user?.role || '');
+    const { user, token, logout, updateProfile, changePassword } = useAuth();
     const [isSubmitting, setIsSubmitting] = useState(false);
+    const [username, setUsername] = useState<string>(user?.username || '');
+    const [email, setEmail] = useState<string>(user?.email || '');
+    const [currentPassword, setCurrentPassword] = useState('');
+    const [newPassword, setNewPassword] = useState('');
+    const [application, setApplication] = useState<SellerApplication | null>(null);
+    const [applicationForm, setApplicationForm] = useState(emptyApplication);
     const navigate = useNavigate();
 
-    const handleRoleChange = (event: React.ChangeEvent<HTMLInputElement>) => {
-        setSelectedRole(event.target.value);
+    useEffect(() => {
+        if (!token || user?.role !== 'buyer') return;
+
+        axios
+            .get(`${API_URL}/seller-applications/me`, { headers: { Authorization: `Bearer ${token}` } })
+            .then((response) => setApplication(response.data))
+            .catch((error) => {
+                if (error.response?.status !== 404) {
+                    console.error('Error fetching seller application:', error);
+                }
+            });
+    }, [token, user?.role]);
+
+    const handleProfileSubmit = async () => {
+        const changes: { username?: string; email?: string } = {};
+        if (username !== user?.username) changes.username = username;
+        if (email !== user?.email) changes.email = email;
+
+        setIsSubmitting(true);
+        try {
+            const pendingEmail = await updateProfile(changes);
+            if (pendingEmail) {
+                toast.info(`We sent a verification link to ${pendingEmail}`);
+                setEmail(user?.email || '');
+            } else {
+                toast.success('Profile updated');
+            }
+        } catch (error) {
+            console.error('Error updating profile:', error);
+            toast.error('Failed to update profile');
+        } finally {
+            setIsSubmitting(false);
+        }
     };
 
-    const handleRoleSubmit = async () => {
-        if (selectedRole === user?.role) {
-            toast.info('You already have this role');
-            return;
+    const handlePasswordSubmit = async () => {
+        setIsSubmitting(true);
+        try {
+            await changePassword(currentPassword, newPassword);
+            toast.success('Password changed. Your other sessions have been signed out.');
+            setCurrentPassword('');
+            setNewPassword('');
+        } catch (error) {
+            console.error('Error changing password:', error);
+            toast.error('Failed to change password');
+        } finally {
+            setIsSubmitting(false);
         }
+    };
+
+    const handleApplicationChange = (event: React.ChangeEvent<HTMLInputElement>) => {
+        setApplicationForm({ ...applicationForm, [event.target.name]: event.target.value });
+    };
 
+    const handleApplicationSubmit = async () => {
         setIsSubmitting(true);
         try {
-            await updateRole(selectedRole);
-            toast.success(`Your role has been updated to ${selectedRole}`);
+            const response = await axios.post(`${API_URL}/seller-applications`, applicationForm, {
+                headers: { Authorization: `Bearer ${token}` },
+            });
+            setApplication(response.data);
+            setApplicationForm(emptyApplication);
+            toast.success('Your application has been sent for review');
         } catch (error) {
-            console.error('Error updating role:', error);
-            toast.error('Failed to update role');
+            console.error('Error submitting seller application:', error);
+            toast.error('Failed to submit seller application');
         } finally {
             setIsSubmitting(false);
         }
@@ -84,31 +152,142 @@ const UserProfile: React.FC = () => {
                     <Grid item xs={12}>
                         <Divider sx={{ my: 2 }} />
                         <Typography variant="h6" gutterBottom>
-                            Change Role
+                            Edit Profile
                         </Typography>
-                        <FormControl component="fieldset">
-                            <FormLabel component="legend">Select Role</FormLabel>
-                            <RadioGroup
-                                name="role"
-                                value={selectedRole}
-                                onChange={handleRoleChange}
+                        <Box sx={{ display: 'flex', flexDirection: 'column', gap: 2, maxWidth: 400 }}>
+                            <TextField
+                                label="Username"
+                                value={username}
+                                onChange={(event) => setUsername(event.target.value)}
+                            />
+                            <TextField
+                                label="Email"
+                                type="email"
+                                value={email}
+                                onChange={(event) => setEmail(event.target.value)}
+                            />
+                            <Button
+                                variant="contained"
+                                color="primary"
+                                onClick={handleProfileSubmit}
+                                disabled={isSubmitting || (username === user.username && email === user.email)}
                             >
-                                <FormControlLabel value="buyer" control={<Radio />} label="Buyer" />
-                                <FormControlLabel value="seller" control={<Radio />} label="Seller" />
-                            </RadioGroup>
-                        </FormControl>
-                        <Box sx={{ mt: 2 }}>
+                                Save Profile
+                            </Button>
+                        </Box>
+                    </Grid>
+
+                    <Grid item xs={12}>
+                        <Divider sx={{ my: 2 }} />
+                        <Typography variant="h6" gutterBottom>
+                            Change Password
+                        </Typography>
+                        <Box sx={{ display: 'flex', flexDirection: 'column', gap: 2, maxWidth: 400 }}>
+                            <TextField
+                                label="Current Password"
+                                type="password"
+                                value={currentPassword}
+                                onChange={(event) => setCurrentPassword(event.target.value)}
+                            />
+                            <TextField
+                                label="New Password"
+                                type="password"
+                                value={newPassword}
+                                onChange={(event) => setNewPassword(event.target.value)}
+                            />
                             <Button
                                 variant="contained"
                                 color="primary"
-                                onClick={handleRoleSubmit}
-                                disabled={isSubmitting || selectedRole === user.role}
+                                onClick={handlePasswordSubmit}
+                                disabled={isSubmitting || !currentPassword || newPassword.length < 6}
                             >
-                                {isSubmitting ? 'Updating...' : 'Update Role'}
+                                Change Password
                             </Button>
                         </Box>
                     </Grid>
 
+                    {user.role === 'buyer' && (
+                        <Grid item xs={12}>
+                            <Divider sx={{ my: 2 }} />
+                            <Typography variant="h6" gutterBottom>
+                                Become a Seller
+                            </Typography>
+                            {application && (
+                                <Alert
+                                    severity={application.status === 'rejected' ? 'warning' : 'info'}
+                                    sx={{ mb: 2 }}
+                                >
+                                    Your application for {application.business_name} is {application.status}.
+                                    {application.review_note && ` Note: ${application.review_note}`}
+                                </Alert>
+                            )}
+                            {application?.status !== 'pending' && (
+                                <Box sx={{ display: 'flex', flexDirection: 'column', gap: 2, maxWidth: 400 }}>
+                                    <TextField
+                                        label="Business Name"
+                                        name="business_name"
+                                        value={applicationForm.business_name}
+                                        onChange={handleApplicationChange}
+                                        required
+                                    />
+                                    <TextField
+                                        label="Business Email"
+                                        name="business_email"
+                                        type="email"
+                                        value={applicationForm.business_email}
+                                        onChange={handleApplicationChange}
+                                        required
+                                    />
+                                    <TextField
+                                        label="Business Phone"
+                                        name="business_phone"
+                                        value={applicationForm.business_phone}
+                                        onChange={handleApplicationChange}
+                                        required
+                                    />
+                                    <TextField
+                                        label="Business Address"
+                                        name="business_address"
+                                        value={applicationForm.business_address}
+                                        onChange={handleApplicationChange}
+                                        multiline
+                                        required
+                                    />
+                                    <TextField
+                                        label="Tax ID"
+                                        name="tax_id"
+                                        value={applicationForm.tax_id}
+                                        onChange={handleApplicationChange}
+                                        required
+                                    />
+                                    <TextField
+                                        label="What will you sell?"
+                                        name="description"
+                                        value={applicationForm.description}
+                                        onChange={handleApplicationChange}
+                                        multiline
+                                        rows={3}
+                                    />
+                                    <Button
+                                        variant="contained"
+                                        color="primary"
+                                        onClick={handleApplicationSubmit}
+                                        disabled={
+                                            isSubmitting ||
+                                            !applicationForm.business_name ||
+                                            !applicationForm.business_email ||
+                                            !applicationForm.business_phone ||
+                                            !applicationForm.business_address ||
+                                            !applicationForm.tax_id
+                                        }
+                                    >
+                                        {isSubmitting ? 'Submitting...' : 'Apply to Sell'}
+                                    </Button>
+                                </Box>
+                            )}
+                        </Grid>
+                    )}
+
                     <Grid item xs={12}>
                         <Divider sx={{ my: 2 }} />
                         <Box sx={{ display: 'flex', justifyContent: 'space-between', mt: 3 }}>
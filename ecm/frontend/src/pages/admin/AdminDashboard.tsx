@@ -23,20 +23,40 @@ import { useAuth } from '../../contexts/AuthContext';
 import { API_URL } from '../../config/constants';
 
 interface DashboardStats {
-    totalUsers: number;
-    totalProducts: number;
-    totalShops: number;
-    totalOrders: number;
-    recentOrders: {
+    total_users: number;
+    total_products: number;
+    total_shops: number;
+    total_orders: number;
+    orders_by_status: Record<string, number>;
+    revenue: {
+        interval: string;
+        from: string;
+        to: string;
+        total: string;
+        periods: {
+            period_start: string;
+            order_count: number;
+            revenue: string;
+        }[];
+    };
+    top_products: {
         id: string;
-        status: string;
-        total_amount: number;
-        created_at: string;
+        name: string;
+        units_sold: number;
+        revenue: string;
     }[];
 }
 
+interface RecentOrder {
+    id: string;
+    status: string;
+    total_amount: string;
+    created_at: string;
+}
+
 const AdminDashboard: React.FC = () => {
     const [stats, setStats] = useState<DashboardStats | null>(null);
+    const [recentOrders, setRecentOrders] = useState<RecentOrder[]>([]);
     const [loading, setLoading] = useState(true);
     const [error, setError] = useState<string | null>(null);
     const { token } = useAuth();
@@ -47,36 +67,17 @@ const AdminDashboard: React.FC = () => {
 
             setLoading(true);
             try {
-                // This would be replaced by actual API calls to get dashboard stats
-                // For now, we'll mock the data
-                const mockStats: DashboardStats = {
-                    totalUsers: 120,
-                    totalProducts: 450,
-                    totalShops: 25,
-                    totalOrders: 310,
-                    recentOrders: [
-                        {
-                            id: '6d7f5e8a-9c3b-4d2a-8e7f-1a2b3c4d5e6f',
-                            status: 'pending',
-                            total_amount: 189.95,
-                            created_at: new Date().toISOString(),
-                        },
-                        {
-                            id: '7e8f6d9c-5b4a-3e2d-1f2e-3d4e5f6a7b8c',
-                            status: 'processing',
-                            total_amount: 75.50,
-                            created_at: new Date(Date.now() - 3600000).toISOString(),
-                        },
-                        {
-                            id: '8a9b7c6d-5e4f-3d2e-1c2b-3a4b5c6d7e8f',
-                            status: 'shipped',
-                            total_amount: 230.00,
-                            created_at: new Date(Date.now() - 86400000).toISOString(),
-                        },
-                    ],
-                };
-
-                setStats(mockStats);
+                const headers = { Authorization: `Bearer ${token}` };
+                const [statsResponse, ordersResponse] = await Promise.all([
+                    axios.get(`${API_URL}/admin/stats`, { headers }),
+                    axios.get(`${API_URL}/orders/all`, {
+                        params: { page_id: 1, page_size: 5 },
+                        headers,
+                    }),
+                ]);
+
+                setStats(statsResponse.data);
+                setRecentOrders(ordersResponse.data);
             } catch (error) {
                 console.error('Error fetching dashboard stats:', error);
                 setError('Failed to load dashboard statistics');
@@ -119,7 +120,7 @@ const AdminDashboard: React.FC = () => {
                             <Typography variant="h6">Total Users</Typography>
                         </Box>
                         <Typography variant="h3" component="div">
-                            {stats?.totalUsers}
+                            {stats?.total_users}
                         </Typography>
                     </Paper>
                 </Grid>
@@ -130,7 +131,7 @@ const AdminDashboard: React.FC = () => {
                             <Typography variant="h6">Total Products</Typography>
                         </Box>
                         <Typography variant="h3" component="div">
-                            {stats?.totalProducts}
+                            {stats?.total_products}
                         </Typography>
                     </Paper>
                 </Grid>
@@ -141,7 +142,7 @@ const AdminDashboard: React.FC = () => {
                             <Typography variant="h6">Total Shops</Typography>
                         </Box>
                         <Typography variant="h3" component="div">
-                            {stats?.totalShops}
+                            {stats?.total_shops}
                         </Typography>
                     </Paper>
                 </Grid>
@@ -152,7 +153,7 @@ const AdminDashboard: React.FC = () => {
                             <Typography variant="h6">Total Orders</Typography>
                         </Box>
                         <Typography variant="h3" component="div">
-                            {stats?.totalOrders}
+                            {stats?.total_orders}
                         </Typography>
                     </Paper>
                 </Grid>
@@ -164,12 +165,12 @@ const AdminDashboard: React.FC = () => {
                             Recent Orders
                         </Typography>
                         <List>
-                            {stats?.recentOrders.map((order) => (
+                            {recentOrders.map((order) => (
                                 <React.Fragment key={order.id}>
                                     <ListItem>
                                         <ListItemText
                                             primary={`Order #${order.id.substring(0, 8)}...`}
-                                            secondary={`Status: ${order.status} - $${order.total_amount.toFixed(2)}`}
+                                            secondary={`Status: ${order.status} - $${order.total_amount}`}
                                         />
                                         <Button
                                             variant="outlined"
@@ -187,6 +188,53 @@ const AdminDashboard: React.FC = () => {
                     </Paper>
                 </Grid>
 
+                {/* Revenue */}
+                <Grid item xs={12} md={6}>
+                    <Paper sx={{ p: 2 }}>
+                        <Typography variant="h6" gutterBottom>
+                            Revenue ({stats?.revenue.from} to {stats?.revenue.to})
+                        </Typography>
+                        <Typography variant="h4" component="div" gutterBottom>
+                            ${stats?.revenue.total}
+                        </Typography>
+                        <List dense>
+                            {stats?.revenue.periods
+                                .filter((period) => period.order_count > 0)
+                                .slice(-7)
+                                .map((period) => (
+                                    <ListItem key={period.period_start}>
+                                        <ListItemText
+                                            primary={period.period_start}
+                                            secondary={`${period.order_count} orders - $${period.revenue}`}
+                                        />
+                                    </ListItem>
+                                ))}
+                        </List>
+                    </Paper>
+                </Grid>
+
+                {/* Top Products */}
+                <Grid item xs={12} md={6}>
+                    <Paper sx={{ p: 2 }}>
+                        <Typography variant="h6" gutterBottom>
+                            Top Products
+                        </Typography>
+                        <List>
+                            {stats?.top_products.map((product) => (
+                                <React.Fragment key={product.id}>
+                                    <ListItem>
+                                        <ListItemText
+                                            primary={product.name}
+                                            secondary={`${product.units_sold} sold - $${product.revenue}`}
+                                        />
+                                    </ListItem>
+                                    <Divider />
+                                </React.Fragment>
+                            ))}
+                        </List>
+                    </Paper>
+                </Grid>
+
                 {/* Quick Links */}
                 <Grid item xs={12} md={6}>
                     <Paper sx={{ p: 2 }}>
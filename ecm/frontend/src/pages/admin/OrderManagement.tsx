@@ -49,7 +49,7 @@ interface OrderItem {
     product_id: string;
     product_name: string;
     quantity: number;
-    price: number;
+    price: string;
     image_url: string;
 }
 
@@ -57,27 +57,35 @@ interface Order {
     id: string;
     user_id: string;
     status: string;
-    total_amount: number;
+    total_amount: string;
     shipping_address: string;
     payment_method: string;
     created_at: string;
     updated_at: string;
+    username: string;
+    item_count: number;
     items?: OrderItem[];
 }
 
-interface User {
-    id: string;
-    username: string;
-    email: string;
-}
+const PAGE_SIZE = 20;
+
+// Mirrors the transitions the server allows; delivered and cancelled orders are final
+const NEXT_STATUSES: Record<string, string[]> = {
+    pending: ['processing', 'cancelled'],
+    processing: ['shipped', 'cancelled'],
+    shipped: ['delivered'],
+    delivered: [],
+    cancelled: [],
+};
 
 const OrderManagement: React.FC = () => {
     const [orders, setOrders] = useState<Order[]>([]);
-    const [users, setUsers] = useState<Record<string, User>>({});
     const [loading, setLoading] = useState(true);
     const [error, setError] = useState<string | null>(null);
     const [searchTerm, setSearchTerm] = useState('');
     const [statusFilter, setStatusFilter] = useState('all');
+    const [sort, setSort] = useState('created_at:desc');
+    const [page, setPage] = useState(1);
     const [selectedOrder, setSelectedOrder] = useState<Order | null>(null);
     const [openViewDialog, setOpenViewDialog] = useState(false);
     const [openEditDialog, setOpenEditDialog] = useState(false);
@@ -87,111 +95,31 @@ const OrderManagement: React.FC = () => {
 
     useEffect(() => {
         fetchOrders();
-    }, [token]);
+    }, [token, statusFilter, sort, page]);
 
     const fetchOrders = async () => {
         if (!token) return;
 
+        const [sortField, sortOrder] = sort.split(':');
         setLoading(true);
         try {
-            // In a real implementation, you would have an API endpoint like this
             const response = await axios.get(`${API_URL}/orders/all`, {
+                params: {
+                    page_id: page,
+                    page_size: PAGE_SIZE,
+                    status: statusFilter === 'all' ? undefined : statusFilter,
+                    sort: sortField,
+                    order: sortOrder,
+                },
                 headers: {
                     Authorization: `Bearer ${token}`,
                 },
             });
             setOrders(response.data);
-
-            // Fetch user details for each order
-            const userMap: Record<string, User> = {};
-
-            // Use an object to track unique user IDs instead of a Set
-            const uniqueUserIdsObj: Record<string, boolean> = {};
-            response.data.forEach((order: Order) => {
-                uniqueUserIdsObj[order.user_id] = true;
-            });
-
-            // Convert keys to array
-            const uniqueUserIds = Object.keys(uniqueUserIdsObj);
-
-            for (const userId of uniqueUserIds) {
-                try {
-                    const userResponse = await axios.get(`${API_URL}/users/${userId}`, {
-                        headers: {
-                            Authorization: `Bearer ${token}`,
-                        },
-                    });
-                    userMap[userId] = userResponse.data;
-                } catch (error) {
-                    console.error(`Error fetching user ${userId}:`, error);
-                }
-            }
-
-            setUsers(userMap);
+            setError(null);
         } catch (error) {
             console.error('Error fetching orders:', error);
-
-            // For now, generate mock data if the endpoint doesn't exist
-            const mockOrders = [
-                {
-                    id: '1',
-                    user_id: '3',
-                    status: 'pending',
-                    total_amount: 99.95,
-                    shipping_address: '123 Main St, City, Country',
-                    payment_method: 'credit_card',
-                    created_at: new Date().toISOString(),
-                    updated_at: new Date().toISOString(),
-                },
-                {
-                    id: '2',
-                    user_id: '5',
-                    status: 'processing',
-                    total_amount: 149.50,
-                    shipping_address: '456 Oak Ave, Town, Country',
-                    payment_method: 'paypal',
-                    created_at: new Date(Date.now() - 2 * 24 * 60 * 60 * 1000).toISOString(),
-                    updated_at: new Date(Date.now() - 1 * 24 * 60 * 60 * 1000).toISOString(),
-                },
-                {
-                    id: '3',
-                    user_id: '3',
-                    status: 'shipped',
-                    total_amount: 79.99,
-                    shipping_address: '789 Pine Rd, Village, Country',
-                    payment_method: 'credit_card',
-                    created_at: new Date(Date.now() - 5 * 24 * 60 * 60 * 1000).toISOString(),
-                    updated_at: new Date(Date.now() - 2 * 24 * 60 * 60 * 1000).toISOString(),
-                },
-                {
-                    id: '4',
-                    user_id: '5',
-                    status: 'delivered',
-                    total_amount: 199.95,
-                    shipping_address: '101 Elm St, County, Country',
-                    payment_method: 'bank_transfer',
-                    created_at: new Date(Date.now() - 10 * 24 * 60 * 60 * 1000).toISOString(),
-                    updated_at: new Date(Date.now() - 7 * 24 * 60 * 60 * 1000).toISOString(),
-                },
-                {
-                    id: '5',
-                    user_id: '3',
-                    status: 'cancelled',
-                    total_amount: 59.99,
-                    shipping_address: '202 Maple Dr, Valley, Country',
-                    payment_method: 'credit_card',
-                    created_at: new Date(Date.now() - 15 * 24 * 60 * 60 * 1000).toISOString(),
-                    updated_at: new Date(Date.now() - 14 * 24 * 60 * 60 * 1000).toISOString(),
-                },
-            ];
-
-            const mockUsers = {
-                '3': { id: '3', username: 'buyer1', email: 'buyer1@example.com' },
-                '5': { id: '5', username: 'buyer2', email: 'buyer2@example.com' },
-            };
-
-            setOrders(mockOrders);
-            setUsers(mockUsers);
+            setError('Failed to load orders. Please try again.');
         } finally {
             setLoading(false);
         }
@@ -218,7 +146,7 @@ const OrderManagement: React.FC = () => {
                         product_id: 'p1',
                         product_name: 'Product 1',
                         quantity: 2,
-                        price: 19.99,
+                        price: '19.99',
                         image_url: 'https://via.placeholder.com/50',
                     },
                     {
@@ -226,7 +154,7 @@ const OrderManagement: React.FC = () => {
                         product_id: 'p2',
                         product_name: 'Product 2',
                         quantity: 1,
-                        price: 59.97,
+                        price: '59.97',
                         image_url: 'https://via.placeholder.com/50',
                     }
                 ]
@@ -272,6 +200,12 @@ const OrderManagement: React.FC = () => {
 
     const handleStatusFilterChange = (event: SelectChangeEvent) => {
         setStatusFilter(event.target.value);
+        setPage(1);
+    };
+
+    const handleSortChange = (event: SelectChangeEvent) => {
+        setSort(event.target.value);
+        setPage(1);
     };
 
     const handleUpdateStatus = async () => {
@@ -297,7 +231,11 @@ const OrderManagement: React.FC = () => {
             handleCloseEditDialog();
         } catch (error) {
             console.error('Error updating order status:', error);
-            toast.error('Failed to update order status');
+            if (axios.isAxiosError(error) && error.response?.status === 409) {
+                toast.error(error.response.data.error);
+            } else {
+                toast.error('Failed to update order status');
+            }
         }
     };
 
@@ -318,17 +256,11 @@ const OrderManagement: React.FC = () => {
         }
     };
 
-    const filteredOrders = orders.filter(order => {
-        // Filter by search term
-        const matchesSearch =
-            order.id.toLowerCase().includes(searchTerm.toLowerCase()) ||
-            (users[order.user_id]?.username.toLowerCase() || '').includes(searchTerm.toLowerCase());
-
-        // Filter by status
-        const matchesStatus = statusFilter === 'all' || order.status === statusFilter;
-
-        return matchesSearch && matchesStatus;
-    });
+    // Status, sorting and paging happen on the server, the search only narrows down the current page
+    const filteredOrders = orders.filter(order =>
+        order.id.toLowerCase().includes(searchTerm.toLowerCase()) ||
+        order.username.toLowerCase().includes(searchTerm.toLowerCase())
+    );
 
     if (loading) {
         return (
@@ -383,6 +315,19 @@ const OrderManagement: React.FC = () => {
                             <MenuItem value="cancelled">Cancelled</MenuItem>
                         </Select>
                     </FormControl>
+                    <FormControl sx={{ minWidth: 160 }}>
+                        <InputLabel>Sort By</InputLabel>
+                        <Select
+                            value={sort}
+                            onChange={handleSortChange}
+                            label="Sort By"
+                        >
+                            <MenuItem value="created_at:desc">Newest first</MenuItem>
+                            <MenuItem value="created_at:asc">Oldest first</MenuItem>
+                            <MenuItem value="total_amount:desc">Highest total</MenuItem>
+                            <MenuItem value="total_amount:asc">Lowest total</MenuItem>
+                        </Select>
+                    </FormControl>
                 </Box>
             </Paper>
 
@@ -393,6 +338,7 @@ const OrderManagement: React.FC = () => {
                             <TableCell>Order ID</TableCell>
                             <TableCell>Customer</TableCell>
                             <TableCell>Date</TableCell>
+                            <TableCell>Items</TableCell>
                             <TableCell>Total</TableCell>
                             <TableCell>Status</TableCell>
                             <TableCell align="right">Actions</TableCell>
@@ -401,7 +347,7 @@ const OrderManagement: React.FC = () => {
                     <TableBody>
                         {filteredOrders.length === 0 ? (
                             <TableRow>
-                                <TableCell colSpan={6} align="center">
+                                <TableCell colSpan={7} align="center">
                                     No orders found
                                 </TableCell>
                             </TableRow>
@@ -409,11 +355,10 @@ const OrderManagement: React.FC = () => {
                             filteredOrders.map((order) => (
                                 <TableRow key={order.id}>
                                     <TableCell>{order.id.substring(0, 8)}...</TableCell>
-                                    <TableCell>
-                                        {users[order.user_id]?.username || 'Unknown'}
-                                    </TableCell>
+                                    <TableCell>{order.username}</TableCell>
                                     <TableCell>{formatDate(order.created_at)}</TableCell>
-                                    <TableCell>${order.total_amount.toFixed(2)}</TableCell>
+                                    <TableCell>{order.item_count}</TableCell>
+                                    <TableCell>${order.total_amount}</TableCell>
                                     <TableCell>
                                         <Chip
                                             label={order.status.toUpperCase()}
@@ -442,6 +387,16 @@ const OrderManagement: React.FC = () => {
                 </Table>
             </TableContainer>
 
+            <Box sx={{ display: 'flex', justifyContent: 'flex-end', alignItems: 'center', gap: 2, mt: 2 }}>
+                <Button disabled={page === 1} onClick={() => setPage(page - 1)}>
+                    Previous
+                </Button>
+                <Typography variant="body2">Page {page}</Typography>
+                <Button disabled={orders.length < PAGE_SIZE} onClick={() => setPage(page + 1)}>
+                    Next
+                </Button>
+            </Box>
+
             {/* View Order Dialog */}
             <Dialog
                 open={openViewDialog}
@@ -466,7 +421,7 @@ const OrderManagement: React.FC = () => {
                                             Customer
                                         </Typography>
                                         <Typography variant="body1">
-                                            {users[selectedOrder.user_id]?.username || 'Unknown'} ({users[selectedOrder.user_id]?.email || 'No email'})
+                                            {selectedOrder.username}
                                         </Typography>
                                     </Box>
                                     <Box sx={{ mb: 2 }}>
@@ -510,7 +465,7 @@ const OrderManagement: React.FC = () => {
                                             Total Amount
                                         </Typography>
                                         <Typography variant="body1" fontWeight="bold">
-                                            ${selectedOrder.total_amount.toFixed(2)}
+                                            ${selectedOrder.total_amount}
                                         </Typography>
                                     </Box>
                                 </Grid>
@@ -533,13 +488,13 @@ const OrderManagement: React.FC = () => {
                                                         secondary={
                                                             <>
                                                                 <Typography component="span" variant="body2">
-                                                                    ${item.price.toFixed(2)} × {item.quantity}
+                                                                    ${item.price} × {item.quantity}
                                                                 </Typography>
                                                             </>
                                                         }
                                                     />
                                                     <Typography variant="body1" sx={{ fontWeight: 'bold', minWidth: 80, textAlign: 'right' }}>
-                                                        ${(item.price * item.quantity).toFixed(2)}
+                                                        ${(Number(item.price) * item.quantity).toFixed(2)}
                                                     </Typography>
                                                 </ListItem>
                                             ))}
@@ -599,11 +554,14 @@ const OrderManagement: React.FC = () => {
                                         onChange={handleStatusChange}
                                         label="New Status"
                                     >
-                                        <MenuItem value="pending">Pending</MenuItem>
-                                        <MenuItem value="processing">Processing</MenuItem>
-                                        <MenuItem value="shipped">Shipped</MenuItem>
-                                        <MenuItem value="delivered">Delivered</MenuItem>
-                                        <MenuItem value="cancelled">Cancelled</MenuItem>
+                                        <MenuItem value={selectedOrder.status} disabled>
+                                            {selectedOrder.status.charAt(0).toUpperCase() + selectedOrder.status.slice(1)}
+                                        </MenuItem>
+                                        {NEXT_STATUSES[selectedOrder.status]?.map((status) => (
+                                            <MenuItem key={status} value={status}>
+                                                {status.charAt(0).toUpperCase() + status.slice(1)}
+                                            </MenuItem>
+                                        ))}
                                     </Select>
                                 </FormControl>
                             </Box>
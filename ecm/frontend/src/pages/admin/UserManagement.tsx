@@ -41,14 +41,18 @@ interface User {
     username: string;
     email: string;
     role: string;
-    created_at?: string;
+    suspended: boolean;
 }
 
+const PAGE_SIZE = 20;
+
 const UserManagement: React.FC = () => {
     const [users, setUsers] = useState<User[]>([]);
     const [loading, setLoading] = useState(true);
     const [error, setError] = useState<string | null>(null);
     const [searchTerm, setSearchTerm] = useState('');
+    const [roleFilter, setRoleFilter] = useState('');
+    const [page, setPage] = useState(1);
     const [selectedUser, setSelectedUser] = useState<User | null>(null);
     const [openEditDialog, setOpenEditDialog] = useState(false);
     const [openDeleteDialog, setOpenDeleteDialog] = useState(false);
@@ -58,32 +62,29 @@ const UserManagement: React.FC = () => {
 
     useEffect(() => {
         fetchUsers();
-    }, [token]);
+    }, [token, searchTerm, roleFilter, page]);
 
     const fetchUsers = async () => {
         if (!token) return;
 
         setLoading(true);
         try {
-            // In a real implementation, you would have an API endpoint like this
             const response = await axios.get(`${API_URL}/users`, {
+                params: {
+                    page_id: page,
+                    page_size: PAGE_SIZE,
+                    search: searchTerm || undefined,
+                    role: roleFilter || undefined,
+                },
                 headers: {
                     Authorization: `Bearer ${token}`,
                 },
             });
             setUsers(response.data);
+            setError(null);
         } catch (error) {
             console.error('Error fetching users:', error);
-
-            // For now, generate mock data if the endpoint doesn't exist
-            const mockUsers = [
-                { id: '1', username: 'admin', email: 'admin@example.com', role: 'admin', created_at: new Date().toISOString() },
-                { id: '2', username: 'seller1', email: 'seller1@example.com', role: 'seller', created_at: new Date().toISOString() },
-                { id: '3', username: 'buyer1', email: 'buyer1@example.com', role: 'buyer', created_at: new Date().toISOString() },
-                { id: '4', username: 'seller2', email: 'seller2@example.com', role: 'seller', created_at: new Date().toISOString() },
-                { id: '5', username: 'buyer2', email: 'buyer2@example.com', role: 'buyer', created_at: new Date().toISOString() },
-            ];
-            setUsers(mockUsers);
+            setError('Failed to load users. Please try again.');
         } finally {
             setLoading(false);
         }
@@ -126,9 +127,9 @@ const UserManagement: React.FC = () => {
             ));
 
             handleCloseEditDialog();
-        } catch (error) {
+        } catch (error: any) {
             console.error('Error updating user role:', error);
-            toast.error('Failed to update user role');
+            toast.error(error.response?.data?.error || 'Failed to update user role');
         }
     };
 
@@ -154,9 +155,31 @@ const UserManagement: React.FC = () => {
             toast.success('User deleted successfully');
             setUsers(users.filter(user => user.id !== selectedUser.id));
             handleCloseDeleteDialog();
-        } catch (error) {
+        } catch (error: any) {
             console.error('Error deleting user:', error);
-            toast.error('Failed to delete user');
+            toast.error(error.response?.data?.error || 'Failed to delete user');
+        }
+    };
+
+    const handleToggleSuspension = async (user: User) => {
+        if (!token) return;
+
+        const action = user.suspended ? 'unsuspend' : 'suspend';
+        try {
+            const response = await axios.post(
+                `${API_URL}/admin/users/${user.id}/${action}`,
+                {},
+                {
+                    headers: {
+                        Authorization: `Bearer ${token}`,
+                    },
+                }
+            );
+            toast.success(user.suspended ? 'User reactivated' : 'User suspended');
+            setUsers(users.map(u => (u.id === user.id ? response.data : u)));
+        } catch (error: any) {
+            console.error(`Error trying to ${action} user:`, error);
+            toast.error(error.response?.data?.error || `Failed to ${action} user`);
         }
     };
 
@@ -173,19 +196,6 @@ const UserManagement: React.FC = () => {
         }
     };
 
-    const filteredUsers = users.filter(user =>
-        user.username.toLowerCase().includes(searchTerm.toLowerCase()) ||
-        user.email.toLowerCase().includes(searchTerm.toLowerCase())
-    );
-
-    if (loading) {
-        return (
-            <Container maxWidth="lg" sx={{ py: 4, textAlign: 'center' }}>
-                <CircularProgress />
-            </Container>
-        );
-    }
-
     return (
         <Container maxWidth="lg" sx={{ py: 4 }}>
             <Box sx={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center', mb: 3 }}>
@@ -200,13 +210,16 @@ const UserManagement: React.FC = () => {
 
             {error && <Alert severity="error" sx={{ mb: 3 }}>{error}</Alert>}
 
-            <Paper sx={{ p: 2, mb: 3 }}>
+            <Paper sx={{ p: 2, mb: 3, display: 'flex', gap: 2 }}>
                 <TextField
                     fullWidth
                     variant="outlined"
                     placeholder="Search users by username or email..."
                     value={searchTerm}
-                    onChange={(e) => setSearchTerm(e.target.value)}
+                    onChange={(e) => {
+                        setSearchTerm(e.target.value);
+                        setPage(1);
+                    }}
                     InputProps={{
                         startAdornment: (
                             <InputAdornment position="start">
@@ -215,6 +228,22 @@ const UserManagement: React.FC = () => {
                         ),
                     }}
                 />
+                <FormControl sx={{ minWidth: 160 }}>
+                    <InputLabel>Role</InputLabel>
+                    <Select
+                        value={roleFilter}
+                        onChange={(e: SelectChangeEvent) => {
+                            setRoleFilter(e.target.value);
+                            setPage(1);
+                        }}
+                        label="Role"
+                    >
+                        <MenuItem value="">All</MenuItem>
+                        <MenuItem value="admin">Admin</MenuItem>
+                        <MenuItem value="seller">Seller</MenuItem>
+                        <MenuItem value="buyer">Buyer</MenuItem>
+                    </Select>
+                </FormControl>
             </Paper>
 
             <TableContainer component={Paper}>
@@ -224,18 +253,25 @@ const UserManagement: React.FC = () => {
                             <TableCell>Username</TableCell>
                             <TableCell>Email</TableCell>
                             <TableCell>Role</TableCell>
+                            <TableCell>Status</TableCell>
                             <TableCell align="right">Actions</TableCell>
                         </TableRow>
                     </TableHead>
                     <TableBody>
-                        {filteredUsers.length === 0 ? (
+                        {loading ? (
                             <TableRow>
-                                <TableCell colSpan={4} align="center">
+                                <TableCell colSpan={5} align="center">
+                                    <CircularProgress />
+                                </TableCell>
+                            </TableRow>
+                        ) : users.length === 0 ? (
+                            <TableRow>
+                                <TableCell colSpan={5} align="center">
                                     No users found
                                 </TableCell>
                             </TableRow>
                         ) : (
-                            filteredUsers.map((user) => (
+                            users.map((user) => (
                                 <TableRow key={user.id}>
                                     <TableCell>{user.username}</TableCell>
                                     <TableCell>{user.email}</TableCell>
@@ -246,7 +282,22 @@ const UserManagement: React.FC = () => {
                                             size="small"
                                         />
                                     </TableCell>
+                                    <TableCell>
+                                        <Chip
+                                            label={user.suspended ? 'SUSPENDED' : 'ACTIVE'}
+                                            color={user.suspended ? 'warning' : 'default'}
+                                            size="small"
+                                        />
+                                    </TableCell>
                                     <TableCell align="right">
+                                        <Button
+                                            size="small"
+                                            color={user.suspended ? 'primary' : 'warning'}
+                                            onClick={() => handleToggleSuspension(user)}
+                                            disabled={user.id === currentUser?.id}
+                                        >
+                                            {user.suspended ? 'Reactivate' : 'Suspend'}
+                                        </Button>
                                         <IconButton
                                             color="primary"
                                             onClick={() => handleEditClick(user)}
@@ -269,6 +320,16 @@ const UserManagement: React.FC = () => {
                 </Table>
             </TableContainer>
 
+            <Box sx={{ display: 'flex', justifyContent: 'flex-end', alignItems: 'center', gap: 2, mt: 2 }}>
+                <Button disabled={page === 1 || loading} onClick={() => setPage(page - 1)}>
+                    Previous
+                </Button>
+                <Typography variant="body2">Page {page}</Typography>
+                <Button disabled={users.length < PAGE_SIZE || loading} onClick={() => setPage(page + 1)}>
+                    Next
+                </Button>
+            </Box>
+
             {/* Edit Role Dialog */}
             <Dialog
                 open={openEditDialog}
@@ -40,7 +40,7 @@ interface Product {
     id: string;
     name: string;
     description: string;
-    price: number;
+    price: string;
     stock_quantity: number;
     shop_id: string;
     category_id: string;
@@ -73,7 +73,7 @@ const ProductManagement: React.FC = () => {
     const [formData, setFormData] = useState({
         name: '',
         description: '',
-        price: 0,
+        price: '',
         stock_quantity: 0,
         shop_id: '',
         category_id: '',
@@ -159,7 +159,7 @@ const ProductManagement: React.FC = () => {
             setFormData({
                 name: '',
                 description: '',
-                price: 0,
+                price: '',
                 stock_quantity: 0,
                 shop_id: selectedShop,
                 category_id: categories.length > 0 ? categories[0].id : '',
@@ -178,7 +178,7 @@ const ProductManagement: React.FC = () => {
         const { name, value } = e.target;
         setFormData({
             ...formData,
-            [name]: name === 'price' || name === 'stock_quantity' ? Number(value) : value,
+            [name]: name === 'stock_quantity' ? Number(value) : value,
         });
     };
 
@@ -357,7 +357,7 @@ const ProductManagement: React.FC = () => {
                                                 )}
                                             </TableCell>
                                             <TableCell>{product.name}</TableCell>
-                                            <TableCell>${product.price.toFixed(2)}</TableCell>
+                                            <TableCell>${product.price}</TableCell>
                                             <TableCell>{product.stock_quantity}</TableCell>
                                             <TableCell align="right">
                                                 <IconButton
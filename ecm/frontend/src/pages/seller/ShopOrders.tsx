@@ -0,0 +1,287 @@
+import React, { useState, useEffect } from 'react';
+import axios from 'axios';
+import {
+    Container,
+    Typography,
+    Paper,
+    Table,
+    TableBody,
+    TableCell,
+    TableContainer,
+    TableHead,
+    TableRow,
+    Box,
+    Chip,
+    CircularProgress,
+    Alert,
+    MenuItem,
+    FormControl,
+    InputLabel,
+    Select,
+    SelectChangeEvent,
+} from '@mui/material';
+import { toast } from 'react-toastify';
+import { useAuth } from '../../contexts/AuthContext';
+import { API_URL } from '../../config/constants';
+
+interface Shop {
+    id: string;
+    name: string;
+}
+
+interface ShopOrderItem {
+    id: string;
+    product_name: string;
+    quantity: number;
+    price: string;
+}
+
+interface ShopOrder {
+    id: string;
+    order_id: string;
+    status: string;
+    subtotal: string;
+    username: string;
+    shipping_address: string;
+    created_at: string;
+    items: ShopOrderItem[];
+}
+
+// Statuses a shop order can move to from each status, as enforced by the API
+const NEXT_STATUSES: Record<string, string[]> = {
+    pending: ['processing', 'cancelled'],
+    processing: ['shipped', 'cancelled'],
+    shipped: ['delivered'],
+    delivered: [],
+    cancelled: [],
+};
+
+const ShopOrders: React.FC = () => {
+    const [shops, setShops] = useState<Shop[]>([]);
+    const [selectedShop, setSelectedShop] = useState<string>('');
+    const [shopOrders, setShopOrders] = useState<ShopOrder[]>([]);
+    const [statusFilter, setStatusFilter] = useState<string>('');
+    const [loading, setLoading] = useState(true);
+    const [error, setError] = useState<string | null>(null);
+    const { token } = useAuth();
+
+    useEffect(() => {
+        fetchShops();
+    }, []);
+
+    useEffect(() => {
+        if (selectedShop) {
+            fetchShopOrders();
+        }
+    }, [selectedShop, statusFilter]);
+
+    const fetchShops = async () => {
+        if (!token) return;
+        try {
+            const response = await axios.get(`${API_URL}/shops`, {
+                headers: {
+                    Authorization: `Bearer ${token}`,
+                },
+            });
+            setShops(response.data);
+            if (response.data.length > 0) {
+                setSelectedShop(response.data[0].id);
+            } else {
+                setLoading(false);
+            }
+        } catch (error) {
+            console.error('Error fetching shops:', error);
+            setError('Failed to load shops');
+            setLoading(false);
+        }
+    };
+
+    const fetchShopOrders = async () => {
+        if (!token || !selectedShop) return;
+        setLoading(true);
+        try {
+            const params = new URLSearchParams({ page_id: '1', page_size: '50' });
+            if (statusFilter) {
+                params.append('status', statusFilter);
+            }
+            const response = await axios.get(`${API_URL}/shops/${selectedShop}/orders?${params.toString()}`, {
+                headers: {
+                    Authorization: `Bearer ${token}`,
+                },
+            });
+            setShopOrders(response.data);
+            setError(null);
+        } catch (error) {
+            console.error('Error fetching shop orders:', error);
+            setError('Failed to load orders');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    const handleStatusChange = async (shopOrder: ShopOrder, status: string) => {
+        if (!token) return;
+        try {
+            const response = await axios.patch(
+                `${API_URL}/shops/${selectedShop}/orders/${shopOrder.id}/status`,
+                { status },
+                {
+                    headers: {
+                        Authorization: `Bearer ${token}`,
+                    },
+                }
+            );
+            setShopOrders(
+                shopOrders.map((o) => (o.id === shopOrder.id ? { ...o, status: response.data.status } : o))
+            );
+            toast.success('Order status updated');
+        } catch (error) {
+            console.error('Error updating shop order status:', error);
+            if (axios.isAxiosError(error) && error.response?.status === 409) {
+                toast.error(error.response.data.error);
+            } else {
+                toast.error('Failed to update order status');
+            }
+        }
+    };
+
+    const getStatusColor = (status: string) => {
+        switch (status) {
+            case 'pending':
+                return 'warning';
+            case 'processing':
+                return 'info';
+            case 'shipped':
+                return 'primary';
+            case 'delivered':
+                return 'success';
+            case 'cancelled':
+                return 'error';
+            default:
+                return 'default';
+        }
+    };
+
+    if (!loading && !shops.length) {
+        return (
+            <Container maxWidth="lg" sx={{ py: 4 }}>
+                <Alert severity="info">You don't have any shops yet.</Alert>
+            </Container>
+        );
+    }
+
+    return (
+        <Container maxWidth="lg" sx={{ py: 4 }}>
+            <Typography variant="h4" gutterBottom>
+                Shop Orders
+            </Typography>
+            <Box sx={{ display: 'flex', gap: 2, mb: 3 }}>
+                <FormControl fullWidth>
+                    <InputLabel>Select Shop</InputLabel>
+                    <Select
+                        value={selectedShop}
+                        onChange={(e: SelectChangeEvent) => setSelectedShop(e.target.value)}
+                        label="Select Shop"
+                    >
+                        {shops.map((shop) => (
+                            <MenuItem key={shop.id} value={shop.id}>
+                                {shop.name}
+                            </MenuItem>
+                        ))}
+                    </Select>
+                </FormControl>
+                <FormControl sx={{ minWidth: 200 }}>
+                    <InputLabel>Status</InputLabel>
+                    <Select
+                        value={statusFilter}
+                        onChange={(e: SelectChangeEvent) => setStatusFilter(e.target.value)}
+                        label="Status"
+                    >
+                        <MenuItem value="">All</MenuItem>
+                        {Object.keys(NEXT_STATUSES).map((status) => (
+                            <MenuItem key={status} value={status}>
+                                {status.charAt(0).toUpperCase() + status.slice(1)}
+                            </MenuItem>
+                        ))}
+                    </Select>
+                </FormControl>
+            </Box>
+
+            {loading ? (
+                <Box sx={{ display: 'flex', justifyContent: 'center', my: 4 }}>
+                    <CircularProgress />
+                </Box>
+            ) : error ? (
+                <Alert severity="error">{error}</Alert>
+            ) : shopOrders.length === 0 ? (
+                <Paper sx={{ p: 3, textAlign: 'center' }}>
+                    <Typography variant="body1">No orders found for this shop.</Typography>
+                </Paper>
+            ) : (
+                <TableContainer component={Paper}>
+                    <Table>
+                        <TableHead>
+                            <TableRow>
+                                <TableCell>Placed</TableCell>
+                                <TableCell>Buyer</TableCell>
+                                <TableCell>Items</TableCell>
+                                <TableCell>Ship To</TableCell>
+                                <TableCell>Subtotal</TableCell>
+                                <TableCell>Status</TableCell>
+                                <TableCell align="right">Move To</TableCell>
+                            </TableRow>
+                        </TableHead>
+                        <TableBody>
+                            {shopOrders.map((shopOrder) => (
+                                <TableRow key={shopOrder.id}>
+                                    <TableCell>{shopOrder.created_at.substring(0, 16)}</TableCell>
+                                    <TableCell>{shopOrder.username}</TableCell>
+                                    <TableCell>
+                                        {shopOrder.items.map((item) => (
+                                            <div key={item.id}>
+                                                {item.quantity} × {item.product_name}
+                                            </div>
+                                        ))}
+                                    </TableCell>
+                                    <TableCell>{shopOrder.shipping_address}</TableCell>
+                                    <TableCell>${shopOrder.subtotal}</TableCell>
+                                    <TableCell>
+                                        <Chip
+                                            label={shopOrder.status.toUpperCase()}
+                                            color={getStatusColor(shopOrder.status) as any}
+                                            size="small"
+                                        />
+                                    </TableCell>
+                                    <TableCell align="right">
+                                        {NEXT_STATUSES[shopOrder.status]?.length > 0 && (
+                                            <FormControl size="small" sx={{ minWidth: 140 }}>
+                                                <Select
+                                                    value=""
+                                                    displayEmpty
+                                                    onChange={(e: SelectChangeEvent) =>
+                                                        handleStatusChange(shopOrder, e.target.value)
+                                                    }
+                                                >
+                                                    <MenuItem value="" disabled>
+                                                        Choose...
+                                                    </MenuItem>
+                                                    {NEXT_STATUSES[shopOrder.status].map((status) => (
+                                                        <MenuItem key={status} value={status}>
+                                                            {status.charAt(0).toUpperCase() + status.slice(1)}
+                                                        </MenuItem>
+                                                    ))}
+                                                </Select>
+                                            </FormControl>
+                                        )}
+                                    </TableCell>
+                                </TableRow>
+                            ))}
+                        </TableBody>
+                    </Table>
+                </TableContainer>
+            )}
+        </Container>
+    );
+};
+
+export default ShopOrders;
@@ -0,0 +1,84 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is an email to be delivered
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender is an interface for delivering emails
+type Sender interface {
+	// Send delivers the message
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender creates the sender selected by driver: "log" or "file"
+func NewSender(driver string, from string, dir string) (Sender, error) {
+	switch driver {
+	case "log":
+		return NewLogSender(from), nil
+	case "file":
+		return NewFileSender(from, dir)
+	default:
+		return nil, fmt.Errorf("unsupported mail driver %q", driver)
+	}
+}
+
+// LogSender writes emails to the application log, for local development
+type LogSender struct {
+	from string
+}
+
+// NewLogSender creates a new LogSender
+func NewLogSender(from string) *LogSender {
+	return &LogSender{from}
+}
+
+// Send logs the message
+func (sender *LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail from %s to %s: %s\n%s", sender.from, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileSender writes each email to its own file in a directory, for local development
+type FileSender struct {
+	from string
+	dir  string
+}
+
+// NewFileSender creates a new FileSender, creating the directory if needed
+func NewFileSender(from string, dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create mail directory: %w", err)
+	}
+	return &FileSender{from: from, dir: dir}, nil
+}
+
+// Send writes the message to a new .eml file
+func (sender *FileSender) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405"), uuid.NewString())
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "From: %s\r\n", sender.from)
+	fmt.Fprintf(&content, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&content, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&content, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	content.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	content.WriteString(msg.Body)
+
+	return os.WriteFile(filepath.Join(sender.dir, name), []byte(content.String()), 0o644)
+}
@@ -0,0 +1,172 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidAmount is returned for text that is not an amount with at most two decimal places
+var ErrInvalidAmount = errors.New("amount is invalid")
+
+// Amount is an amount of money in cents. Prices are stored as DECIMAL(10, 2), which cents hold
+// exactly, so amounts can be added up and multiplied by quantities without float rounding errors.
+type Amount int64
+
+// MaxDecimal is the largest amount a DECIMAL(10, 2) column holds, 99999999.99
+const MaxDecimal Amount = 99999999_99
+
+// Parse parses a decimal amount such as "12.34", "-5" or ".5". Decimal places past the cents must be
+// zero, an amount is never rounded, so "0.005" and "1.999" are rejected. Exponents such as "1e2" are
+// not accepted either, and the whole units must stay below math.MaxInt64/100 for the cents to fit.
+func Parse(s string) (Amount, error) {
+	text := strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+") {
+		negative = text[0] == '-'
+		text = text[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(text, ".")
+	if whole == "" && fraction == "" || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > 2 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	var units int64
+	if whole != "" {
+		var err error
+		units, err = strconv.ParseInt(whole, 10, 64)
+		if err != nil || units >= math.MaxInt64/100 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+	}
+
+	cents, _ := strconv.ParseInt(fraction+strings.Repeat("0", 2-len(fraction)), 10, 64)
+	amount := Amount(units*100 + cents)
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParse is like Parse but panics if the amount is invalid
+func MustParse(s string) Amount {
+	amount, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+// Mul multiplies the amount by a quantity
+func (a Amount) Mul(quantity int32) Amount {
+	return a * Amount(quantity)
+}
+
+// String formats the amount with two decimal places, such as "12.30"
+func (a Amount) String() string {
+	sign := ""
+	cents := int64(a)
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// MarshalJSON encodes the amount as a string, so clients do not read it into a float by accident
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON decodes an amount from either a string or a number
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	text := string(data)
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+
+	amount, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+// Scan reads the amount from a DECIMAL column
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return a.scanText(string(v))
+	case string:
+		return a.scanText(v)
+	case int64:
+		*a = Amount(v * 100)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into money.Amount", src)
+	}
+}
+
+func (a *Amount) scanText(text string) error {
+	amount, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+// Value writes the amount to a DECIMAL column
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// NullAmount is an Amount that may be NULL
+type NullAmount struct {
+	Amount Amount
+	Valid  bool // Valid is true if Amount is not NULL
+}
+
+// Scan reads the amount from a DECIMAL column that may be NULL
+func (n *NullAmount) Scan(src interface{}) error {
+	if src == nil {
+		n.Amount, n.Valid = 0, false
+		return nil
+	}
+	n.Valid = true
+	return n.Amount.Scan(src)
+}
+
+// Value writes the amount to a DECIMAL column, or NULL if it is not valid
+func (n NullAmount) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Amount.Value()
+}
@@ -0,0 +1,207 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name  string
+		text  string
+		want  Amount
+		valid bool
+	}{
+		{name: "Whole", text: "12", want: 1200, valid: true},
+		{name: "Cents", text: "12.34", want: 1234, valid: true},
+		{name: "OneDecimal", text: "12.3", want: 1230, valid: true},
+		{name: "Zero", text: "0", want: 0, valid: true},
+		{name: "Negative", text: "-0.01", want: -1, valid: true},
+		{name: "Plus", text: "+1", want: 100, valid: true},
+		{name: "TrailingPoint", text: "1.", want: 100, valid: true},
+		{name: "LeadingPoint", text: ".5", want: 50, valid: true},
+		{name: "TrailingZeros", text: "1.500", want: 150, valid: true},
+		{name: "Spaces", text: " 7.05 ", want: 705, valid: true},
+		// Amounts are never rounded, so fractions of a cent are rejected rather than rounded
+		{name: "HalfCent", text: "0.005", valid: false},
+		{name: "FractionOfCent", text: "1.999", valid: false},
+		{name: "Exponent", text: "1e2", valid: false},
+		{name: "Empty", text: "", valid: false},
+		{name: "SignOnly", text: "-", valid: false},
+		{name: "PointOnly", text: ".", valid: false},
+		{name: "TwoSigns", text: "--1", valid: false},
+		{name: "TwoPoints", text: "1.2.3", valid: false},
+		{name: "Letters", text: "abc", valid: false},
+		{name: "Comma", text: "1,50", valid: false},
+		// Whole units must stay below MaxInt64/100 so that the amount in cents cannot overflow
+		{name: "Largest", text: "92233720368547757.99", want: 9223372036854775799, valid: true},
+		{name: "LargestNegative", text: "-92233720368547757.99", want: -9223372036854775799, valid: true},
+		{name: "TooLarge", text: "92233720368547758", valid: false},
+		{name: "Overflow", text: "9223372036854775808", valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse(tc.text)
+			if !tc.valid {
+				if !errors.Is(err, ErrInvalidAmount) {
+					t.Fatalf("Parse(%q) = %d, %v; want ErrInvalidAmount", tc.text, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tc.text, err)
+			}
+			if got != tc.want {
+				t.Fatalf("Parse(%q) = %d; want %d", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	testCases := []struct {
+		amount Amount
+		want   string
+	}{
+		{amount: 0, want: "0.00"},
+		{amount: 5, want: "0.05"},
+		{amount: -1, want: "-0.01"},
+		{amount: 1230, want: "12.30"},
+		{amount: -1234, want: "-12.34"},
+		{amount: MaxDecimal, want: "99999999.99"},
+		{amount: 9223372036854775799, want: "92233720368547757.99"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			if got := tc.amount.String(); got != tc.want {
+				t.Fatalf("Amount(%d).String() = %q; want %q", int64(tc.amount), got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMul(t *testing.T) {
+	if got := MustParse("19.99").Mul(3); got != 5997 {
+		t.Fatalf("19.99 * 3 = %d; want 5997", got)
+	}
+}
+
+func TestScan(t *testing.T) {
+	testCases := []struct {
+		name  string
+		src   interface{}
+		want  Amount
+		valid bool
+	}{
+		{name: "Bytes", src: []byte("12.34"), want: 1234, valid: true},
+		{name: "String", src: "0.50", want: 50, valid: true},
+		{name: "Int64", src: int64(12), want: 1200, valid: true},
+		{name: "NegativeInt64", src: int64(-3), want: -300, valid: true},
+		{name: "InvalidBytes", src: []byte("1.234"), valid: false},
+		{name: "InvalidString", src: "abc", valid: false},
+		{name: "Float64", src: 12.34, valid: false},
+		{name: "Nil", src: nil, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got Amount
+			err := got.Scan(tc.src)
+			if !tc.valid {
+				if err == nil {
+					t.Fatalf("Scan(%#v) = %d; want an error", tc.src, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan(%#v) returned error: %v", tc.src, err)
+			}
+			if got != tc.want {
+				t.Fatalf("Scan(%#v) = %d; want %d", tc.src, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNullAmountScan(t *testing.T) {
+	var null NullAmount
+	if err := null.Scan(nil); err != nil || null.Valid {
+		t.Fatalf("Scan(nil) = %+v, %v; want an invalid amount", null, err)
+	}
+
+	if err := null.Scan([]byte("3.10")); err != nil || !null.Valid || null.Amount != 310 {
+		t.Fatalf("Scan(3.10) = %+v, %v; want a valid 310", null, err)
+	}
+
+	value, err := NullAmount{}.Value()
+	if err != nil || value != nil {
+		t.Fatalf("Value() = %v, %v; want nil", value, err)
+	}
+}
+
+func TestValue(t *testing.T) {
+	value, err := Amount(-1234).Value()
+	if err != nil || value != "-12.34" {
+		t.Fatalf("Value() = %v, %v; want -12.34", value, err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type item struct {
+		Price Amount  `json:"price"`
+		Total *Amount `json:"total"`
+	}
+
+	total := Amount(-250)
+	data, err := json.Marshal(item{Price: 1999, Total: &total})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"price":"19.99","total":"-2.50"}`; string(data) != want {
+		t.Fatalf("Marshal = %s; want %s", data, want)
+	}
+
+	var decoded item
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Price != 1999 || decoded.Total == nil || *decoded.Total != -250 {
+		t.Fatalf("Unmarshal(%s) = %+v; want the marshaled item back", data, decoded)
+	}
+
+	testCases := []struct {
+		name  string
+		data  string
+		want  Amount
+		valid bool
+	}{
+		{name: "String", data: `"12.30"`, want: 1230, valid: true},
+		{name: "Number", data: `12.3`, want: 1230, valid: true},
+		{name: "Null", data: `null`, want: 0, valid: true},
+		{name: "FractionOfCent", data: `"0.005"`, valid: false},
+		{name: "Exponent", data: `1e2`, valid: false},
+		{name: "Bool", data: `true`, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got Amount
+			err := json.Unmarshal([]byte(tc.data), &got)
+			if !tc.valid {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) = %d; want an error", tc.data, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s) returned error: %v", tc.data, err)
+			}
+			if got != tc.want {
+				t.Fatalf("Unmarshal(%s) = %d; want %d", tc.data, got, tc.want)
+			}
+		})
+	}
+}
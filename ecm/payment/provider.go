@@ -0,0 +1,254 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qhh/ecm/money"
+)
+
+// Status is where a payment stands with the provider
+type Status string
+
+const (
+	// StatusPending waits for the provider or the buyer to finish the payment
+	StatusPending Status = "pending"
+	// StatusRequiresAction waits for the buyer to authenticate the card with 3-D Secure
+	StatusRequiresAction Status = "requires_action"
+	StatusAuthorized     Status = "authorized"
+	StatusCaptured       Status = "captured"
+	StatusDeclined       Status = "declined"
+	StatusVoided         Status = "voided"
+)
+
+// ErrInvalidPayment is returned for operations on a payment the provider does not know
+var ErrInvalidPayment = errors.New("payment is unknown to the provider")
+
+// Card is a payment card. Card details are passed on to the provider and never stored.
+type Card struct {
+	Number   string
+	ExpMonth int
+	ExpYear  int
+	CVC      string
+}
+
+// Last4 returns the last four digits of the card number
+func (card Card) Last4() string {
+	if len(card.Number) < 4 {
+		return card.Number
+	}
+	return card.Number[len(card.Number)-4:]
+}
+
+// AuthorizeRequest asks a provider to reserve an amount for an order
+type AuthorizeRequest struct {
+	// Reference identifies the payment on our side
+	Reference string
+	Amount    money.Amount
+	// Method is the payment method the buyer chose, such as "credit_card"
+	Method string
+	// Card is required for the credit_card method
+	Card *Card
+	// ThreeDSecure is the outcome of authenticating the card, when the provider asked for it
+	ThreeDSecure string
+}
+
+// Result is what a provider reports back about a payment
+type Result struct {
+	// PaymentID identifies the payment with the provider
+	PaymentID string
+	Status    Status
+	// Message explains why a payment was declined or what the buyer has to do next
+	Message string
+}
+
+// Provider is an interface for taking payments
+type Provider interface {
+	// Name identifies the provider in payments
+	Name() string
+	// Authorize reserves the amount. The result can also be declined or need more from the buyer.
+	Authorize(ctx context.Context, req AuthorizeRequest) (Result, error)
+	// Capture collects an authorized amount
+	Capture(ctx context.Context, paymentID string, amount money.Amount) (Result, error)
+	// Void releases an authorization that was not captured. Voiding it again succeeds as well.
+	Void(ctx context.Context, paymentID string) (Result, error)
+	// Refund pays back some or all of a captured amount and returns the refund's ID. Refunds sent
+	// again with the same idempotency key are only paid once, and return the first refund's ID.
+	Refund(ctx context.Context, paymentID string, amount money.Amount, idempotencyKey string) (string, error)
+	// ParseWebhook checks the signature of a webhook sent by the provider and reads its event
+	ParseWebhook(payload []byte, signature string) (WebhookEvent, error)
+}
+
+// NewProvider creates the provider selected by driver. Only "mock" is supported for now. Webhooks
+// are signed with webhookSecret.
+func NewProvider(driver string, webhookSecret string) (Provider, error) {
+	switch driver {
+	case "mock":
+		return NewMockProvider(webhookSecret), nil
+	default:
+		return nil, fmt.Errorf("unsupported payment provider %q", driver)
+	}
+}
+
+// Test card numbers understood by MockProvider
+const (
+	MockCardSuccess      = "4242424242424242"
+	MockCardDecline      = "4000000000000002"
+	MockCardThreeDSecure = "4000000000003220"
+)
+
+// Outcomes of 3-D Secure authentication for MockCardThreeDSecure
+const (
+	MockThreeDSecurePassed = "passed"
+	MockThreeDSecureFailed = "failed"
+)
+
+const mockPaymentPrefix = "mock_pay_"
+
+// MockProvider is a fake provider for local development and testing. Card payments succeed, are
+// declined or need 3-D Secure depending on the test card number. Every other method is authorized
+// straight away. It keeps no state, so it keeps working across restarts.
+type MockProvider struct {
+	webhookSecret string
+}
+
+// NewMockProvider creates a new MockProvider
+func NewMockProvider(webhookSecret string) *MockProvider {
+	return &MockProvider{
+		webhookSecret: webhookSecret,
+	}
+}
+
+// Name returns "mock"
+func (provider *MockProvider) Name() string {
+	return "mock"
+}
+
+// Authorize decides the outcome from the test card number
+func (provider *MockProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Result, error) {
+	result := Result{
+		PaymentID: mockPaymentPrefix + uuid.NewString(),
+		Status:    StatusAuthorized,
+	}
+	if req.Method != "credit_card" {
+		return result, nil
+	}
+	if req.Card == nil {
+		return Result{}, errors.New("card is required for credit_card payments")
+	}
+
+	switch req.Card.Number {
+	case MockCardSuccess:
+	case MockCardDecline:
+		result.Status = StatusDeclined
+		result.Message = "Your card was declined."
+	case MockCardThreeDSecure:
+		switch req.ThreeDSecure {
+		case MockThreeDSecurePassed:
+		case MockThreeDSecureFailed:
+			result.Status = StatusDeclined
+			result.Message = "3-D Secure authentication failed."
+		default:
+			result.Status = StatusRequiresAction
+			result.Message = "Your bank asks you to confirm this payment with 3-D Secure."
+		}
+	default:
+		result.Status = StatusDeclined
+		result.Message = "Use one of the test card numbers."
+	}
+	return result, nil
+}
+
+// Capture always succeeds for payments that MockProvider authorized
+func (provider *MockProvider) Capture(ctx context.Context, paymentID string, amount money.Amount) (Result, error) {
+	if err := provider.check(paymentID); err != nil {
+		return Result{}, err
+	}
+	return Result{PaymentID: paymentID, Status: StatusCaptured}, nil
+}
+
+// Void always succeeds for payments that MockProvider authorized
+func (provider *MockProvider) Void(ctx context.Context, paymentID string) (Result, error) {
+	if err := provider.check(paymentID); err != nil {
+		return Result{}, err
+	}
+	return Result{PaymentID: paymentID, Status: StatusVoided}, nil
+}
+
+// Refund always succeeds for payments that MockProvider authorized. The refund's ID is derived from
+// the idempotency key, so that sending a refund again gives back the same refund.
+func (provider *MockProvider) Refund(ctx context.Context, paymentID string, amount money.Amount, idempotencyKey string) (string, error) {
+	if err := provider.check(paymentID); err != nil {
+		return "", err
+	}
+	if amount <= 0 {
+		return "", fmt.Errorf("cannot refund %s", amount)
+	}
+	if idempotencyKey == "" {
+		return "", errors.New("idempotency key is required")
+	}
+	return "mock_re_" + idempotencyKey, nil
+}
+
+// mockWebhook is the payload of a webhook for MockProvider
+type mockWebhook struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		PaymentID string `json:"payment_id"`
+		Status    Status `json:"status"`
+		Message   string `json:"message"`
+	} `json:"data"`
+}
+
+// ParseWebhook reads a webhook signed with Sign. Its payload looks like
+//
+//	{"id": "evt_1", "type": "payment.captured", "created": 1700000000,
+//	 "data": {"payment_id": "mock_pay_...", "status": "captured", "message": ""}}
+//
+// where status is authorized, captured, declined or voided.
+func (provider *MockProvider) ParseWebhook(payload []byte, signature string) (WebhookEvent, error) {
+	err := VerifySignature(payload, signature, provider.webhookSecret, time.Now())
+	if err != nil {
+		return WebhookEvent{}, err
+	}
+
+	var webhook mockWebhook
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return WebhookEvent{}, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	if webhook.ID == "" || webhook.Type == "" {
+		return WebhookEvent{}, fmt.Errorf("%w: id and type are required", ErrInvalidWebhook)
+	}
+	if err := provider.check(webhook.Data.PaymentID); err != nil {
+		return WebhookEvent{}, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+
+	switch webhook.Data.Status {
+	case StatusAuthorized, StatusCaptured, StatusDeclined, StatusVoided:
+	default:
+		return WebhookEvent{}, fmt.Errorf("%w: unexpected status %q", ErrInvalidWebhook, webhook.Data.Status)
+	}
+
+	return WebhookEvent{
+		ID:        webhook.ID,
+		Type:      webhook.Type,
+		PaymentID: webhook.Data.PaymentID,
+		Status:    webhook.Data.Status,
+		Message:   webhook.Data.Message,
+		CreatedAt: time.Unix(webhook.Created, 0),
+	}, nil
+}
+
+func (provider *MockProvider) check(paymentID string) error {
+	if !strings.HasPrefix(paymentID, mockPaymentPrefix) {
+		return fmt.Errorf("%w: %q", ErrInvalidPayment, paymentID)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the request header that carries the signature of a webhook
+const SignatureHeader = "Payment-Signature"
+
+// SignatureTolerance is how long a webhook signature stays valid, so that a request that was
+// intercepted cannot be sent again later
+const SignatureTolerance = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature is returned for webhooks that were not signed with the webhook secret
+	ErrInvalidSignature = errors.New("webhook signature is invalid")
+	// ErrInvalidWebhook is returned for webhooks that do not describe a payment update
+	ErrInvalidWebhook = errors.New("webhook payload is invalid")
+)
+
+// WebhookEvent is an update on a payment that a provider sends on its own, for payments that are
+// confirmed after the request that made them
+type WebhookEvent struct {
+	// ID identifies the event with the provider, which can send the same event more than once
+	ID   string
+	Type string
+	// PaymentID identifies the payment with the provider
+	PaymentID string
+	Status    Status
+	Message   string
+	CreatedAt time.Time
+}
+
+// Sign signs a webhook payload with secret at the given time. The signature has the form
+// "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<payload>">".
+func Sign(payload []byte, secret string, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, signature(payload, secret, timestamp))
+}
+
+// VerifySignature checks that a webhook payload was signed with secret by Sign, no longer than
+// SignatureTolerance ago. The signature can hold several v1 values while the secret is being rotated.
+// Nothing is valid without a secret.
+func VerifySignature(payload []byte, header string, secret string, now time.Time) error {
+	if secret == "" {
+		return fmt.Errorf("%w: no webhook secret is configured", ErrInvalidSignature)
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return fmt.Errorf("%w: expected t=<unix time>,v1=<signature>", ErrInvalidSignature)
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+	if age > SignatureTolerance || age < -SignatureTolerance {
+		return fmt.Errorf("%w: signed %s ago, more than %s", ErrInvalidSignature, age.Round(time.Second), SignatureTolerance)
+	}
+
+	expected := signature(payload, secret, timestamp)
+	for _, s := range signatures {
+		if hmac.Equal([]byte(s), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func signature(payload []byte, secret string, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,89 @@
+package payment
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"payment.captured","payment_id":"pay_1","status":"captured"}`)
+	secret := "whsec_current"
+	now := time.Unix(1700000000, 0)
+
+	signed := Sign(payload, secret, now)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	v1 := strings.TrimPrefix(signed, "t="+timestamp+",")
+	oldV1 := strings.TrimPrefix(Sign(payload, "whsec_old", now), "t="+timestamp+",")
+
+	testCases := []struct {
+		name    string
+		payload []byte
+		header  string
+		secret  string
+		now     time.Time
+		valid   bool
+	}{
+		{name: "Valid", header: signed, valid: true},
+		{name: "SpacesAfterCommas", header: "t=" + timestamp + ", " + v1, valid: true},
+		{name: "SignatureFirst", header: v1 + ",t=" + timestamp, valid: true},
+		{name: "UnknownSchemeIgnored", header: signed + ",v0=abc", valid: true},
+		{name: "WrongSecret", header: signed, secret: "whsec_other"},
+		{name: "TamperedPayload", payload: []byte(`{"id":"evt_1","type":"payment.captured","payment_id":"pay_2","status":"captured"}`), header: signed},
+		{name: "TamperedTimestamp", header: "t=" + strconv.FormatInt(now.Unix()+1, 10) + "," + v1},
+		{name: "UppercaseSignature", header: "t=" + timestamp + "," + strings.ToUpper(v1)},
+		{name: "Empty", header: ""},
+		{name: "NoTimestamp", header: v1},
+		{name: "NoSignature", header: "t=" + timestamp},
+		{name: "BadTimestamp", header: "t=yesterday," + v1},
+		// Signatures are accepted within SignatureTolerance either way, to allow for clock skew
+		{name: "AtTolerance", header: signed, now: now.Add(SignatureTolerance), valid: true},
+		{name: "TooOld", header: signed, now: now.Add(SignatureTolerance + time.Second)},
+		{name: "AheadWithinTolerance", header: signed, now: now.Add(-SignatureTolerance), valid: true},
+		{name: "TooFarAhead", header: signed, now: now.Add(-SignatureTolerance - time.Second)},
+		// While the secret is rotated the provider signs with both, and either value may come first
+		{name: "RotationNewFirst", header: "t=" + timestamp + "," + v1 + "," + oldV1, valid: true},
+		{name: "RotationNewLast", header: "t=" + timestamp + "," + oldV1 + "," + v1, valid: true},
+		{name: "RotationOldSecret", header: "t=" + timestamp + "," + oldV1 + "," + v1, secret: "whsec_old", valid: true},
+		{name: "RotationNeitherSecret", header: "t=" + timestamp + "," + oldV1 + "," + v1, secret: "whsec_other"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := payload
+			if tc.payload != nil {
+				body = tc.payload
+			}
+			key := secret
+			if tc.secret != "" {
+				key = tc.secret
+			}
+			at := now
+			if !tc.now.IsZero() {
+				at = tc.now
+			}
+
+			err := VerifySignature(body, tc.header, key, at)
+			if tc.valid && err != nil {
+				t.Fatalf("VerifySignature returned error: %v", err)
+			}
+			if !tc.valid && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("VerifySignature = %v; want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+// TestVerifySignatureNoSecret checks that nothing is accepted when no webhook secret is configured,
+// not even a payload signed with an empty secret
+func TestVerifySignatureNoSecret(t *testing.T) {
+	payload := []byte(`{"id":"evt_1"}`)
+	now := time.Now()
+
+	err := VerifySignature(payload, Sign(payload, "", now), "", now)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("VerifySignature = %v; want ErrInvalidSignature", err)
+	}
+}
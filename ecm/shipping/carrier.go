@@ -0,0 +1,164 @@
+package shipping
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Status is how far a parcel has come on its way to the buyer
+type Status string
+
+const (
+	StatusLabelCreated   Status = "label_created"
+	StatusInTransit      Status = "in_transit"
+	StatusOutForDelivery Status = "out_for_delivery"
+	StatusDelivered      Status = "delivered"
+	StatusException      Status = "exception"
+)
+
+// Parcel is a package handed to a carrier
+type Parcel struct {
+	// Reference identifies the parcel on our side, such as the shop order it belongs to
+	Reference string
+	Address   string
+}
+
+// Label is what a carrier hands back for a parcel it accepted
+type Label struct {
+	TrackingNumber    string
+	EstimatedDelivery time.Time
+}
+
+// Event is a tracking update that a carrier reports for a parcel
+type Event struct {
+	Carrier        string
+	TrackingNumber string
+	Status         Status
+	Location       string
+	Description    string
+	OccurredAt     time.Time
+}
+
+// EventHandler receives the tracking events that a carrier reports
+type EventHandler func(ctx context.Context, event Event) error
+
+// Carrier is an interface for shipping parcels
+type Carrier interface {
+	// Name identifies the carrier in shipments and tracking events
+	Name() string
+	// CreateShipment books a parcel with the carrier
+	CreateShipment(ctx context.Context, parcel Parcel) (Label, error)
+	// Dispatch hands a booked parcel over to the carrier once its shipment is saved, after which the
+	// carrier reports its tracking events. Parcels that were already handed over are left alone.
+	Dispatch(ctx context.Context, trackingNumber string) error
+}
+
+// NewCarrier creates the carrier selected by driver. Only "local" is supported for now. Tracking
+// events are passed to handler as the carrier reports them.
+func NewCarrier(driver string, interval time.Duration, handler EventHandler) (Carrier, error) {
+	switch driver {
+	case "local":
+		return NewLocalCarrier(interval, handler), nil
+	default:
+		return nil, fmt.Errorf("unsupported shipping carrier %q", driver)
+	}
+}
+
+// localSteps are the events that LocalCarrier reports for every parcel, one per interval
+var localSteps = []struct {
+	status      Status
+	location    string
+	description string
+}{
+	{StatusInTransit, "Local sorting center", "Parcel has left the sorting center"},
+	{StatusOutForDelivery, "Delivery depot", "Parcel is out for delivery"},
+	{StatusDelivered, "Buyer's address", "Parcel was delivered"},
+}
+
+// localBookingTTL is how long LocalCarrier keeps a parcel that was booked but never dispatched, as
+// happens when the shipment could not be saved
+const localBookingTTL = time.Hour
+
+// LocalCarrier is a fake carrier for local development. Every parcel it dispatches is in transit after
+// one interval, out for delivery after two and delivered after three.
+type LocalCarrier struct {
+	interval time.Duration
+	handler  EventHandler
+
+	mu sync.Mutex
+	// booked holds when each parcel that has not been dispatched yet was booked
+	booked map[string]time.Time
+}
+
+// NewLocalCarrier creates a new LocalCarrier
+func NewLocalCarrier(interval time.Duration, handler EventHandler) *LocalCarrier {
+	return &LocalCarrier{
+		interval: interval,
+		handler:  handler,
+		booked:   make(map[string]time.Time),
+	}
+}
+
+// Name returns "local"
+func (carrier *LocalCarrier) Name() string {
+	return "local"
+}
+
+// CreateShipment makes up a tracking number for the parcel
+func (carrier *LocalCarrier) CreateShipment(ctx context.Context, parcel Parcel) (Label, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000_000_000))
+	if err != nil {
+		return Label{}, err
+	}
+	trackingNumber := fmt.Sprintf("LC%012d", n)
+
+	now := time.Now()
+	carrier.mu.Lock()
+	for booked, at := range carrier.booked {
+		if now.Sub(at) > localBookingTTL {
+			delete(carrier.booked, booked)
+		}
+	}
+	carrier.booked[trackingNumber] = now
+	carrier.mu.Unlock()
+
+	return Label{
+		TrackingNumber:    trackingNumber,
+		EstimatedDelivery: now.Add(time.Duration(len(localSteps)) * carrier.interval),
+	}, nil
+}
+
+// Dispatch schedules the tracking events of a parcel booked with CreateShipment. Other tracking
+// numbers are ignored.
+func (carrier *LocalCarrier) Dispatch(ctx context.Context, trackingNumber string) error {
+	carrier.mu.Lock()
+	_, ok := carrier.booked[trackingNumber]
+	delete(carrier.booked, trackingNumber)
+	carrier.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	for i, step := range localSteps {
+		event := Event{
+			Carrier:        carrier.Name(),
+			TrackingNumber: trackingNumber,
+			Status:         step.status,
+			Location:       step.location,
+			Description:    step.description,
+			OccurredAt:     now.Add(time.Duration(i+1) * carrier.interval),
+		}
+		time.AfterFunc(time.Until(event.OccurredAt), func() {
+			if err := carrier.handler(context.Background(), event); err != nil {
+				log.Printf("Cannot handle tracking event %s for %s: %v", event.Status, event.TrackingNumber, err)
+			}
+		})
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package token
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// KeyPublisher is implemented by makers whose tokens can be verified with published public keys
+type KeyPublisher interface {
+	// JWKS returns the public keys that verifiers should trust
+	JWKS() JSONWebKeySet
+}
+
+// AsymmetricJWTMaker is a JSON Web Token maker that signs with rotating RS256 or EdDSA keys
+type AsymmetricJWTMaker struct {
+	keySet *KeySet
+}
+
+// NewAsymmetricJWTMaker creates a new AsymmetricJWTMaker from the key directory
+func NewAsymmetricJWTMaker(keyDir string) (Maker, error) {
+	keySet, err := LoadKeySet(keyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := keySet.signingKey(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &AsymmetricJWTMaker{keySet}, nil
+}
+
+// CreateToken creates a new token signed with the currently active key
+func (maker *AsymmetricJWTMaker) CreateToken(tokenType TokenType, sessionID uuid.UUID, userID uuid.UUID, username string, role string, duration time.Duration) (string, *Payload, error) {
+	key, err := maker.keySet.signingKey(time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+
+	payload, err := NewPayload(tokenType, sessionID, userID, username, role, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	jwtToken := jwt.NewWithClaims(key.Method, payload)
+	jwtToken.Header["kid"] = key.ID
+	token, err := jwtToken.SignedString(key.PrivateKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, payload, nil
+}
+
+// VerifyToken checks if the token is valid and of the expected type
+func (maker *AsymmetricJWTMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+
+		key, ok := maker.keySet.verificationKey(kid, time.Now())
+		if !ok || token.Method.Alg() != key.Method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return key.PrivateKey.Public(), nil
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok || payload.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	if err := payload.Valid(); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// JWKS returns the public keys of the key set
+func (maker *AsymmetricJWTMaker) JWKS() JSONWebKeySet {
+	return maker.keySet.JWKS(time.Now())
+}
@@ -25,18 +25,22 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 }
 
 // CreateToken creates a new token for a specific username and duration
-func (maker *JWTMaker) CreateToken(userID uuid.UUID, username string, role string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(userID, username, role, duration)
+func (maker *JWTMaker) CreateToken(tokenType TokenType, sessionID uuid.UUID, userID uuid.UUID, username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(tokenType, sessionID, userID, username, role, duration)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	return jwtToken.SignedString([]byte(maker.secretKey))
+	token, err := jwtToken.SignedString([]byte(maker.secretKey))
+	if err != nil {
+		return "", nil, err
+	}
+	return token, payload, nil
 }
 
-// VerifyToken checks if the token is valid or not
-func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
+// VerifyToken checks if the token is valid and of the expected type
+func (maker *JWTMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
 		_, ok := token.Method.(*jwt.SigningMethodHMAC)
 		if !ok {
@@ -54,9 +58,14 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 	}
 
 	payload, ok := jwtToken.Claims.(*Payload)
-	if !ok {
+	if !ok || payload.Type != tokenType {
 		return nil, ErrInvalidToken
 	}
 
+	// The registered claims are left empty, so expiry is checked on our own payload
+	if err := payload.Valid(); err != nil {
+		return nil, err
+	}
+
 	return payload, nil
 }
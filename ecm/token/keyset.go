@@ -0,0 +1,217 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// keySetManifest is the file in the key directory describing the rotation schedule
+	keySetManifest = "keys.json"
+
+	minRSAKeyBits = 2048
+)
+
+// SigningKey is a private key used to sign tokens, identified by its key ID (kid).
+// A key signs new tokens from NotBefore until a newer key becomes active, and tokens
+// signed with it are accepted until NotAfter (a zero NotAfter never retires the key).
+type SigningKey struct {
+	ID         string
+	PrivateKey crypto.Signer
+	Method     jwt.SigningMethod
+	NotBefore  time.Time
+	NotAfter   time.Time
+}
+
+// KeySet is a rotation schedule of signing keys
+type KeySet struct {
+	keys []SigningKey
+}
+
+type keySetManifestEntry struct {
+	ID        string    `json:"kid"`
+	File      string    `json:"file"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+type keySetManifestFile struct {
+	Keys []keySetManifestEntry `json:"keys"`
+}
+
+// LoadKeySet reads the rotation schedule in dir/keys.json and the PEM encoded private keys it refers to.
+// RSA keys sign with RS256 and Ed25519 keys sign with EdDSA.
+func LoadKeySet(dir string) (*KeySet, error) {
+	data, err := os.ReadFile(filepath.Join(dir, keySetManifest))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key manifest: %w", err)
+	}
+
+	var manifest keySetManifestFile
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("cannot parse key manifest: %w", err)
+	}
+	if len(manifest.Keys) == 0 {
+		return nil, errors.New("key manifest does not list any keys")
+	}
+
+	seen := make(map[string]bool)
+	keys := make([]SigningKey, 0, len(manifest.Keys))
+	for _, entry := range manifest.Keys {
+		if entry.ID == "" {
+			return nil, fmt.Errorf("key %q has no kid", entry.File)
+		}
+		if seen[entry.ID] {
+			return nil, fmt.Errorf("duplicate kid %q", entry.ID)
+		}
+		seen[entry.ID] = true
+
+		if !entry.NotAfter.IsZero() && !entry.NotAfter.After(entry.NotBefore) {
+			return nil, fmt.Errorf("key %q retires before it becomes active", entry.ID)
+		}
+
+		privateKey, method, err := loadPrivateKey(filepath.Join(dir, entry.File))
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", entry.ID, err)
+		}
+
+		keys = append(keys, SigningKey{
+			ID:         entry.ID,
+			PrivateKey: privateKey,
+			Method:     method,
+			NotBefore:  entry.NotBefore,
+			NotAfter:   entry.NotAfter,
+		})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].NotBefore.Before(keys[j].NotBefore)
+	})
+
+	return &KeySet{keys: keys}, nil
+}
+
+// loadPrivateKey parses a PKCS#8 or PKCS#1 PEM file
+func loadPrivateKey(path string) (crypto.Signer, jwt.SigningMethod, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("no PEM block found")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		return k, jwt.SigningMethodRS256, nil
+	case ed25519.PrivateKey:
+		return k, jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// signingKey returns the newest key that is active at the given time
+func (ks *KeySet) signingKey(now time.Time) (*SigningKey, error) {
+	for i := len(ks.keys) - 1; i >= 0; i-- {
+		key := &ks.keys[i]
+		if key.activeAt(now) {
+			return key, nil
+		}
+	}
+	return nil, errors.New("no signing key is active")
+}
+
+// verificationKey returns the key with the given kid if tokens signed with it are still accepted
+func (ks *KeySet) verificationKey(kid string, now time.Time) (*SigningKey, bool) {
+	for i := range ks.keys {
+		key := &ks.keys[i]
+		if key.ID == kid && key.activeAt(now) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func (key *SigningKey) activeAt(now time.Time) bool {
+	return !now.Before(key.NotBefore) && (key.NotAfter.IsZero() || now.Before(key.NotAfter))
+}
+
+// JSONWebKey is the public part of a signing key in JWK format
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is a JWKS document
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the public keys that are or will become valid, so verifiers can fetch a key before it is used
+func (ks *KeySet) JWKS(now time.Time) JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range ks.keys {
+		if !key.NotAfter.IsZero() && !now.Before(key.NotAfter) {
+			continue
+		}
+
+		jwk := JSONWebKey{
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+		}
+		switch publicKey := key.PrivateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
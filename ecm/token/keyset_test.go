@@ -0,0 +1,275 @@
+package token
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// testKey is a key to write into a test key directory
+type testKey struct {
+	id        string
+	key       crypto.Signer
+	notBefore time.Time
+	notAfter  time.Time
+	// pkcs1 writes an RSA key as "RSA PRIVATE KEY" rather than PKCS#8
+	pkcs1 bool
+}
+
+// writeTestKeySet writes the keys and their keys.json into a new temporary directory and returns it
+func writeTestKeySet(t *testing.T, keys ...testKey) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	var manifest keySetManifestFile
+	for i, k := range keys {
+		block := &pem.Block{Type: "PRIVATE KEY"}
+		if rsaKey, ok := k.key.(*rsa.PrivateKey); ok && k.pkcs1 {
+			block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}
+		} else {
+			der, err := x509.MarshalPKCS8PrivateKey(k.key)
+			if err != nil {
+				t.Fatalf("cannot encode key %q: %v", k.id, err)
+			}
+			block.Bytes = der
+		}
+
+		file := fmt.Sprintf("key%d.pem", i)
+		if err := os.WriteFile(filepath.Join(dir, file), pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		manifest.Keys = append(manifest.Keys, keySetManifestEntry{
+			ID:        k.id,
+			File:      file,
+			NotBefore: k.notBefore,
+			NotAfter:  k.notAfter,
+		})
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, keySetManifest), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// testEd25519Key derives an Ed25519 key from a repeated byte, so that tests get the same key every run
+func testEd25519Key(b byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{b}, ed25519.SeedSize))
+}
+
+func testRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("cannot generate RSA key: %v", err)
+	}
+	return key
+}
+
+// signTestToken signs a new access token with the given method, key and kid header, bypassing the key set
+func signTestToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string) string {
+	t.Helper()
+	payload, err := NewPayload(TokenTypeAccess, uuid.New(), uuid.New(), "alice", "buyer", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwtToken := jwt.NewWithClaims(method, payload)
+	if kid != "" {
+		jwtToken.Header["kid"] = kid
+	}
+	token, err := jwtToken.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAsymmetricJWTMakerRotation(t *testing.T) {
+	now := time.Now()
+	retired, old, current, next := testEd25519Key(1), testEd25519Key(2), testRSAKey(t, 2048), testEd25519Key(4)
+
+	dir := writeTestKeySet(t,
+		testKey{id: "retired", key: retired, notBefore: now.Add(-3 * time.Hour), notAfter: now.Add(-time.Hour)},
+		// Listed out of order, the key set sorts keys by when they become active
+		testKey{id: "current", key: current, notBefore: now.Add(-time.Hour), pkcs1: true},
+		testKey{id: "old", key: old, notBefore: now.Add(-2 * time.Hour), notAfter: now.Add(time.Hour)},
+		testKey{id: "next", key: next, notBefore: now.Add(time.Hour)},
+	)
+	maker := mustMaker(t)(NewAsymmetricJWTMaker(dir))
+
+	// New tokens are signed with the newest key that is active
+	token, _, err := maker.CreateToken(TokenTypeAccess, uuid.New(), uuid.New(), "alice", "buyer", time.Minute)
+	if err != nil {
+		t.Fatalf("CreateToken returned error: %v", err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Payload{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := parsed.Header["kid"]; kid != "current" {
+		t.Errorf("kid = %v; want current", kid)
+	}
+	if alg := parsed.Header["alg"]; alg != "RS256" {
+		t.Errorf("alg = %v; want RS256", alg)
+	}
+
+	testCases := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "Current", token: signTestToken(t, jwt.SigningMethodRS256, current, "current"), valid: true},
+		// Tokens signed before the rotation stay valid until the old key retires
+		{name: "Old", token: signTestToken(t, jwt.SigningMethodEdDSA, old, "old"), valid: true},
+		{name: "Retired", token: signTestToken(t, jwt.SigningMethodEdDSA, retired, "retired")},
+		{name: "NotActiveYet", token: signTestToken(t, jwt.SigningMethodEdDSA, next, "next")},
+		{name: "UnknownKid", token: signTestToken(t, jwt.SigningMethodEdDSA, old, "unknown")},
+		{name: "NoKid", token: signTestToken(t, jwt.SigningMethodEdDSA, old, "")},
+		{name: "OtherKeysKid", token: signTestToken(t, jwt.SigningMethodEdDSA, old, "current")},
+		{name: "WrongKeyForKid", token: signTestToken(t, jwt.SigningMethodEdDSA, next, "old")},
+		{name: "HMACWithPublicKey", token: signTestToken(t, jwt.SigningMethodHS256, []byte(old.Public().(ed25519.PublicKey)), "old")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := maker.VerifyToken(tc.token, TokenTypeAccess)
+			if tc.valid && err != nil {
+				t.Fatalf("VerifyToken returned error: %v", err)
+			}
+			if !tc.valid && !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("VerifyToken = %v, %v; want ErrInvalidToken", payload, err)
+			}
+		})
+	}
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	now := time.Now()
+	rsaKey, edKey := testRSAKey(t, 2048), testEd25519Key(1)
+
+	keySet, err := LoadKeySet(writeTestKeySet(t,
+		testKey{id: "retired", key: testEd25519Key(2), notBefore: now.Add(-2 * time.Hour), notAfter: now.Add(-time.Hour)},
+		testKey{id: "rsa", key: rsaKey, notBefore: now.Add(-time.Hour), notAfter: now.Add(time.Hour)},
+		testKey{id: "ed", key: edKey, notBefore: now.Add(time.Hour)},
+	))
+	if err != nil {
+		t.Fatalf("LoadKeySet returned error: %v", err)
+	}
+
+	set := keySet.JWKS(now)
+
+	// Retired keys are left out, and keys that will become active are published ahead of time
+	want := []JSONWebKey{
+		{
+			KeyType:   "RSA",
+			KeyID:     "rsa",
+			Use:       "sig",
+			Algorithm: "RS256",
+			N:         base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		},
+		{
+			KeyType:   "OKP",
+			KeyID:     "ed",
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey)),
+		},
+	}
+	if len(set.Keys) != len(want) {
+		t.Fatalf("JWKS has %d keys; want %d: %+v", len(set.Keys), len(want), set.Keys)
+	}
+	for i := range want {
+		if set.Keys[i] != want[i] {
+			t.Errorf("key %d = %+v; want %+v", i, set.Keys[i], want[i])
+		}
+	}
+
+	// The published keys verify the tokens signed with them
+	token := signTestToken(t, jwt.SigningMethodRS256, rsaKey, "rsa")
+	n, err := base64.RawURLEncoding.DecodeString(set.Keys[0].N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: rsaKey.E}
+	_, err = jwt.ParseWithClaims(token, &Payload{}, func(*jwt.Token) (interface{}, error) { return publicKey, nil })
+	if err != nil {
+		t.Errorf("published RSA key does not verify the token: %v", err)
+	}
+
+	// Keys drop out of the set once they retire
+	if got := keySet.JWKS(now.Add(2 * time.Hour)); len(got.Keys) != 1 || got.Keys[0].KeyID != "ed" {
+		t.Errorf("JWKS two hours later = %+v; want only ed", got.Keys)
+	}
+}
+
+func TestLoadKeySetInvalid(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name string
+		dir  func(t *testing.T) string
+	}{
+		{name: "NoManifest", dir: func(t *testing.T) string { return t.TempDir() }},
+		{name: "NoKeys", dir: func(t *testing.T) string { return writeTestKeySet(t) }},
+		{name: "NoKid", dir: func(t *testing.T) string {
+			return writeTestKeySet(t, testKey{key: testEd25519Key(1), notBefore: now})
+		}},
+		{name: "DuplicateKid", dir: func(t *testing.T) string {
+			return writeTestKeySet(t,
+				testKey{id: "a", key: testEd25519Key(1), notBefore: now},
+				testKey{id: "a", key: testEd25519Key(2), notBefore: now.Add(time.Hour)},
+			)
+		}},
+		{name: "RetiresBeforeActive", dir: func(t *testing.T) string {
+			return writeTestKeySet(t, testKey{id: "a", key: testEd25519Key(1), notBefore: now, notAfter: now.Add(-time.Hour)})
+		}},
+		{name: "ShortRSAKey", dir: func(t *testing.T) string {
+			return writeTestKeySet(t, testKey{id: "a", key: testRSAKey(t, 1024), notBefore: now})
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if keySet, err := LoadKeySet(tc.dir(t)); err == nil {
+				t.Fatalf("LoadKeySet = %+v; want an error", keySet)
+			}
+		})
+	}
+}
+
+// TestNewAsymmetricJWTMakerNoActiveKey checks that the maker refuses a key set it cannot sign with yet
+func TestNewAsymmetricJWTMakerNoActiveKey(t *testing.T) {
+	now := time.Now()
+	dir := writeTestKeySet(t,
+		testKey{id: "retired", key: testEd25519Key(1), notBefore: now.Add(-2 * time.Hour), notAfter: now.Add(-time.Hour)},
+		testKey{id: "next", key: testEd25519Key(2), notBefore: now.Add(time.Hour)},
+	)
+
+	if maker, err := NewAsymmetricJWTMaker(dir); err == nil {
+		t.Fatalf("created %T; want an error", maker)
+	}
+}
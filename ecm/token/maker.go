@@ -8,9 +8,9 @@ import (
 
 // Maker is an interface for managing tokens
 type Maker interface {
-	// CreateToken creates a new token for a specific user id, username and role
-	CreateToken(userID uuid.UUID, username string, role string, duration time.Duration) (string, error)
+	// CreateToken creates a new token of the given type for a specific session, user id, username and role
+	CreateToken(tokenType TokenType, sessionID uuid.UUID, userID uuid.UUID, username string, role string, duration time.Duration) (string, *Payload, error)
 
-	// VerifyToken checks if the token is valid or not
-	VerifyToken(token string) (*Payload, error)
+	// VerifyToken checks if the token is valid and of the expected type
+	VerifyToken(token string, tokenType TokenType) (*Payload, error)
 }
@@ -0,0 +1,230 @@
+package token
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// testMakers creates two makers of each kind with different keys, for tokens from one to be checked
+// by the other
+var testMakers = []struct {
+	name     string
+	newMaker func(t *testing.T) Maker
+	newOther func(t *testing.T) Maker
+}{
+	{
+		name:     "JWT",
+		newMaker: func(t *testing.T) Maker { return mustMaker(t)(NewJWTMaker(strings.Repeat("a", minSecretKeySize))) },
+		newOther: func(t *testing.T) Maker { return mustMaker(t)(NewJWTMaker(strings.Repeat("b", minSecretKeySize))) },
+	},
+	{
+		name:     "PasetoLocal",
+		newMaker: func(t *testing.T) Maker { return mustMaker(t)(NewPasetoMaker(strings.Repeat("a", 32))) },
+		newOther: func(t *testing.T) Maker { return mustMaker(t)(NewPasetoMaker(strings.Repeat("b", 32))) },
+	},
+	{
+		name:     "PasetoPublic",
+		newMaker: func(t *testing.T) Maker { return mustMaker(t)(NewPasetoPublicMaker(strings.Repeat("0a", 32))) },
+		newOther: func(t *testing.T) Maker { return mustMaker(t)(NewPasetoPublicMaker(strings.Repeat("0b", 32))) },
+	},
+	{
+		// Both key sets use the same kid, so the other maker's tokens only fail on the signature
+		name: "AsymmetricJWT",
+		newMaker: func(t *testing.T) Maker {
+			return mustMaker(t)(NewAsymmetricJWTMaker(writeTestKeySet(t, testKey{id: "test", key: testEd25519Key(0xa)})))
+		},
+		newOther: func(t *testing.T) Maker {
+			return mustMaker(t)(NewAsymmetricJWTMaker(writeTestKeySet(t, testKey{id: "test", key: testEd25519Key(0xb)})))
+		},
+	},
+}
+
+func mustMaker(t *testing.T) func(Maker, error) Maker {
+	return func(maker Maker, err error) Maker {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("cannot create maker: %v", err)
+		}
+		return maker
+	}
+}
+
+func TestMakerRoundTrip(t *testing.T) {
+	for _, tm := range testMakers {
+		t.Run(tm.name, func(t *testing.T) {
+			maker := tm.newMaker(t)
+			sessionID, userID := uuid.New(), uuid.New()
+
+			token, created, err := maker.CreateToken(TokenTypeAccess, sessionID, userID, "alice", "seller", time.Minute)
+			if err != nil {
+				t.Fatalf("CreateToken returned error: %v", err)
+			}
+			if token == "" {
+				t.Fatal("CreateToken returned an empty token")
+			}
+
+			payload, err := maker.VerifyToken(token, TokenTypeAccess)
+			if err != nil {
+				t.Fatalf("VerifyToken returned error: %v", err)
+			}
+
+			if payload.ID != created.ID || payload.ID == uuid.Nil {
+				t.Errorf("ID = %s; want %s", payload.ID, created.ID)
+			}
+			if payload.Type != TokenTypeAccess {
+				t.Errorf("Type = %s; want %s", payload.Type, TokenTypeAccess)
+			}
+			if payload.SessionID != sessionID {
+				t.Errorf("SessionID = %s; want %s", payload.SessionID, sessionID)
+			}
+			if payload.UserID != userID {
+				t.Errorf("UserID = %s; want %s", payload.UserID, userID)
+			}
+			if payload.Username != "alice" || payload.Role != "seller" {
+				t.Errorf("Username, Role = %s, %s; want alice, seller", payload.Username, payload.Role)
+			}
+			if !payload.IssuedAt.Equal(created.IssuedAt) || !payload.ExpiredAt.Equal(created.ExpiredAt) {
+				t.Errorf("IssuedAt, ExpiredAt = %s, %s; want %s, %s", payload.IssuedAt, payload.ExpiredAt, created.IssuedAt, created.ExpiredAt)
+			}
+			if got := payload.ExpiredAt.Sub(payload.IssuedAt); got != time.Minute {
+				t.Errorf("token lasts %s; want %s", got, time.Minute)
+			}
+		})
+	}
+}
+
+func TestMakerExpiredToken(t *testing.T) {
+	for _, tm := range testMakers {
+		t.Run(tm.name, func(t *testing.T) {
+			maker := tm.newMaker(t)
+
+			token, _, err := maker.CreateToken(TokenTypeAccess, uuid.New(), uuid.New(), "alice", "buyer", -time.Minute)
+			if err != nil {
+				t.Fatalf("CreateToken returned error: %v", err)
+			}
+
+			payload, err := maker.VerifyToken(token, TokenTypeAccess)
+			if !errors.Is(err, ErrExpiredToken) {
+				t.Fatalf("VerifyToken = %v, %v; want ErrExpiredToken", payload, err)
+			}
+		})
+	}
+}
+
+func TestMakerInvalidToken(t *testing.T) {
+	for _, tm := range testMakers {
+		t.Run(tm.name, func(t *testing.T) {
+			maker := tm.newMaker(t)
+
+			token, _, err := maker.CreateToken(TokenTypeAccess, uuid.New(), uuid.New(), "alice", "buyer", time.Minute)
+			if err != nil {
+				t.Fatalf("CreateToken returned error: %v", err)
+			}
+
+			testCases := []struct {
+				name      string
+				maker     Maker
+				token     string
+				tokenType TokenType
+			}{
+				{name: "Tampered", maker: maker, token: tamper(token), tokenType: TokenTypeAccess},
+				{name: "Truncated", maker: maker, token: token[:len(token)-4], tokenType: TokenTypeAccess},
+				{name: "WrongKey", maker: tm.newOther(t), token: token, tokenType: TokenTypeAccess},
+				{name: "WrongType", maker: maker, token: token, tokenType: TokenTypeRefresh},
+				{name: "Empty", maker: maker, token: "", tokenType: TokenTypeAccess},
+				{name: "Garbage", maker: maker, token: "not.a.token", tokenType: TokenTypeAccess},
+			}
+
+			for _, tc := range testCases {
+				t.Run(tc.name, func(t *testing.T) {
+					payload, err := tc.maker.VerifyToken(tc.token, tc.tokenType)
+					if !errors.Is(err, ErrInvalidToken) {
+						t.Fatalf("VerifyToken = %v, %v; want ErrInvalidToken", payload, err)
+					}
+				})
+			}
+		})
+	}
+}
+
+// TestMakerOtherKind checks that no maker accepts a token issued by a maker of another kind
+func TestMakerOtherKind(t *testing.T) {
+	for _, issuer := range testMakers {
+		token, _, err := issuer.newMaker(t).CreateToken(TokenTypeAccess, uuid.New(), uuid.New(), "alice", "buyer", time.Minute)
+		if err != nil {
+			t.Fatalf("CreateToken returned error: %v", err)
+		}
+
+		for _, verifier := range testMakers {
+			if verifier.name == issuer.name {
+				continue
+			}
+			t.Run(issuer.name+"To"+verifier.name, func(t *testing.T) {
+				payload, err := verifier.newMaker(t).VerifyToken(token, TokenTypeAccess)
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("VerifyToken = %v, %v; want ErrInvalidToken", payload, err)
+				}
+			})
+		}
+	}
+}
+
+func TestJWTMakerUnsignedToken(t *testing.T) {
+	maker := testMakers[0].newMaker(t)
+
+	payload, err := NewPayload(TokenTypeAccess, uuid.New(), uuid.New(), "alice", "admin", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, payload).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verified, err := maker.VerifyToken(token, TokenTypeAccess)
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("VerifyToken = %v, %v; want ErrInvalidToken", verified, err)
+	}
+}
+
+func TestNewMakerInvalidKey(t *testing.T) {
+	testCases := []struct {
+		name     string
+		newMaker func() (Maker, error)
+	}{
+		{name: "JWTShortKey", newMaker: func() (Maker, error) { return NewJWTMaker(strings.Repeat("a", minSecretKeySize-1)) }},
+		{name: "PasetoLocalShortKey", newMaker: func() (Maker, error) { return NewPasetoMaker(strings.Repeat("a", 31)) }},
+		{name: "PasetoLocalLongKey", newMaker: func() (Maker, error) { return NewPasetoMaker(strings.Repeat("a", 33)) }},
+		{name: "PasetoPublicNotHex", newMaker: func() (Maker, error) { return NewPasetoPublicMaker(strings.Repeat("zz", 32)) }},
+		{name: "PasetoPublicShortKey", newMaker: func() (Maker, error) { return NewPasetoPublicMaker(strings.Repeat("0a", 31)) }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := tc.newMaker()
+			if err == nil {
+				t.Fatalf("created %T; want an error", maker)
+			}
+		})
+	}
+}
+
+// tamper changes one character in the middle of a token's body, which every maker has to detect
+func tamper(token string) string {
+	i := len(token) / 2
+	for token[i] == '.' {
+		i++
+	}
+
+	replacement := byte('A')
+	if token[i] == 'A' {
+		replacement = 'B'
+	}
+	return token[:i] + string(replacement) + token[i+1:]
+}
@@ -0,0 +1,275 @@
+package token
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	pasetoLocalHeader  = "v4.local."
+	pasetoPublicHeader = "v4.public."
+
+	pasetoNonceSize = 32
+	pasetoMACSize   = 32
+)
+
+// PasetoMaker is a PASETO v4 token maker. It produces v4.local tokens when created with a
+// symmetric key and v4.public tokens when created with an Ed25519 private key.
+type PasetoMaker struct {
+	symmetricKey []byte
+	privateKey   ed25519.PrivateKey
+	publicKey    ed25519.PublicKey
+}
+
+// NewPasetoMaker creates a new PasetoMaker issuing v4.local tokens
+func NewPasetoMaker(symmetricKey string) (Maker, error) {
+	if len(symmetricKey) != chacha20.KeySize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", chacha20.KeySize)
+	}
+	return &PasetoMaker{symmetricKey: []byte(symmetricKey)}, nil
+}
+
+// NewPasetoPublicMaker creates a new PasetoMaker issuing v4.public tokens. The key is the hex
+// encoding of either an Ed25519 seed or a full Ed25519 private key.
+func NewPasetoPublicMaker(privateKeyHex string) (Maker, error) {
+	key, err := hex.DecodeString(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	var privateKey ed25519.PrivateKey
+	switch len(key) {
+	case ed25519.SeedSize:
+		privateKey = ed25519.NewKeyFromSeed(key)
+	case ed25519.PrivateKeySize:
+		privateKey = ed25519.PrivateKey(key)
+	default:
+		return nil, fmt.Errorf("invalid key size: must be a %d byte seed or a %d byte private key", ed25519.SeedSize, ed25519.PrivateKeySize)
+	}
+
+	return &PasetoMaker{
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+// CreateToken creates a new token for a specific username and duration
+func (maker *PasetoMaker) CreateToken(tokenType TokenType, sessionID uuid.UUID, userID uuid.UUID, username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(tokenType, sessionID, userID, username, role, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var token string
+	if maker.privateKey != nil {
+		token = maker.sign(message)
+	} else {
+		token, err = maker.encrypt(message)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	return token, payload, nil
+}
+
+// VerifyToken checks if the token is valid and of the expected type
+func (maker *PasetoMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
+	var message []byte
+	var err error
+	if maker.privateKey != nil {
+		message, err = maker.verify(token)
+	} else {
+		message, err = maker.decrypt(token)
+	}
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	if err := json.Unmarshal(message, payload); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if payload.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	if err := payload.Valid(); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// encrypt builds a v4.local token without footer or implicit assertion
+func (maker *PasetoMaker) encrypt(message []byte) (string, error) {
+	nonce := make([]byte, pasetoNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return maker.encryptWithNonce(message, nonce)
+}
+
+// encryptWithNonce builds a v4.local token from a given random nonce
+func (maker *PasetoMaker) encryptWithNonce(message []byte, nonce []byte) (string, error) {
+	encKey, counterNonce, authKey, err := maker.splitKeys(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(message))
+	cipher.XORKeyStream(ciphertext, message)
+
+	mac, err := keyedHash(authKey, pasetoMACSize, pae([]byte(pasetoLocalHeader), nonce, ciphertext, nil, nil))
+	if err != nil {
+		return "", err
+	}
+
+	body := make([]byte, 0, len(nonce)+len(ciphertext)+len(mac))
+	body = append(body, nonce...)
+	body = append(body, ciphertext...)
+	body = append(body, mac...)
+	return pasetoLocalHeader + base64.RawURLEncoding.EncodeToString(body), nil
+}
+
+// decrypt authenticates and decrypts a v4.local token
+func (maker *PasetoMaker) decrypt(token string) ([]byte, error) {
+	body, err := decodePasetoBody(token, pasetoLocalHeader)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < pasetoNonceSize+pasetoMACSize {
+		return nil, ErrInvalidToken
+	}
+
+	nonce := body[:pasetoNonceSize]
+	ciphertext := body[pasetoNonceSize : len(body)-pasetoMACSize]
+	mac := body[len(body)-pasetoMACSize:]
+
+	encKey, counterNonce, authKey, err := maker.splitKeys(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedMAC, err := keyedHash(authKey, pasetoMACSize, pae([]byte(pasetoLocalHeader), nonce, ciphertext, nil, nil))
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(mac, expectedMAC) {
+		return nil, ErrInvalidToken
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return nil, err
+	}
+	message := make([]byte, len(ciphertext))
+	cipher.XORKeyStream(message, ciphertext)
+	return message, nil
+}
+
+// splitKeys derives the encryption key, XChaCha20 nonce and authentication key for a token nonce
+func (maker *PasetoMaker) splitKeys(nonce []byte) (encKey, counterNonce, authKey []byte, err error) {
+	tmp, err := keyedHash(maker.symmetricKey, chacha20.KeySize+chacha20.NonceSizeX, append([]byte("paseto-encryption-key"), nonce...))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	authKey, err = keyedHash(maker.symmetricKey, 32, append([]byte("paseto-auth-key-for-aead"), nonce...))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return tmp[:chacha20.KeySize], tmp[chacha20.KeySize:], authKey, nil
+}
+
+// sign builds a v4.public token without footer or implicit assertion
+func (maker *PasetoMaker) sign(message []byte) string {
+	signature := ed25519.Sign(maker.privateKey, pae([]byte(pasetoPublicHeader), message, nil, nil))
+
+	body := make([]byte, 0, len(message)+len(signature))
+	body = append(body, message...)
+	body = append(body, signature...)
+	return pasetoPublicHeader + base64.RawURLEncoding.EncodeToString(body)
+}
+
+// verify checks the signature of a v4.public token and returns its message
+func (maker *PasetoMaker) verify(token string) ([]byte, error) {
+	body, err := decodePasetoBody(token, pasetoPublicHeader)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < ed25519.SignatureSize {
+		return nil, ErrInvalidToken
+	}
+
+	message := body[:len(body)-ed25519.SignatureSize]
+	signature := body[len(body)-ed25519.SignatureSize:]
+	if !ed25519.Verify(maker.publicKey, pae([]byte(pasetoPublicHeader), message, nil, nil), signature) {
+		return nil, ErrInvalidToken
+	}
+	return message, nil
+}
+
+// decodePasetoBody strips the header and decodes the body of a token that carries no footer
+func decodePasetoBody(token string, header string) ([]byte, error) {
+	if !strings.HasPrefix(token, header) {
+		return nil, ErrInvalidToken
+	}
+
+	encoded := strings.TrimPrefix(token, header)
+	if strings.Contains(encoded, ".") {
+		return nil, ErrInvalidToken
+	}
+
+	return base64.RawURLEncoding.DecodeString(encoded)
+}
+
+// keyedHash computes a keyed BLAKE2b digest of the given size
+func keyedHash(key []byte, size int, message []byte) ([]byte, error) {
+	h, err := blake2b.New(size, key)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(message)
+	return h.Sum(nil), nil
+}
+
+// pae implements PASETO's pre-authentication encoding
+func pae(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+	writeLength := func(n int) {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], uint64(n)&^(1<<63))
+		buf.Write(b[:])
+	}
+
+	writeLength(len(pieces))
+	for _, piece := range pieces {
+		writeLength(len(piece))
+		buf.Write(piece)
+	}
+	return buf.Bytes()
+}
@@ -14,9 +14,22 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// TokenType distinguishes what a token may be used for
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+
+	// TokenTypeMFA is issued after a correct password when a second factor is still needed
+	TokenTypeMFA TokenType = "mfa"
+)
+
 // Payload contains the payload data of the token
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
+	Type      TokenType `json:"token_type"`
+	SessionID uuid.UUID `json:"session_id"`
 	UserID    uuid.UUID `json:"user_id"`
 	Username  string    `json:"username"`
 	Role      string    `json:"role"`
@@ -25,8 +38,8 @@ type Payload struct {
 	jwt.RegisteredClaims
 }
 
-// NewPayload creates a new token payload with a specific username and duration
-func NewPayload(userID uuid.UUID, username string, role string, duration time.Duration) (*Payload, error) {
+// NewPayload creates a new token payload of the given type for a specific user, session and duration
+func NewPayload(tokenType TokenType, sessionID uuid.UUID, userID uuid.UUID, username string, role string, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -35,6 +48,8 @@ func NewPayload(userID uuid.UUID, username string, role string, duration time.Du
 	now := time.Now()
 	payload := &Payload{
 		ID:        tokenID,
+		Type:      tokenType,
+		SessionID: sessionID,
 		UserID:    userID,
 		Username:  username,
 		Role:      role,
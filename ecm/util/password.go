@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"sync"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -19,3 +20,21 @@ func HashPassword(password string) (string, error) {
 func CheckPassword(password, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
+)
+
+// CheckDummyPassword spends as long as CheckPassword does, so a login for an unknown user takes
+// the same time as one with a wrong password. It always returns an error.
+func CheckDummyPassword(password string) error {
+	dummyPasswordHashOnce.Do(func() {
+		hash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+		if err == nil {
+			dummyPasswordHash = string(hash)
+		}
+	})
+	bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+	return bcrypt.ErrMismatchedHashAndPassword
+}
@@ -37,7 +37,15 @@ func SeedAdminUser(store db.Store) error {
 		Role:         "admin",
 	}
 
-	_, err = store.CreateUser(ctx, arg)
+	admin, err := store.CreateUser(ctx, arg)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.MarkUserEmailVerified(ctx, db.MarkUserEmailVerifiedParams{
+		ID:    admin.ID,
+		Email: admin.Email,
+	})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,99 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 appendix B test vectors, base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// rfc6238Vectors are the SHA-1 test vectors of RFC 6238 appendix B. The RFC lists 8 digit codes, so
+// the 6 digit codes are their last 6 digits.
+var rfc6238Vectors = []struct {
+	unix int64
+	step int64
+	code string
+}{
+	{unix: 59, step: 0x1, code: "287082"},
+	{unix: 1111111109, step: 0x23523EC, code: "081804"},
+	{unix: 1111111111, step: 0x23523ED, code: "050471"},
+	{unix: 1234567890, step: 0x273EF07, code: "005924"},
+	{unix: 2000000000, step: 0x3F940AA, code: "279037"},
+	{unix: 20000000000, step: 0x27BC86AA, code: "353130"},
+}
+
+func TestTOTPCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	for _, tc := range rfc6238Vectors {
+		if got := totpCode(key, tc.step); got != tc.code {
+			t.Errorf("totpCode(step %#x) = %s, want %s", tc.step, got, tc.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	for _, tc := range rfc6238Vectors {
+		now := time.Unix(tc.unix, 0)
+
+		step, ok := ValidateTOTP(rfc6238Secret, tc.code, now)
+		if !ok {
+			t.Errorf("ValidateTOTP(%s) at %d rejected the code", tc.code, tc.unix)
+			continue
+		}
+		if step != tc.step {
+			t.Errorf("ValidateTOTP(%s) at %d = step %#x, want %#x", tc.code, tc.unix, step, tc.step)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// The code of step 0x23523EC, valid from 1111111080 to 1111111109
+	code := "081804"
+	testCases := []struct {
+		name  string
+		unix  int64
+		valid bool
+	}{
+		{name: "PreviousPeriod", unix: 1111111079, valid: true},
+		{name: "NextPeriod", unix: 1111111139, valid: true},
+		{name: "TwoPeriodsEarly", unix: 1111111049, valid: false},
+		{name: "TwoPeriodsLate", unix: 1111111140, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, code, time.Unix(tc.unix, 0))
+			if ok != tc.valid {
+				t.Fatalf("ValidateTOTP at %d = %v, want %v", tc.unix, ok, tc.valid)
+			}
+			if ok && step != 0x23523EC {
+				t.Errorf("ValidateTOTP at %d = step %#x, want %#x", tc.unix, step, 0x23523EC)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejects(t *testing.T) {
+	now := time.Unix(59, 0)
+	testCases := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{name: "WrongCode", secret: rfc6238Secret, code: "287083"},
+		{name: "EightDigits", secret: rfc6238Secret, code: "94287082"},
+		{name: "Short", secret: rfc6238Secret, code: "28708"},
+		{name: "Empty", secret: rfc6238Secret, code: ""},
+		{name: "BadSecret", secret: "not base32!", code: "287082"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tc.secret, tc.code, now); ok {
+				t.Errorf("ValidateTOTP(%q, %q) accepted the code", tc.secret, tc.code)
+			}
+		})
+	}
+}